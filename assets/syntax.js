@@ -5,9 +5,9 @@ Prism.languages.goscript = {
         pattern: /(\bfunc\s+)[a-zA-Z_]\w*(?=\()/,
         lookbehind: true
     },
-    'keyword': /\b(?:if|for|return)\b/,
+    'keyword': /\b(?:if|else|for|return|try|catch|finally|throw|defer|async|await|go|select|case|default|yield|import|from|range)\b/,
     'boolean': /\b(?:true|false)\b/,
-    'number': /\b\d+(?:\.\d+)?\b/,
+    'number': /\b0[xX][\da-fA-F_]+\b|\b0[oO][0-7_]+\b|\b0[bB][01_]+\b|(?:\b\d[\d_]*(?:\.[\d_]+)?|\B\.\d[\d_]*)(?:[eE][+-]?\d[\d_]*)?\b/,
     'operator': /=/,
     'punctuation': /[{}[\];(),.:]/
 };
\ No newline at end of file
@@ -0,0 +1,19 @@
+import "time"
+
+async func fetch(name, seconds) {
+    time.sleep(seconds)
+    return name + " done"
+}
+
+async func fail() {
+    throw "lost connection"
+}
+
+results = await gather([fetch("a", 0.3), fetch("b", 0.1), fetch("c", 0.2)])
+print(results)
+
+try {
+    await fail()
+} catch err {
+    print("failed:", err.message)
+}
@@ -0,0 +1,39 @@
+import "sync"
+import "time"
+
+jobs = make_chan(10)
+results = make_chan(10)
+wg = sync.waitGroup()
+
+func worker(id) {
+    defer wg.done()
+    for job := range jobs {
+        time.sleep(0.05)
+        results.send(job * 2)
+    }
+}
+
+for w := 0; w < 3; w++ {
+    wg.add(1)
+    go worker(w)
+}
+for i := 1; i <= 6; i++ {
+    jobs.send(i)
+}
+jobs.close()
+wg.wait()
+results.close()
+
+total = 0
+for r := range results {
+    total = total + r
+}
+print("total:", total)
+
+quiet = make_chan()
+select {
+case msg := quiet.recv():
+    print("unexpected", msg)
+case time.after(0.1).recv():
+    print("timed out")
+}
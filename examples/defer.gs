@@ -0,0 +1,18 @@
+func release(name) {
+    print("released", name)
+}
+
+func work() {
+    defer release("first")
+    defer release("second")
+    panic("something went wrong")
+}
+
+func safely() {
+    defer func() {
+        print("recovered:", recover())
+    }()
+    work()
+}
+
+safely()
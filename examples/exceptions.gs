@@ -0,0 +1,17 @@
+func divide(a, b) {
+    return a / b
+}
+
+try {
+    print(divide(1, 0))
+} catch e {
+    print(e.type, e.message)
+} finally {
+    print("done")
+}
+
+try {
+    throw error("invalid configuration", "ValueError")
+} catch e {
+    print(e.type)
+}
@@ -0,0 +1,31 @@
+func fibonacci() {
+    a = 0
+    b = 1
+    for i := 0; i < 10; i++ {
+        yield a
+        next = a + b
+        a = b
+        b = next
+    }
+}
+
+for n := range fibonacci() {
+    print(n)
+}
+
+func averager() {
+    total = 0
+    count = 0
+    average = 0
+    for i := 0; i < 100; i++ {
+        value = yield average
+        total = total + value
+        count = count + 1
+        average = total / count
+    }
+}
+
+avg = averager()
+avg.next()
+print(avg.send(10))
+print(avg.send(20))
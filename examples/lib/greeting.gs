@@ -0,0 +1,5 @@
+prefix = "Hello, "
+
+func Greet(name) {
+    return prefix + name
+}
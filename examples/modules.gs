@@ -0,0 +1,3 @@
+import greeting from "./lib/greeting.gs"
+
+print(greeting.Greet("GoScript"))
@@ -1,20 +1,27 @@
 package commands
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
 	"runtime/pprof"
 	"strings"
 	"syscall"
 
+	"github.com/hyperioxx/goscript/pkg/core"
 	"github.com/hyperioxx/goscript/pkg/version"
 )
 
 const ModulePath string = "./"
 
+// MODULE_PATH_ENV names the environment variable listing extra directories
+// imports are searched in, separated like PATH.
+const MODULE_PATH_ENV = "GOSCRIPTPATH"
+
 type Command interface {
 	Execute(args []string) error
 	Name() string
@@ -23,25 +30,49 @@ type Command interface {
 type CommandFactory func(debugFlag *bool) (Command, error)
 
 type Application struct {
-	debugFlag   *bool
-	versionFlag *bool
-	commands    map[string]CommandFactory
-	args        []string
-	exit        func(int)
+	debugFlag    *bool
+	versionFlag  *bool
+	vmFlag       *bool
+	optimiseFlag *int
+	sandboxFlag  *bool
+	commands     map[string]CommandFactory
+	args         []string
+	exit         func(int)
 }
 
 func NewApplication(args []string, exit func(int)) *Application {
 	debugFlag := flag.Bool("v", false, "verbose")
 	versionFlag := flag.Bool("version", false, "Print version information")
+	vmFlag := flag.Bool("vm", false, "Run scripts on the bytecode virtual machine")
+	optimiseFlag := new(int)
+	*optimiseFlag = 1
+	flag.BoolFunc("O0", "Run scripts as written, without optimising them", func(string) error {
+		*optimiseFlag = 0
+		return nil
+	})
+	flag.BoolFunc("O1", "Optimise scripts before running them (default)", func(string) error {
+		*optimiseFlag = 1
+		return nil
+	})
+
+	sandboxFlag := flag.Bool("sandbox", false, "Run scripts without access to files, environment, processes, network or clock")
 
 	commands := map[string]CommandFactory{}
 
 	return &Application{
-		debugFlag:   debugFlag,
-		versionFlag: versionFlag,
-		commands:    commands,
-		args:        args,
-		exit:        exit,
+		debugFlag:    debugFlag,
+		versionFlag:  versionFlag,
+		vmFlag:       vmFlag,
+		optimiseFlag: optimiseFlag,
+		sandboxFlag:  sandboxFlag,
+		commands:     commands,
+		args:         args,
+		// the commands a script started run in process groups of their
+		// own, an interrupt does not reach them
+		exit: func(code int) {
+			core.KillCommands()
+			exit(code)
+		},
 	}
 }
 
@@ -87,10 +118,15 @@ func (app *Application) Run() {
 		}
 	}
 
-	if len(app.args) <= 2 && !strings.HasSuffix(app.args[len(app.args)-1], ".gs") {
+	script := scriptIndex(app.args)
+	if len(app.args) <= 2 && script == -1 {
 
-		interpreter := NewInterpreter(app.debugFlag, version.GetVersion())
+		interpreter := NewInterpreter(app.debugFlag, app.vmFlag, app.optimiseFlag, app.sandboxFlag, version.GetVersion())
 		err := interpreter.Execute(nil)
+		if code, ok := exitCode(err); ok {
+			app.exit(code)
+			return
+		}
 		if err != nil {
 			fmt.Printf("Interpreter failed: %s\n", err.Error())
 			app.exit(1)
@@ -110,9 +146,13 @@ func (app *Application) Run() {
 			app.exit(1)
 		}
 	} else {
-		if strings.HasSuffix(app.args[len(app.args)-1], ".gs") {
-			fileHandler := NewFileHandler(app.debugFlag)
+		if script != -1 {
+			fileHandler := NewFileHandler(app.debugFlag, app.vmFlag, app.optimiseFlag, app.sandboxFlag)
 			err := fileHandler.Execute(app.args)
+			if code, ok := exitCode(err); ok {
+				app.exit(code)
+				return
+			}
 			if err != nil {
 				fmt.Printf("%s\n", err.Error())
 				app.exit(1)
@@ -124,3 +164,29 @@ func (app *Application) Run() {
 		}
 	}
 }
+
+// moduleSearchPath returns the directories imports are looked up in,
+// ModulePath followed by those listed in GOSCRIPTPATH.
+func moduleSearchPath() []string {
+	return append([]string{ModulePath}, filepath.SplitList(os.Getenv(MODULE_PATH_ENV))...)
+}
+
+// scriptIndex returns the position in args of the .gs file to run, or -1.
+// The arguments following it belong to the script.
+func scriptIndex(args []string) int {
+	for i := 1; i < len(args); i++ {
+		if strings.HasSuffix(args[i], ".gs") {
+			return i
+		}
+	}
+	return -1
+}
+
+// exitCode reports the code a script asked to exit with through os.exit.
+func exitCode(err error) (int, bool) {
+	var exitErr *core.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code, true
+	}
+	return 0, false
+}
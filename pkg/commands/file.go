@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -8,20 +9,27 @@ import (
 )
 
 type FileHandler struct {
-	debugFlag *bool
+	debugFlag    *bool
+	vmFlag       *bool
+	optimiseFlag *int
+	sandboxFlag  *bool
 }
 
-func NewFileHandler(debugFlag *bool) *FileHandler {
+func NewFileHandler(debugFlag, vmFlag *bool, optimiseFlag *int, sandboxFlag *bool) *FileHandler {
 	return &FileHandler{
-		debugFlag: debugFlag,
+		debugFlag:    debugFlag,
+		vmFlag:       vmFlag,
+		optimiseFlag: optimiseFlag,
+		sandboxFlag:  sandboxFlag,
 	}
 }
 
 func (f *FileHandler) Execute(args []string) error {
-	if len(args) < 2 {
+	script := scriptIndex(args)
+	if script == -1 {
 		return fmt.Errorf("missing file name")
 	}
-	filename := args[len(args)-1]
+	filename := args[script]
 
 	fileBytes, err := os.ReadFile(filename)
 	if err != nil {
@@ -32,20 +40,44 @@ func (f *FileHandler) Execute(args []string) error {
 	l := core.NewV1Lexer(fileContent)
 	p := core.NewV1Parser(l, *f.debugFlag)
 	e := core.NewEvaluator(*f.debugFlag)
+	e.SetVM(*f.vmFlag)
+	e.SetOptimisation(*f.optimiseFlag)
+	if *f.sandboxFlag {
+		e.SetPolicy(core.Policy{})
+	}
+	e.SetSearchPath(moduleSearchPath())
+	e.SetArgs(args[script+1:])
+	if err := e.SetFilename(filename); err != nil {
+		return err
+	}
 	program, err := p.ParseProgram()
 	if err != nil {
 		fmt.Println(err)
 		return nil
 	}
+	if err := e.Resolve(program); err != nil {
+		return uncaughtError(err)
+	}
+	program = core.Optimise(program, *f.optimiseFlag)
 
 	_, err = e.Evaluate(program)
 	if err != nil {
-		return err
+		return uncaughtError(err)
 	}
 
 	return nil
 }
 
+// uncaughtError turns an error that escaped the script into the one reported
+// to the user, including the stack trace of runtime errors.
+func uncaughtError(err error) error {
+	var runtimeErr *core.RuntimeError
+	if errors.As(err, &runtimeErr) {
+		return errors.New(runtimeErr.Traceback())
+	}
+	return err
+}
+
 func (f *FileHandler) Name() string {
 	return "FileHandler"
 }
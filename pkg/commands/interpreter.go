@@ -1,9 +1,7 @@
 package commands
 
 import (
-	"bufio"
 	"fmt"
-	"os"
 	"runtime"
 	"strings"
 
@@ -12,21 +10,32 @@ import (
 )
 
 type Interpreter struct {
-	debugFlag *bool
-	version   version.Version
+	debugFlag    *bool
+	vmFlag       *bool
+	optimiseFlag *int
+	sandboxFlag  *bool
+	version      version.Version
 }
 
-func NewInterpreter(debugFlag *bool, ver version.Version) *Interpreter {
+func NewInterpreter(debugFlag, vmFlag *bool, optimiseFlag *int, sandboxFlag *bool, ver version.Version) *Interpreter {
 	return &Interpreter{
-		debugFlag: debugFlag,
-		version:   ver,
+		debugFlag:    debugFlag,
+		vmFlag:       vmFlag,
+		optimiseFlag: optimiseFlag,
+		sandboxFlag:  sandboxFlag,
+		version:      ver,
 	}
 }
 
 func (i *Interpreter) Execute(args []string) error {
 	i.printSystemInfo()
-	scanner := bufio.NewScanner(os.Stdin)
 	e := core.NewEvaluator(*i.debugFlag)
+	e.SetVM(*i.vmFlag)
+	e.SetOptimisation(*i.optimiseFlag)
+	if *i.sandboxFlag {
+		e.SetPolicy(core.Policy{})
+	}
+	e.SetSearchPath(moduleSearchPath())
 
 	var multiLine string
 	isMultiLine := false
@@ -34,13 +43,13 @@ func (i *Interpreter) Execute(args []string) error {
 
 	for {
 		fmt.Print(prompt)
-		scanned := scanner.Scan()
-		if !scanned {
+		// lines are read through the evaluator so that input() and stdin
+		// in the REPL read the lines typed after them
+		line, err := e.ReadLine()
+		if err != nil {
 			return fmt.Errorf("unable to scan input from stdin")
 		}
 
-		line := scanner.Text()
-
 		if strings.Contains(line, "{") && !isMultiLine {
 			isMultiLine = true
 			prompt = "       "
@@ -71,9 +80,17 @@ func (i *Interpreter) Execute(args []string) error {
 			return nil
 		}
 
-		_, err = e.Evaluate(program)
+		// the line sees the variables the lines before it defined
+		if err := e.Resolve(program); err != nil {
+			fmt.Println(uncaughtError(err))
+			continue
+		}
+		_, err = e.Evaluate(core.Optimise(program, *i.optimiseFlag))
+		if _, ok := exitCode(err); ok {
+			return err
+		}
 		if err != nil {
-			fmt.Println(err)
+			fmt.Println(uncaughtError(err))
 		}
 
 	}
@@ -0,0 +1,104 @@
+package core
+
+// inspect walks the tree rooted at node depth first, calling visit for every
+// node before its children. The children of a node are skipped when visit
+// returns false.
+func inspect(node Node, visit func(Node) bool) {
+	if node == nil || !visit(node) {
+		return
+	}
+	for _, child := range children(node) {
+		inspect(child, visit)
+	}
+}
+
+// children returns the nodes directly below node in the order they are
+// evaluated.
+func children(node Node) []Node {
+	var nodes []Node
+	add := func(children ...Node) {
+		for _, child := range children {
+			if child != nil {
+				nodes = append(nodes, child)
+			}
+		}
+	}
+	switch n := node.(type) {
+	case *BlockStatement:
+		add(n.Statements...)
+	case *ArrayLiteral:
+		add(n.Elements...)
+	case *MapLiteral:
+		for i := range n.Keys {
+			add(n.Keys[i], n.Values[i])
+		}
+	case *IndexNode:
+		add(n.Object, n.Index)
+	case *SliceNode:
+		add(n.Object, n.Start, n.End)
+	case *AttributeNode:
+		add(n.Object)
+	case *ReturnStatement:
+		add(n.ReturnValue)
+	case *ThrowStatement:
+		add(n.Thrown)
+	case *TryStatement:
+		add(n.Body)
+		if n.CatchName != nil {
+			add(n.CatchName)
+		}
+		if n.Catch != nil {
+			add(n.Catch)
+		}
+		if n.Finally != nil {
+			add(n.Finally)
+		}
+	case *RangeNode:
+		add(n.Iterable)
+		if n.Key != nil {
+			add(n.Key)
+		}
+		if n.Element != nil {
+			add(n.Element)
+		}
+		add(n.Body)
+	case *ForNode:
+		add(n.Initialisation, n.Condition, n.Body, n.Updater)
+	case *FunctionLiteral:
+		for _, argument := range n.Arguments {
+			add(argument)
+		}
+		add(n.Body)
+	case *FunctionCall:
+		add(n.Function)
+		add(n.Arguments...)
+	case *DeferStatement:
+		add(n.Call)
+	case *GoStatement:
+		add(n.Call)
+	case *SelectStatement:
+		for _, sc := range n.Cases {
+			add(sc.Channel, sc.Send)
+			if sc.Name != nil {
+				add(sc.Name)
+			}
+			add(sc.Body)
+		}
+		if n.Default != nil {
+			add(n.Default)
+		}
+	case *YieldExpression:
+		add(n.YieldValue)
+	case *AwaitExpression:
+		add(n.Task)
+	case *IfNode:
+		add(n.Condition, n.Consequence, n.Alternative)
+	case *InfixNode:
+		add(n.Left, n.Right)
+	case *SufixNode:
+		add(n.Left)
+	case *incrementStatement:
+		add(n.Left)
+	}
+	return nodes
+}
@@ -0,0 +1,201 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Tasks run GoScript concurrently. Calling an async function starts a task
+// running it on a goroutine and an evaluator of its own, await waits for the
+// task to finish. Evaluators share their interpreter, whose lock is held by
+// whichever evaluator is running GoScript so objects never see two
+// goroutines at once. Builtins that block, await included, release the lock
+// while they wait.
+
+// acquire takes the interpreter lock for the evaluator.
+func (e *Evaluator) acquire() {
+	e.lock.Lock()
+	e.locked = true
+}
+
+func (e *Evaluator) release() {
+	e.locked = false
+	e.lock.Unlock()
+}
+
+// blocking runs fn without the interpreter lock, which the caller must
+// hold, so that other tasks run while fn waits. fn must not touch GoScript
+// objects.
+func (i *interpreter) blocking(fn func()) {
+	i.lock.Unlock()
+	defer i.lock.Lock()
+	fn()
+}
+
+// spawn returns an evaluator for a new task. Its call stack starts at the
+// global scope it shares with e.
+func (e *Evaluator) spawn() *Evaluator {
+	return &Evaluator{
+		interpreter: e.interpreter,
+		debug:       e.debug,
+		callStack:   []*Frame{e.globals},
+		moduleDir:   e.moduleDir,
+	}
+}
+
+// startTask calls an async function as a new task.
+func (e *Evaluator) startTask(fn *Function, args []Object, line int) (Object, error) {
+	if len(args) != len(fn.Arguments) {
+		return &Nil{}, newTypeError("function '%s' takes %d arguments only %d was given", fn.Name, len(fn.Arguments), len(args))
+	}
+	task := newTask()
+	child := e.spawn()
+	go func() {
+		child.acquire()
+		defer child.release()
+		task.finish(child.callFunction(fn, args, line))
+	}()
+	return task, nil
+}
+
+// startGoroutine calls fn on a goroutine of its own for a go statement.
+// Nothing waits for a goroutine so an error escaping it is reported on the
+// error output, and os.exit() in it ends the program, like in Go.
+func (e *Evaluator) startGoroutine(fn Object, args []Object, line int) {
+	child := e.spawn()
+	go func() {
+		child.acquire()
+		defer child.release()
+		_, err := child.callObject(fn, args, line)
+		var exit *ExitError
+		if errors.As(err, &exit) {
+			child.exitProgram(exit)
+			return
+		}
+		if err != nil {
+			fmt.Fprintln(child.errOut, toRuntimeError(err).Traceback())
+		}
+	}()
+}
+
+// exitProgram stops the program with the exit of a goroutine, Evaluate
+// returns it to whoever runs the program so they decide what exiting means.
+// The first exit wins.
+func (e *Evaluator) exitProgram(exit *ExitError) {
+	if e.exit != nil {
+		return
+	}
+	e.exit = exit
+	e.limited = true
+	e.halt(exit)
+}
+
+// Task is the result of calling an async function, or of gather, that
+// await waits for.
+type Task struct {
+	nativeObject
+	done   chan struct{}
+	result Object
+	err    error
+}
+
+func newTask() *Task {
+	return &Task{nativeObject: nativeObject{"task"}, done: make(chan struct{})}
+}
+
+// finish records the outcome of the task and wakes whoever awaits it.
+func (t *Task) finish(result Object, err error) {
+	t.result, t.err = result, err
+	close(t.done)
+}
+
+// wait blocks e until the task finishes and gives its outcome, an error
+// raised by the task is raised again by every await of it.
+func (t *Task) wait(e *Evaluator) (Object, error) {
+	err := e.wait(func(stop <-chan struct{}) {
+		select {
+		case <-t.done:
+		case <-stop:
+		}
+	})
+	if err != nil {
+		return &Nil{}, err
+	}
+	if t.err != nil {
+		return &Nil{}, t.err
+	}
+	return t.result, nil
+}
+
+func (t *Task) finished() bool {
+	select {
+	case <-t.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// gsgather is gather(tasks), a task finishing with the array of results of
+// tasks once they have all finished, or with the error of the first task
+// in the array that failed.
+func gsgather(args []Object) (Object, error) {
+	if err := checkArgs("gather", args, 1, 1); err != nil {
+		return &Nil{}, err
+	}
+	array, ok := args[0].(*Array)
+	if !ok {
+		return &Nil{}, newTypeError("gather() expects an array of tasks, got %s", args[0].Type())
+	}
+	tasks := make([]*Task, len(array.Elements))
+	for i, element := range array.Elements {
+		task, ok := element.(*Task)
+		if !ok {
+			return &Nil{}, newTypeError("gather() expects an array of tasks, got %s in it", element.Type())
+		}
+		tasks[i] = task
+	}
+
+	gathered := newTask()
+	go func() {
+		results := make([]Object, len(tasks))
+		for i, task := range tasks {
+			<-task.done
+			if task.err != nil {
+				gathered.finish(&Nil{}, task.err)
+				return
+			}
+			results[i] = task.result
+		}
+		gathered.finish(&Array{Elements: results}, nil)
+	}()
+	return gathered, nil
+}
+
+// GetAttribute gives done, whether the task has finished.
+func (t *Task) GetAttribute(name string) (Object, bool) {
+	if name == "done" {
+		return &Boolean{value: t.finished()}, true
+	}
+	return nil, false
+}
+
+func (t *Task) Type() string {
+	return "task"
+}
+
+func (t *Task) Value() interface{} {
+	return t
+}
+
+func (t *Task) String() *String {
+	return &String{value: "<task>"}
+}
+
+func (t *Task) Equal(other Object) (Object, error) {
+	return &Boolean{value: t == other}, nil
+}
+
+func (t *Task) NotEqual(other Object) (Object, error) {
+	return &Boolean{value: t != other}, nil
+}
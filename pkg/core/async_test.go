@@ -0,0 +1,153 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAsync(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected Object
+		err      string
+	}{
+		{
+			name:     "test await async function",
+			input:    "async func add(a, b) {\n return a + b\n}\nawait add(1, 2)",
+			expected: &Integer{value: 3},
+		},
+		{
+			name:     "test calling async function returns a task",
+			input:    "async func work() {\n return 1\n}\ntask = work()\nresult = await task\n[task.done, result, await task]",
+			expected: &Array{Elements: []Object{&Boolean{value: true}, &Integer{value: 1}, &Integer{value: 1}}},
+		},
+		{
+			name:     "test anonymous async function",
+			input:    "double = async func(n) {\n return n * 2\n}\n(await double(4)) + 1",
+			expected: &Integer{value: 9},
+		},
+		{
+			name:     "test gather keeps order",
+			input:    "import \"time\"\nasync func slow(n) {\n time.sleep(0.01 * (3 - n))\n return n\n}\nawait gather([slow(0), slow(1), slow(2)])",
+			expected: &Array{Elements: []Object{&Integer{value: 0}, &Integer{value: 1}, &Integer{value: 2}}},
+		},
+		{
+			name:     "test tasks share globals",
+			input:    "counter = 0\nasync func count() {\n for i := 0; i < 100; i++ {\n  counter = counter + 1\n }\n}\nawait gather([count(), count(), count(), count()])\ncounter",
+			expected: &Integer{value: 400},
+		},
+		{
+			name:     "test awaiting a task started by a task",
+			input:    "async func inner() {\n return 5\n}\nasync func outer() {\n return (await inner()) * 2\n}\nawait outer()",
+			expected: &Integer{value: 10},
+		},
+		{
+			name:     "test error propagates through await",
+			input:    "async func fail() {\n throw \"boom\"\n}\nmsg = \"\"\ntry {\n await fail()\n} catch e {\n msg = e.message\n}\nmsg",
+			expected: &String{value: "boom"},
+		},
+		{
+			name:  "test gather fails with the first error",
+			input: "async func ok() {\n return 1\n}\nasync func fail() {\n return 1 / 0\n}\nawait gather([ok(), fail()])",
+			err:   "Division by zero",
+		},
+		{
+			name:  "test await needs a task",
+			input: "await 1",
+			err:   "object of type integer can't be awaited",
+		},
+		{
+			name:  "test gather needs tasks",
+			input: "gather([1])",
+			err:   "gather() expects an array of tasks, got integer in it",
+		},
+		{
+			name:  "test async function argument count",
+			input: "async func one(a) {\n return a\n}\none()",
+			err:   "function 'one' takes 1 arguments only 0 was given",
+		},
+		{
+			name:     "test awaiting a failed task twice gives the same trace",
+			input:    "async func fail() {\n throw \"boom\"\n}\nfunc wait(t) {\n return await t\n}\nt = fail()\ntry { wait(t) } catch e { first = e.stack }\ntry { wait(t) } catch e { second = e.stack }\n[first, second]",
+			expected: &Array{Elements: []Object{&String{value: "at fail (line 7)\nat wait (line 8)"}, &String{value: "at fail (line 7)\nat wait (line 9)"}}},
+		},
+		{
+			name:     "test rethrowing leaves the caught trace alone",
+			input:    "caught = 0\nfunc fail() {\n throw \"boom\"\n}\nfunc rethrow() {\n try { fail() } catch e {\n  caught = e\n  throw e\n }\n}\ntry { rethrow() } catch e { outer = e.stack }\n[caught.stack, outer]",
+			expected: &Array{Elements: []Object{&String{value: "at fail (line 6)"}, &String{value: "at fail (line 6)\nat rethrow (line 11)"}}},
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := evalInput(test.input)
+			if test.err != "" {
+				if err == nil || toRuntimeError(err).Message != test.err {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestAsyncRunsConcurrently(t *testing.T) {
+	input := "import \"time\"\nasync func nap() {\n time.sleep(0.2)\n}\nawait gather([nap(), nap(), nap(), nap(), nap()])"
+	start := time.Now()
+	if _, err := evalInput(input); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 800*time.Millisecond {
+		t.Fatalf("expected the tasks to sleep at the same time, took %v", elapsed)
+	}
+}
+
+func TestGoroutineExit(t *testing.T) {
+	input := "import \"os\"\nch = make_chan()\nfunc quit() {\n os.exit(42)\n}\ngo quit()\nch.recv()"
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			_, err := evalInputWith(input, backend.vm)
+			var exit *ExitError
+			if !errors.As(err, &exit) || exit.Code != 42 {
+				t.Fatalf("expected exit code 42, got %v", err)
+			}
+		})
+	}
+}
+
+func TestGoroutineErrorOutput(t *testing.T) {
+	input := "import \"time\"\nfunc fail() {\n throw \"goroutine failed\"\n}\ngo fail()\ntime.sleep(0.1)"
+	program, err := NewV1Parser(NewV1Lexer(input), false).ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	evaluator := NewEvaluator(false)
+	var errOut bytes.Buffer
+	evaluator.SetErrorOutput(&errOut)
+	if _, err := evaluator.Evaluate(program); err != nil {
+		t.Fatal(err)
+	}
+	// the goroutine writes holding the interpreter lock, which the next
+	// evaluation takes
+	after, err := NewV1Parser(NewV1Lexer("1"), false).ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := evaluator.Evaluate(after); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(errOut.String(), "goroutine failed") {
+		t.Fatalf("expected the traceback on the error output, got %q", errOut.String())
+	}
+}
@@ -1,17 +1,133 @@
 package core
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"unicode/utf8"
+)
 
-func gsprint(args []Object) (Object, error) {
-	for _, v := range args {
-		str := v.String()
-		fmt.Print(str.String().value)
+// gslength returns the number of elements of an array or map or of runes in
+// a string.
+func gslength(args []Object) (Object, error) {
+	if err := checkArgs("length", args, 1, 1); err != nil {
+		return &Nil{}, err
+	}
+	switch obj := args[0].(type) {
+	case *String:
+		return &Integer{value: int64(utf8.RuneCountInString(obj.value))}, nil
+	case *Array:
+		return &Integer{value: int64(len(obj.Elements))}, nil
+	case *Map:
+		return &Integer{value: int64(len(obj.keys))}, nil
 	}
-	fmt.Print("\n")
-	return &Nil{}, nil
+	return &Nil{}, newTypeError("object of type %s has no length", args[0].Type())
 }
 
-func gslength(args []Object) (Object, error) {
-	// TODO: build out builtin len function
-	return &Nil{}, nil
+// checkArgs returns an error unless a builtin was given between min and max
+// arguments.
+func checkArgs(name string, args []Object, min, max int) error {
+	if len(args) >= min && len(args) <= max {
+		return nil
+	}
+	if min == max {
+		return newTypeError("%s() takes %d arguments (%d given)", name, min, len(args))
+	}
+	return newTypeError("%s() takes %d to %d arguments (%d given)", name, min, max, len(args))
+}
+
+// stringArg returns argument i of a builtin, which must be a string.
+func stringArg(name string, args []Object, i int) (string, error) {
+	str, ok := args[i].(*String)
+	if !ok {
+		return "", newTypeError("%s() expects a string, got %s", name, args[i].Type())
+	}
+	return str.value, nil
+}
+
+// intArg returns argument i of a builtin, which must be an integer that fits
+// in an int.
+func intArg(name string, args []Object, i int) (int, error) {
+	integer, ok := args[i].(*Integer)
+	if !ok {
+		return 0, newTypeError("%s() expects an integer, got %s", name, args[i].Type())
+	}
+	if integer.value < math.MinInt || integer.value > math.MaxInt {
+		return 0, newRuntimeError(VALUE_ERROR, "%s() argument %d out of range", name, integer.value)
+	}
+	return int(integer.value), nil
+}
+
+// gsbigint converts an integer, float or string to a BigInt. Strings may use
+// the 0x, 0o and 0b prefixes and floats are truncated towards zero.
+func gsbigint(args []Object) (Object, error) {
+	if len(args) != 1 {
+		return &Nil{}, fmt.Errorf("bigint() takes exactly 1 argument (%d given)", len(args))
+	}
+	switch arg := args[0].(type) {
+	case *Integer:
+		return arg.toBigInt(), nil
+	case *BigInt:
+		return arg, nil
+	case *Decimal:
+		return &BigInt{value: new(big.Int).Quo(arg.coefficient, pow10(arg.scale))}, nil
+	case *Float:
+		if math.IsInf(arg.value, 0) || math.IsNaN(arg.value) {
+			return &Nil{}, fmt.Errorf("bigint() cannot convert %v", arg.value)
+		}
+		value, _ := big.NewFloat(arg.value).Int(nil)
+		return &BigInt{value: value}, nil
+	case *String:
+		value, ok := new(big.Int).SetString(strings.ReplaceAll(strings.TrimSpace(arg.value), "_", ""), 0)
+		if !ok {
+			return &Nil{}, fmt.Errorf("bigint() invalid integer %q", arg.value)
+		}
+		return &BigInt{value: value}, nil
+	}
+	return &Nil{}, fmt.Errorf("bigint() cannot convert %s", args[0].Type())
+}
+
+// gsdecimal converts a number or string to a Decimal. Floats are converted
+// from their shortest representation so decimal(0.1) is exactly 0.1.
+func gsdecimal(args []Object) (Object, error) {
+	if len(args) != 1 {
+		return &Nil{}, fmt.Errorf("decimal() takes exactly 1 argument (%d given)", len(args))
+	}
+	switch arg := args[0].(type) {
+	case *Integer, *BigInt:
+		return promoteNumber(arg, DECIMAL_RANK), nil
+	case *Decimal:
+		return arg, nil
+	case *Float:
+		return floatToDecimal(arg.value)
+	case *String:
+		return ParseDecimal(arg.value)
+	}
+	return &Nil{}, fmt.Errorf("decimal() cannot convert %s", args[0].Type())
+}
+
+// gserror creates an Error object without raising it, error(message) or
+// error(message, type).
+func gserror(args []Object) (Object, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return &Nil{}, fmt.Errorf("error() takes 1 or 2 arguments (%d given)", len(args))
+	}
+	errorType := ERROR_TYPE
+	if len(args) == 2 {
+		errorType = args[1].String().value
+	}
+	return &RuntimeError{ErrorType: errorType, Message: args[0].String().value}, nil
+}
+
+// gspanic stops the current function the way a runtime error does, a
+// deferred function can recover the value with recover().
+func gspanic(args []Object) (Object, error) {
+	if len(args) != 1 {
+		return &Nil{}, fmt.Errorf("panic() takes exactly 1 argument (%d given)", len(args))
+	}
+	if runtimeErr, ok := args[0].(*RuntimeError); ok {
+		return &Nil{}, runtimeErr
+	}
+	return &Nil{}, &RuntimeError{ErrorType: PANIC_ERROR, Message: args[0].String().value, Thrown: args[0]}
 }
@@ -0,0 +1,240 @@
+package core
+
+import (
+	"reflect"
+)
+
+// channelMethods is the method table of Channel.
+func channelMethods() map[string]*GoFunction {
+	return map[string]*GoFunction{
+		"send":  {Name: "send", EvalFunc: (*Evaluator).channelSend},
+		"recv":  {Name: "recv", EvalFunc: (*Evaluator).channelRecv},
+		"close": {Name: "close", Func: channelClose},
+	}
+}
+
+// Channel is a Go channel of GoScript values made by make_chan. Ranging
+// over a channel receives from it until it is closed.
+type Channel struct {
+	nativeObject
+	interpreter *interpreter
+	ch          chan Object
+}
+
+func newChannel(i *interpreter, size int) *Channel {
+	return &Channel{nativeObject: nativeObject{"channel"}, interpreter: i, ch: make(chan Object, size)}
+}
+
+// makeChan is make_chan() for an unbuffered channel or make_chan(n) for a
+// channel buffering n values.
+func (e *Evaluator) makeChan(args []Object) (Object, error) {
+	if err := checkArgs("make_chan", args, 0, 1); err != nil {
+		return &Nil{}, err
+	}
+	size := 0
+	if len(args) == 1 {
+		n, err := intArg("make_chan", args, 0)
+		if err != nil {
+			return &Nil{}, err
+		}
+		if n < 0 {
+			return &Nil{}, newRuntimeError(VALUE_ERROR, "make_chan() size must not be negative, got %d", n)
+		}
+		size = n
+	}
+	return newChannel(e.interpreter, size), nil
+}
+
+func channelArg(name string, args []Object, count int) (*Channel, error) {
+	if err := checkArgs(name, args, count, count); err != nil {
+		return nil, err
+	}
+	ch, ok := args[0].(*Channel)
+	if !ok {
+		return nil, newTypeError("%s() expects a channel, got %s", name, args[0].Type())
+	}
+	return ch, nil
+}
+
+func closedChannelError(err *error) {
+	if recover() != nil {
+		*err = newRuntimeError(CHANNEL_ERROR, "send on closed channel")
+	}
+}
+
+// channelSend is ch.send(value), waiting until the value is received or
+// buffered.
+func (e *Evaluator) channelSend(args []Object) (Object, error) {
+	ch, err := channelArg("send", args, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	waitErr := e.wait(func(stop <-chan struct{}) {
+		defer closedChannelError(&err)
+		select {
+		case ch.ch <- args[1]:
+		case <-stop:
+		}
+	})
+	if waitErr != nil {
+		return &Nil{}, waitErr
+	}
+	if err != nil {
+		return &Nil{}, err
+	}
+	return &Nil{}, nil
+}
+
+// channelRecv is ch.recv(), waiting for a value. A closed channel gives
+// nil once its buffered values are received.
+func (e *Evaluator) channelRecv(args []Object) (Object, error) {
+	ch, err := channelArg("recv", args, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	value, _, err := ch.recv()
+	return value, err
+}
+
+func (c *Channel) recv() (Object, bool, error) {
+	var value Object
+	var ok bool
+	err := c.interpreter.wait(func(stop <-chan struct{}) {
+		select {
+		case value, ok = <-c.ch:
+		case <-stop:
+		}
+	})
+	if err != nil {
+		return &Nil{}, false, err
+	}
+	if !ok {
+		return &Nil{}, false, nil
+	}
+	return value, true, nil
+}
+
+// channelClose is ch.close(), closing a channel twice is an error.
+func channelClose(args []Object) (result Object, err error) {
+	ch, err := channelArg("close", args, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	defer func() {
+		if recover() != nil {
+			result, err = &Nil{}, newRuntimeError(CHANNEL_ERROR, "close of closed channel")
+		}
+	}()
+	close(ch.ch)
+	return &Nil{}, nil
+}
+
+// Next receives the next value, the iteration ends when the channel is
+// closed.
+func (c *Channel) Next() (Object, bool, error) {
+	return c.recv()
+}
+
+// evaluateSelect runs a select statement. The channels of the cases and the
+// values to send are evaluated in order first, then the statement waits
+// until a case can go ahead, choosing one at random when several can like
+// Go does.
+func (e *Evaluator) evaluateSelect(n *SelectStatement) (Object, error) {
+	cases := make([]reflect.SelectCase, 0, len(n.Cases)+1)
+	for _, sc := range n.Cases {
+		object, err := e.Evaluate(sc.Channel)
+		if err != nil {
+			return &Nil{}, err
+		}
+		ch, ok := object.(*Channel)
+		if !ok {
+			return &Nil{}, newTypeError("select case expects a channel, got %s", object.Type())
+		}
+		selectCase := reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch.ch)}
+		if sc.Send != nil {
+			value, err := e.Evaluate(sc.Send)
+			if err != nil {
+				return &Nil{}, err
+			}
+			selectCase.Dir = reflect.SelectSend
+			selectCase.Send = reflect.ValueOf(value)
+		}
+		cases = append(cases, selectCase)
+	}
+	if n.Default != nil {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectDefault})
+	}
+
+	var chosen int
+	var received reflect.Value
+	var ok bool
+	var err error
+	waitErr := e.wait(func(stop <-chan struct{}) {
+		defer closedChannelError(&err)
+		// the last case stops the wait, it can only be chosen when there
+		// is no default
+		stopCase := reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(stop)}
+		chosen, received, ok = reflect.Select(append(cases, stopCase))
+	})
+	if waitErr != nil {
+		return &Nil{}, waitErr
+	}
+	if err != nil {
+		return &Nil{}, err
+	}
+
+	if err := e.pushFrame(); err != nil {
+		return &Nil{}, err
+	}
+	defer e.popFrame()
+	if chosen == len(n.Cases) {
+		_, err := e.Evaluate(n.Default)
+		return &Nil{}, err
+	}
+	sc := n.Cases[chosen]
+	if sc.Name != nil {
+		var value Object = &Nil{}
+		if ok {
+			value = received.Interface().(Object)
+		}
+		if sc.Assign == ":=" {
+			e.callStack[e.framePointer].scope[sc.Name.value] = value
+		} else {
+			e.setIdentifier(sc.Name.value, value)
+		}
+	}
+	_, err = e.Evaluate(sc.Body)
+	return &Nil{}, err
+}
+
+// GetAttribute gives len, the number of values buffered, and cap, the size
+// of the buffer.
+func (c *Channel) GetAttribute(name string) (Object, bool) {
+	switch name {
+	case "len":
+		return &Integer{value: int64(len(c.ch))}, true
+	case "cap":
+		return &Integer{value: int64(cap(c.ch))}, true
+	}
+	return nil, false
+}
+
+func (c *Channel) Type() string {
+	return "channel"
+}
+
+func (c *Channel) Value() interface{} {
+	return c.ch
+}
+
+func (c *Channel) String() *String {
+	return &String{value: "<channel>"}
+}
+
+func (c *Channel) Equal(other Object) (Object, error) {
+	return &Boolean{value: c == other}, nil
+}
+
+func (c *Channel) NotEqual(other Object) (Object, error) {
+	return &Boolean{value: c != other}, nil
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChannels(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected Object
+		err      string
+	}{
+		{
+			name:     "test buffered channel",
+			input:    "ch = make_chan(2)\nch.send(1)\nch.send(2)\n[ch.len, ch.cap, ch.recv(), ch.recv(), ch.len]",
+			expected: &Array{Elements: []Object{&Integer{value: 2}, &Integer{value: 2}, &Integer{value: 1}, &Integer{value: 2}, &Integer{value: 0}}},
+		},
+		{
+			name:     "test range over channel fed by goroutine",
+			input:    "ch = make_chan()\nfunc produce(n) {\n for i := 0; i < n; i++ {\n  ch.send(i + 1)\n }\n ch.close()\n}\ngo produce(4)\ntotal = 0\nfor v := range ch {\n total = total + v\n}\ntotal",
+			expected: &Integer{value: 10},
+		},
+		{
+			name:     "test goroutine arguments are evaluated by the go statement",
+			input:    "ch = make_chan()\nfunc send(v) {\n ch.send(v)\n}\nx = 1\ngo send(x)\nx = 2\nch.recv()",
+			expected: &Integer{value: 1},
+		},
+		{
+			name:     "test receive from closed channel",
+			input:    "ch = make_chan(1)\nch.send(1)\nch.close()\n[ch.recv(), ch.recv()]",
+			expected: &Array{Elements: []Object{&Integer{value: 1}, &Nil{}}},
+		},
+		{
+			name:  "test send on closed channel",
+			input: "ch = make_chan(1)\nch.close()\nch.send(1)",
+			err:   "send on closed channel",
+		},
+		{
+			name:  "test close of closed channel",
+			input: "ch = make_chan()\nch.close()\nch.close()",
+			err:   "close of closed channel",
+		},
+		{
+			name:  "test go needs a call",
+			input: "go 1",
+			err:   "expression in go must be function call on line: 1",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := evalInput(test.input)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestSelect(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected Object
+		err      string
+	}{
+		{
+			name:     "test select receives into a new variable",
+			input:    "ch = make_chan(1)\nch.send(5)\nresult = 0\nselect {\ncase v := ch.recv():\n result = v * 2\n}\nresult",
+			expected: &Integer{value: 10},
+		},
+		{
+			name:     "test select assigns to an existing variable",
+			input:    "v = 0\nch = make_chan(1)\nch.send(3)\nselect {\ncase v = ch.recv():\n}\nv",
+			expected: &Integer{value: 3},
+		},
+		{
+			name:     "test select default",
+			input:    "ch = make_chan()\nresult = \"\"\nselect {\ncase v := ch.recv():\n result = \"value\"\ndefault:\n result = \"default\"\n}\nresult",
+			expected: &String{value: "default"},
+		},
+		{
+			name:     "test select timeout",
+			input:    "import \"time\"\nch = make_chan()\nresult = \"\"\nselect {\ncase ch.recv():\n result = \"value\"\ncase time.after(0.05).recv():\n result = \"timeout\"\n}\nresult",
+			expected: &String{value: "timeout"},
+		},
+		{
+			name:     "test select send",
+			input:    "ch = make_chan(1)\nselect {\ncase ch.send(7):\ndefault:\n ch.send(0)\n}\nch.recv()",
+			expected: &Integer{value: 7},
+		},
+		{
+			name:     "test select waits for a goroutine",
+			input:    "import \"time\"\nch = make_chan()\nfunc later() {\n time.sleep(0.01)\n ch.send(\"ready\")\n}\ngo later()\nresult = \"\"\nselect {\ncase msg := ch.recv():\n result = msg\ncase time.after(5).recv():\n result = \"timeout\"\n}\nresult",
+			expected: &String{value: "ready"},
+		},
+		{
+			name:  "test select case must be a channel operation",
+			input: "select {\ncase 1:\n}",
+			err:   "select case must be a channel recv() or send() on line: 2",
+		},
+		{
+			name:  "test select case needs a channel",
+			input: "m = {\"recv\": 1}\nselect {\ncase m.recv():\n}",
+			err:   "select case expects a channel, got map",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := evalInput(test.input)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
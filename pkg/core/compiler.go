@@ -0,0 +1,594 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The compiler turns the tree the parser builds into bytecode for the
+// virtual machine in vm.go. Variables live in the same frames the tree
+// walker uses, so both backends see the same scopes, except for the locals
+// and globals the resolver pins down, see resolver.go. Nodes the compiler
+// has no instructions for are handed to the tree walker with OP_EVAL.
+
+// Opcode is an instruction of the virtual machine. Operands follow the
+// opcode as two byte big endian integers.
+type Opcode byte
+
+const (
+	OP_CONSTANT            Opcode = iota // push constants[a]
+	OP_NIL                               // push nil
+	OP_POP                               // drop the top of the stack
+	OP_DUP                               // push the top of the stack again
+	OP_GET_LOCAL                         // push slot a
+	OP_SET_LOCAL                         // pop into slot a
+	OP_GET_NAME                          // push the variable called names[a]
+	OP_GET_FUNCTION                      // push the function called names[a]
+	OP_GET_GLOBAL                        // push the variable called names[a] from the global table
+	OP_GET_GLOBAL_FUNCTION               // push the function called names[a] from the global table
+	OP_SET_NAME                          // pop into the variable called names[a]
+	OP_DECLARE_NAME                      // pop into names[a] in the current frame
+	OP_ATTRIBUTE                         // replace the top with its attribute names[a]
+	OP_INDEX                             // pop index and object, push object[index]
+	OP_SET_INDEX                         // pop index, object and value, object[index] = value
+	OP_SLICE                             // pop the bounds a says there are and an object, push the slice
+	OP_ARRAY                             // pop a elements, push an array of them
+	OP_MAP_KEY                           // check the top of the stack is a valid map key
+	OP_MAP                               // pop a keys and values, push a map of them
+	OP_ADD                               // pop right and left, push left + right
+	OP_SUB
+	OP_MULTIPLY
+	OP_DIVIDE
+	OP_MODULO
+	OP_EQUAL
+	OP_NOT_EQUAL
+	OP_GREATER
+	OP_LESS
+	OP_GREATER_EQUAL
+	OP_LESS_EQUAL
+	OP_INCREMENT     // replace the top with it plus one
+	OP_DECREMENT     // replace the top with it minus one
+	OP_JUMP          // continue at a
+	OP_JUMP_IF_FALSE // pop, continue at a if it is not truthy
+	OP_PUSH_FRAME    // push a block frame
+	OP_POP_FRAME     // pop the block frame
+	OP_RANGE         // pop an iterable, push an iterator over it, a is 1 for a key and element loop
+	OP_RANGE_NEXT    // push the next element and key of the iterator on top, continue at a when done
+	OP_CALL          // pop a arguments and a function, push the result of calling it
+	OP_TAIL_CALL     // pop a arguments and a function, return the result of calling it
+	OP_RETURN        // pop the value the chunk returns
+	OP_THROW         // pop a value and throw it
+	OP_CLOSURE       // push the function nodes[a] defines
+	OP_YIELD         // pop a value, yield it and push the value sent back
+	OP_AWAIT         // pop a task, push its result
+	OP_EVAL          // push the value of evaluating nodes[a] with the tree walker
+)
+
+// binaryOpcodes maps the operators of InfixNode onto their instructions.
+var binaryOpcodes = map[string]Opcode{
+	"+":  OP_ADD,
+	"-":  OP_SUB,
+	"*":  OP_MULTIPLY,
+	"/":  OP_DIVIDE,
+	"%":  OP_MODULO,
+	"==": OP_EQUAL,
+	"!=": OP_NOT_EQUAL,
+	">":  OP_GREATER,
+	"<":  OP_LESS,
+	">=": OP_GREATER_EQUAL,
+	"<=": OP_LESS_EQUAL,
+}
+
+// Chunk is compiled code, a program, a statement or the body of a function.
+type Chunk struct {
+	code []byte
+	// lines holds the line of the call or yield starting at each offset
+	lines     map[int]int
+	constants []Object
+	names     []string
+	nodes     []Node
+	// slots is the number of local variable slots the chunk uses and params
+	// the slot of each parameter of the function, -1 for a parameter kept in
+	// the function's frame
+	slots  int
+	params []int
+}
+
+// errUnsupported is returned when compiling a node the compiler was told
+// not to hand to the tree walker.
+var errUnsupported = errors.New("node not supported by the compiler")
+
+// compiler compiles one chunk.
+type compiler struct {
+	chunk *Chunk
+	// slotted is set when the local variables of the function get slots, in
+	// which case nodes the compiler has no instructions for are an error
+	// rather than run by the tree walker
+	slotted bool
+	// variables is where the resolver found the variables, see resolver.go
+	variables map[Node]variable
+}
+
+// compileProgram compiles a program, or a statement of one, whose value is
+// the value of the chunk.
+func compileProgram(node Node) (*Chunk, error) {
+	c := &compiler{chunk: &Chunk{lines: map[int]int{}}, variables: resolveProgram(node).variables}
+	if err := c.compile(node); err != nil {
+		return nil, err
+	}
+	return c.chunk, nil
+}
+
+// compileFunction compiles the body of a function, closed being the names
+// bound in the scopes it closes over. When the body has no function
+// literals and nothing the compiler hands to the tree walker its local
+// variables are kept in slots, otherwise in the function's frame.
+func compileFunction(body *BlockStatement, params []*IdentifierLiteral, closed map[string]bool) (*Chunk, error) {
+	chunk, err := compileBody(body, params, closed, true)
+	if err == errUnsupported {
+		return compileBody(body, params, closed, false)
+	}
+	return chunk, err
+}
+
+func compileBody(body *BlockStatement, params []*IdentifierLiteral, closed map[string]bool, slotted bool) (*Chunk, error) {
+	res, err := resolveFunction(body, params, closed, slotted)
+	if err != nil {
+		return nil, err
+	}
+	c := &compiler{chunk: &Chunk{lines: map[int]int{}, slots: res.slots, params: res.params}, slotted: slotted, variables: res.variables}
+	if err := c.compileStatements(body.Statements); err != nil {
+		return nil, err
+	}
+	return c.chunk, nil
+}
+
+func (c *compiler) emit(op Opcode, operands ...int) int {
+	offset := len(c.chunk.code)
+	c.chunk.code = append(c.chunk.code, byte(op))
+	for _, operand := range operands {
+		c.chunk.code = append(c.chunk.code, byte(operand>>8), byte(operand))
+	}
+	return offset
+}
+
+// emitLine emits an instruction whose errors report line.
+func (c *compiler) emitLine(line int, op Opcode, operands ...int) {
+	c.chunk.lines[c.emit(op, operands...)] = line
+}
+
+// emitJump emits a jump whose target is patched later.
+func (c *compiler) emitJump(op Opcode) int {
+	return c.emit(op, 0xffff)
+}
+
+// patchJump points the jump at offset to the next instruction.
+func (c *compiler) patchJump(offset int) error {
+	return c.setOperand(offset, len(c.chunk.code))
+}
+
+func (c *compiler) setOperand(offset, operand int) error {
+	if operand > 0xffff {
+		return fmt.Errorf("code too large to compile")
+	}
+	c.chunk.code[offset+1] = byte(operand >> 8)
+	c.chunk.code[offset+2] = byte(operand)
+	return nil
+}
+
+// emitLoop emits a jump back to start.
+func (c *compiler) emitLoop(start int) error {
+	return c.setOperand(c.emitJump(OP_JUMP), start)
+}
+
+func (c *compiler) constant(value Object) (int, error) {
+	c.chunk.constants = append(c.chunk.constants, value)
+	return c.index(len(c.chunk.constants) - 1)
+}
+
+func (c *compiler) name(name string) (int, error) {
+	for i, known := range c.chunk.names {
+		if known == name {
+			return i, nil
+		}
+	}
+	c.chunk.names = append(c.chunk.names, name)
+	return c.index(len(c.chunk.names) - 1)
+}
+
+func (c *compiler) node(node Node) (int, error) {
+	c.chunk.nodes = append(c.chunk.nodes, node)
+	return c.index(len(c.chunk.nodes) - 1)
+}
+
+func (c *compiler) index(i int) (int, error) {
+	if i > 0xffff {
+		return 0, fmt.Errorf("code too large to compile")
+	}
+	return i, nil
+}
+
+// fallback hands node to the tree walker.
+func (c *compiler) fallback(node Node) error {
+	if c.slotted {
+		return errUnsupported
+	}
+	i, err := c.node(node)
+	if err != nil {
+		return err
+	}
+	c.emit(OP_EVAL, i)
+	return nil
+}
+
+// compileStatements compiles the statements of a function body, which
+// leave nothing on the stack.
+func (c *compiler) compileStatements(statements []Node) error {
+	for _, stmt := range statements {
+		if err := c.compile(stmt); err != nil {
+			return err
+		}
+		c.emit(OP_POP)
+	}
+	return nil
+}
+
+// compile compiles node into code leaving its value on the stack.
+func (c *compiler) compile(node Node) error {
+	if !compilable(node) {
+		// try, defer, go, select and import statements and the like
+		return c.fallback(node)
+	}
+	switch n := node.(type) {
+	case Object:
+		i, err := c.constant(n)
+		if err != nil {
+			return err
+		}
+		c.emit(OP_CONSTANT, i)
+	case *IdentifierLiteral:
+		return c.getVariable(n, n.value, OP_GET_NAME, OP_GET_GLOBAL)
+	case *AttributeNode:
+		if err := c.compile(n.Object); err != nil {
+			return err
+		}
+		i, err := c.name(n.Attribute)
+		if err != nil {
+			return err
+		}
+		c.emit(OP_ATTRIBUTE, i)
+	case *ArrayLiteral:
+		for _, element := range n.Elements {
+			if err := c.compile(element); err != nil {
+				return err
+			}
+		}
+		c.emit(OP_ARRAY, len(n.Elements))
+	case *MapLiteral:
+		for i, key := range n.Keys {
+			if err := c.compile(key); err != nil {
+				return err
+			}
+			c.emit(OP_MAP_KEY)
+			if err := c.compile(n.Values[i]); err != nil {
+				return err
+			}
+		}
+		c.emit(OP_MAP, len(n.Keys))
+	case *IndexNode:
+		if err := c.compileAll(n.Object, n.Index); err != nil {
+			return err
+		}
+		c.emit(OP_INDEX)
+	case *SliceNode:
+		if err := c.compile(n.Object); err != nil {
+			return err
+		}
+		bounds := 0
+		if n.Start != nil {
+			if err := c.compile(n.Start); err != nil {
+				return err
+			}
+			bounds |= 1
+		}
+		if n.End != nil {
+			if err := c.compile(n.End); err != nil {
+				return err
+			}
+			bounds |= 2
+		}
+		c.emit(OP_SLICE, bounds)
+	case *ReturnStatement:
+		if call, ok := n.ReturnValue.(*FunctionCall); ok && n.Tail {
+			if err := c.compileCall(call); err != nil {
+				return err
+			}
+			c.emitLine(call.Line, OP_TAIL_CALL, len(call.Arguments))
+			c.emit(OP_NIL)
+			return nil
+		}
+		if err := c.compileOptional(n.ReturnValue); err != nil {
+			return err
+		}
+		c.emit(OP_RETURN)
+		// the value of the statement, which is never used
+		c.emit(OP_NIL)
+	case *ThrowStatement:
+		if err := c.compile(n.Thrown); err != nil {
+			return err
+		}
+		c.emit(OP_THROW)
+		c.emit(OP_NIL)
+	case *RangeNode:
+		return c.compileRange(n)
+	case *ForNode:
+		return c.compileFor(n)
+	case *FunctionLiteral:
+		if c.slotted {
+			// a closure would need the slots in a frame to close over
+			return errUnsupported
+		}
+		i, err := c.node(n)
+		if err != nil {
+			return err
+		}
+		c.emit(OP_CLOSURE, i)
+	case *BlockStatement:
+		for _, stmt := range n.Statements {
+			if err := c.compile(stmt); err != nil {
+				return err
+			}
+			c.emit(OP_POP)
+		}
+		c.emit(OP_NIL)
+	case *FunctionCall:
+		if err := c.compileCall(n); err != nil {
+			return err
+		}
+		c.emitLine(n.Line, OP_CALL, len(n.Arguments))
+	case *YieldExpression:
+		if err := c.compileOptional(n.YieldValue); err != nil {
+			return err
+		}
+		c.emitLine(n.Line, OP_YIELD)
+	case *AwaitExpression:
+		if err := c.compile(n.Task); err != nil {
+			return err
+		}
+		c.emit(OP_AWAIT)
+	case *IfNode:
+		return c.compileIf(n)
+	case *InfixNode:
+		return c.compileInfix(n)
+	case *SufixNode:
+		name := n.Left.String().value
+		if err := c.getVariable(n, name, OP_GET_NAME, OP_GET_GLOBAL); err != nil {
+			return err
+		}
+		if n.Operator == "++" {
+			c.emit(OP_INCREMENT)
+		} else {
+			c.emit(OP_DECREMENT)
+		}
+		c.emit(OP_DUP)
+		return c.setVariable(n, name, OP_SET_NAME)
+	case *incrementStatement:
+		if err := c.getVariable(n, n.Left.value, OP_GET_NAME, OP_GET_GLOBAL); err != nil {
+			return err
+		}
+		if n.Operator == "++" {
+			c.emit(OP_INCREMENT)
+		} else {
+			c.emit(OP_DECREMENT)
+		}
+		if err := c.setVariable(n, n.Left.value, OP_DECLARE_NAME); err != nil {
+			return err
+		}
+		c.emit(OP_NIL)
+	default:
+		return c.fallback(n)
+	}
+	return nil
+}
+
+// compileCall pushes the function a call refers to and its arguments.
+func (c *compiler) compileCall(n *FunctionCall) error {
+	switch callee := n.Function.(type) {
+	case nil, *IdentifierLiteral:
+		if err := c.getVariable(n, n.Name, OP_GET_FUNCTION, OP_GET_GLOBAL_FUNCTION); err != nil {
+			return err
+		}
+	default:
+		if err := c.compile(callee); err != nil {
+			return err
+		}
+	}
+	return c.compileAll(n.Arguments...)
+}
+
+func (c *compiler) compileAll(nodes ...Node) error {
+	for _, node := range nodes {
+		if err := c.compile(node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compileOptional compiles node or nil if there is none.
+func (c *compiler) compileOptional(node Node) error {
+	if node == nil {
+		c.emit(OP_NIL)
+		return nil
+	}
+	return c.compile(node)
+}
+
+// getVariable pushes the variable called name used by node, looked up with
+// op unless it is a local or read from the global table with globalOp.
+func (c *compiler) getVariable(node Node, name string, op, globalOp Opcode) error {
+	v, ok := c.variables[node]
+	if ok && !v.global {
+		c.emit(OP_GET_LOCAL, v.slot)
+		return nil
+	}
+	if ok {
+		op = globalOp
+	}
+	i, err := c.name(name)
+	if err != nil {
+		return err
+	}
+	c.emit(op, i)
+	return nil
+}
+
+// setVariable pops into the variable called name assigned by node, stored
+// with op unless it is a local.
+func (c *compiler) setVariable(node Node, name string, op Opcode) error {
+	if v, ok := c.variables[node]; ok && !v.global {
+		c.emit(OP_SET_LOCAL, v.slot)
+		return nil
+	}
+	i, err := c.name(name)
+	if err != nil {
+		return err
+	}
+	c.emit(op, i)
+	return nil
+}
+
+func (c *compiler) compileInfix(n *InfixNode) error {
+	if n.Operator == "=" || n.Operator == ":=" {
+		index, isIndex := n.Left.(*IndexNode)
+		ident, isIdent := n.Left.(*IdentifierLiteral)
+		switch {
+		case isIndex && n.Operator == "=":
+			if err := c.compileAll(n.Right, index.Object, index.Index); err != nil {
+				return err
+			}
+			c.emit(OP_SET_INDEX)
+		case isIdent && n.Operator == ":=":
+			if err := c.compile(n.Right); err != nil {
+				return err
+			}
+			if err := c.setVariable(n, ident.value, OP_DECLARE_NAME); err != nil {
+				return err
+			}
+		case isIdent:
+			if err := c.compile(n.Right); err != nil {
+				return err
+			}
+			if err := c.setVariable(n, ident.value, OP_SET_NAME); err != nil {
+				return err
+			}
+		default:
+			return c.fallback(n)
+		}
+		c.emit(OP_NIL)
+		return nil
+	}
+
+	op, ok := binaryOpcodes[n.Operator]
+	if !ok {
+		return c.fallback(n)
+	}
+	if err := c.compileAll(n.Left, n.Right); err != nil {
+		return err
+	}
+	c.emit(op)
+	return nil
+}
+
+// compileIf leaves the value of the branch taken on the stack.
+func (c *compiler) compileIf(n *IfNode) error {
+	if err := c.compile(n.Condition); err != nil {
+		return err
+	}
+	otherwise := c.emitJump(OP_JUMP_IF_FALSE)
+	if err := c.compile(n.Consequence); err != nil {
+		return err
+	}
+	end := c.emitJump(OP_JUMP)
+	if err := c.patchJump(otherwise); err != nil {
+		return err
+	}
+	if err := c.compileOptional(n.Alternative); err != nil {
+		return err
+	}
+	return c.patchJump(end)
+}
+
+// compileFor compiles a for loop, run in a frame of its own like the tree
+// walker does.
+func (c *compiler) compileFor(n *ForNode) error {
+	c.emit(OP_PUSH_FRAME)
+	if n.Initialisation != nil {
+		if err := c.compile(n.Initialisation); err != nil {
+			return err
+		}
+		c.emit(OP_POP)
+	}
+	start := len(c.chunk.code)
+	if err := c.compile(n.Condition); err != nil {
+		return err
+	}
+	exit := c.emitJump(OP_JUMP_IF_FALSE)
+	if err := c.compile(n.Body); err != nil {
+		return err
+	}
+	c.emit(OP_POP)
+	if n.Updater != nil {
+		if err := c.compile(n.Updater); err != nil {
+			return err
+		}
+		c.emit(OP_POP)
+	}
+	if err := c.emitLoop(start); err != nil {
+		return err
+	}
+	if err := c.patchJump(exit); err != nil {
+		return err
+	}
+	c.emit(OP_POP_FRAME)
+	c.emit(OP_NIL)
+	return nil
+}
+
+// compileRange compiles a range loop. The iterator stays on the stack
+// while the loop runs.
+func (c *compiler) compileRange(n *RangeNode) error {
+	if err := c.compile(n.Iterable); err != nil {
+		return err
+	}
+	c.emit(OP_PUSH_FRAME)
+	pair := 0
+	if n.Element != nil {
+		pair = 1
+	}
+	c.emit(OP_RANGE, pair)
+	start := len(c.chunk.code)
+	exit := c.emitJump(OP_RANGE_NEXT)
+	if err := c.setVariable(n.Key, n.Key.value, OP_DECLARE_NAME); err != nil {
+		return err
+	}
+	if n.Element != nil {
+		if err := c.setVariable(n.Element, n.Element.value, OP_DECLARE_NAME); err != nil {
+			return err
+		}
+	}
+	if err := c.compile(n.Body); err != nil {
+		return err
+	}
+	c.emit(OP_POP)
+	if err := c.emitLoop(start); err != nil {
+		return err
+	}
+	if err := c.patchJump(exit); err != nil {
+		return err
+	}
+	c.emit(OP_POP)
+	c.emit(OP_POP_FRAME)
+	c.emit(OP_NIL)
+	return nil
+}
@@ -0,0 +1,235 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// error types given to the errors raised by the interpreter itself, scripts
+// can raise errors of any type with error(message, type)
+const (
+	ERROR_TYPE          = "Error"
+	TYPE_ERROR          = "TypeError"
+	NAME_ERROR          = "NameError"
+	ZERO_DIVISION_ERROR = "ZeroDivisionError"
+	RECURSION_ERROR     = "RecursionError"
+	PANIC_ERROR         = "Panic"
+	IMPORT_ERROR        = "ImportError"
+	VALUE_ERROR         = "ValueError"
+	INDEX_ERROR         = "IndexError"
+	OS_ERROR            = "OSError"
+	JSON_ERROR          = "JSONError"
+	HTTP_ERROR          = "HTTPError"
+	CHANNEL_ERROR       = "ChannelError"
+	PERMISSION_ERROR    = "PermissionError"
+	// raised when a program goes over its limits or is cancelled, see
+	// limits.go
+	STEP_LIMIT_ERROR       = "StepLimitError"
+	CALL_DEPTH_LIMIT_ERROR = "CallDepthLimitError"
+	TIME_LIMIT_ERROR       = "TimeLimitError"
+	ALLOCATION_LIMIT_ERROR = "AllocationLimitError"
+	CANCELLED_ERROR        = "CancelledError"
+)
+
+var _ Error = (*RuntimeError)(nil)
+
+// RuntimeError is the Error object behind throw and behind every failure
+// raised while evaluating a script. It is both a GoScript Object, so it can
+// be bound by catch and inspected, and a Go error, so it travels up through
+// Evaluate like any other failure until a try statement stops it.
+type RuntimeError struct {
+	ErrorType  string
+	Message    string
+	StackTrace []string
+	// Thrown holds the original value when something other than an Error
+	// object was thrown
+	Thrown Object
+}
+
+func newRuntimeError(errorType string, format string, args ...interface{}) *RuntimeError {
+	return &RuntimeError{ErrorType: errorType, Message: fmt.Sprintf(format, args...)}
+}
+
+func newTypeError(format string, args ...interface{}) *RuntimeError {
+	return newRuntimeError(TYPE_ERROR, format, args...)
+}
+
+func newZeroDivisionError() *RuntimeError {
+	return newRuntimeError(ZERO_DIVISION_ERROR, "Division by zero")
+}
+
+// newOSError reports a failed file system or process operation.
+func newOSError(err error) *RuntimeError {
+	return newRuntimeError(OS_ERROR, "%s", err.Error())
+}
+
+// toRuntimeError returns err as a RuntimeError, wrapping plain Go errors
+// returned by builtins in a generic Error.
+func toRuntimeError(err error) *RuntimeError {
+	var runtimeErr *RuntimeError
+	if errors.As(err, &runtimeErr) {
+		return runtimeErr
+	}
+	return &RuntimeError{ErrorType: ERROR_TYPE, Message: err.Error()}
+}
+
+func (r *RuntimeError) Error() string {
+	return r.Message
+}
+
+// Traceback formats the error the way it is reported when nothing catches
+// it, innermost call first.
+func (r *RuntimeError) Traceback() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s: %s", r.ErrorType, r.Message)
+	for _, line := range r.StackTrace {
+		sb.WriteString("\n    " + line)
+	}
+	return sb.String()
+}
+
+func (r *RuntimeError) GetAttribute(name string) (Object, bool) {
+	switch name {
+	case "message":
+		return &String{value: r.Message}, true
+	case "type":
+		return &String{value: r.ErrorType}, true
+	case "stack":
+		return &String{value: strings.Join(r.StackTrace, "\n")}, true
+	case "value":
+		if r.Thrown != nil {
+			return r.Thrown, true
+		}
+		return r, true
+	}
+	return nil, false
+}
+
+func (r *RuntimeError) Type() string {
+	return "error"
+}
+
+func (r *RuntimeError) Value() interface{} {
+	return r.Message
+}
+
+func (r *RuntimeError) String() *String {
+	return &String{value: fmt.Sprintf("%s: %s", r.ErrorType, r.Message)}
+}
+
+func (r *RuntimeError) Add(other Object) (Object, error) {
+	return nil, newTypeError("Addition operation not supported for error")
+}
+
+func (r *RuntimeError) Sub(other Object) (Object, error) {
+	return nil, newTypeError("Subtraction operation not supported for error")
+}
+
+func (r *RuntimeError) Multiply(other Object) (Object, error) {
+	return nil, newTypeError("Multiplication operation not supported for error")
+}
+
+func (r *RuntimeError) Divide(other Object) (Object, error) {
+	return nil, newTypeError("Division operation not supported for error")
+}
+
+func (r *RuntimeError) Modulo(other Object) (Object, error) {
+	return nil, newTypeError("Modulo operation not supported for error")
+}
+
+func (r *RuntimeError) Equal(other Object) (Object, error) {
+	return &Boolean{value: r == other}, nil
+}
+
+func (r *RuntimeError) NotEqual(other Object) (Object, error) {
+	return &Boolean{value: r != other}, nil
+}
+
+func (r *RuntimeError) GreaterThan(other Object) (Object, error) {
+	return nil, newTypeError("Comparison operation not supported for error")
+}
+
+func (r *RuntimeError) LessThan(other Object) (Object, error) {
+	return nil, newTypeError("Comparison operation not supported for error")
+}
+
+func (r *RuntimeError) GreaterThanOrEqual(other Object) (Object, error) {
+	return nil, newTypeError("Comparison operation not supported for error")
+}
+
+func (r *RuntimeError) LessThanOrEqual(other Object) (Object, error) {
+	return nil, newTypeError("Comparison operation not supported for error")
+}
+
+func (r *RuntimeError) GetColumn() int {
+	return 0
+}
+func (r *RuntimeError) GetLine() int {
+	return 0
+}
+
+// traceError records the call of the named function that err is unwinding
+// through in its stack trace. The error is copied first, the same error may
+// be unwinding more than once, awaited twice or caught and thrown again.
+func traceError(err error, name string, line int) error {
+	if !isCatchable(err) {
+		return err
+	}
+	traced := *toRuntimeError(err)
+	trace := traced.StackTrace
+	traced.StackTrace = append(trace[:len(trace):len(trace)], fmt.Sprintf("at %s (line %d)", name, line))
+	return &traced
+}
+
+// returnSignal carries the value of a return statement up to the function
+// call that is returning. It is never caught by try.
+type returnSignal struct {
+	value Object
+}
+
+func (r *returnSignal) Error() string {
+	return "'return' outside function"
+}
+
+// tailCall is a return statement calling a GoScript function in tail
+// position. callFunction makes the call in place of the function returning,
+// so neither the call stack nor the Go stack grows. It is never caught by
+// try.
+type tailCall struct {
+	function *Function
+	args     []Object
+	line     int
+}
+
+func (t *tailCall) Error() string {
+	return "'return' outside function"
+}
+
+// ExitError is returned by os.exit. It unwinds the whole script, running
+// deferred calls on the way, and is never caught by try or recover.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// generatorExit unwinds a generator closed while it waits at a yield,
+// running its deferred calls and finally blocks. It is never caught by try.
+type generatorExit struct{}
+
+func (g *generatorExit) Error() string {
+	return "generator closed"
+}
+
+// isCatchable reports whether err is a failure a try statement may handle as
+// opposed to control flow that merely passes through it.
+func isCatchable(err error) bool {
+	switch err.(type) {
+	case *returnSignal, *tailCall, *ExitError, *generatorExit:
+		return false
+	}
+	return true
+}
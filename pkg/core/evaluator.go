@@ -1,6 +1,14 @@
 package core
 
-import "fmt"
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"unicode/utf8"
+)
 
 const (
 	INT_TYPE int = iota
@@ -9,43 +17,264 @@ const (
 	FUNC_TYPE
 )
 
+// Evaluator runs GoScript on a call stack of its own. Tasks run on
+// evaluators of their own sharing the interpreter of the evaluator that
+// started them, see async.go.
 type Evaluator struct {
+	*interpreter
 	debug        bool
-	callStack    []Frame
+	callStack    []*Frame
 	framePointer int
+	// locked is set while the evaluator holds the interpreter lock
+	locked bool
+	// resolved is where the resolver found the variables of the code the
+	// tree walker is running, see resolver.go
+	resolved *resolved
+	// generator is set on the evaluator running a generator's body, see
+	// generator.go
+	generator *coroutine
+	// stack is the operand stack of the virtual machine, see vm.go
+	stack []Object
+	// depth is the number of function calls in progress, see limits.go
+	depth int
+
+	// the module being imported, see modules.go
+	moduleDir string
+	importing []string
+}
+
+// interpreter is the state the evaluators of a program share.
+type interpreter struct {
+	// lock is held by the evaluator running GoScript, builtins that block
+	// release it so that other tasks run in the meantime
+	lock sync.Mutex
+	// globals is the global scope, the bottom frame of every call stack
+	globals  *Frame
+	builtins map[string]Object
+	// methods holds the methods of the builtin types keyed by Type()
+	methods map[string]map[string]*GoFunction
+
+	// vm is set when programs run on the bytecode virtual machine, chunks
+	// holds the compiled function bodies, resolutions the function bodies
+	// resolved for the tree walker and closures the names bound in the
+	// scopes each function closes over, see vm.go and resolver.go
+	vm          bool
+	chunks      map[*BlockStatement]*Chunk
+	resolutions map[*BlockStatement]*resolution
+	closures    map[*BlockStatement]map[string]bool
+	// optimisation is the level imported modules are optimised at, see
+	// optimiser.go
+	optimisation int
+
+	// limits bound the programs run, stop is done once the one running has
+	// to stop, because its context is done or its time is up, and halt
+	// stops it, steps and allocations count what it used and limited is set
+	// when any of it has to be checked, see limits.go
+	limits      Limits
+	stop        context.Context
+	halt        context.CancelCauseFunc
+	steps       int
+	allocations int
+	limited     bool
+	// exit is the os.exit() of a goroutine, which ends the program the
+	// next time it runs if it is not running, see async.go
+	exit *ExitError
+
+	// policy is what the standard library may touch, see policy.go
+	policy Policy
+
+	// module loading, see modules.go, scriptDir is the directory of the
+	// script run, or the working directory when empty, whose modules the
+	// policy trusts, see policy.go
+	searchPath []string
+	modules    map[string]*Module
+	scriptDir  string
+
+	// args are the script arguments, see os.go
+	args []string
+	// out receives what print writes and errOut the tracebacks of errors
+	// nothing catches, see format.go
+	out    io.Writer
+	errOut io.Writer
+	// in is read by input and stdin, see input.go
+	in        *bufio.Reader
+	inputLock sync.Mutex
+	stdin     *Stdin
 }
 
 func NewEvaluator(debug bool) *Evaluator {
-	evaluator := &Evaluator{debug: debug}
-	frame := Frame{scope: map[string]Object{}} // global scope
+	evaluator := &Evaluator{debug: debug, interpreter: &interpreter{modules: map[string]*Module{}, chunks: map[*BlockStatement]*Chunk{}, resolutions: map[*BlockStatement]*resolution{}, closures: map[*BlockStatement]map[string]bool{}, policy: AllowAll(), out: os.Stdout, errOut: os.Stderr, in: bufio.NewReader(os.Stdin)}}
+	evaluator.stdin = &Stdin{nativeObject: nativeObject{"stdin"}, interpreter: evaluator.interpreter}
+	evaluator.stop, evaluator.halt = context.WithCancelCause(context.Background())
 
-	// setup builtin functions in root scope
-	frame.scope["print"] = &GoFunction{Name: "print", Func: gsprint}
-	frame.scope["length"] = &GoFunction{Name: "length", Func: gslength}
-	evaluator.callStack = make([]Frame, 10000) // call stack of 10000
-	evaluator.callStack[evaluator.framePointer] = frame
+	// setup builtin functions, they are visible from every module
+	evaluator.builtins = map[string]Object{
+		"print":     &GoFunction{Name: "print", Func: evaluator.print},
+		"println":   &GoFunction{Name: "println", Func: evaluator.print},
+		"printf":    &GoFunction{Name: "printf", Func: evaluator.printf},
+		"sprintf":   &GoFunction{Name: "sprintf", Func: gssprintf},
+		"input":     &GoFunction{Name: "input", Func: evaluator.input},
+		"gather":    &GoFunction{Name: "gather", Func: gsgather},
+		"make_chan": &GoFunction{Name: "make_chan", EvalFunc: (*Evaluator).makeChan},
+		"stdin":     evaluator.stdin,
+		"length":    &GoFunction{Name: "length", Func: gslength},
+		"bigint":    &GoFunction{Name: "bigint", Func: gsbigint},
+		"decimal":   &GoFunction{Name: "decimal", Func: gsdecimal},
+		"error":     &GoFunction{Name: "error", Func: gserror},
+		"panic":     &GoFunction{Name: "panic", Func: gspanic},
+		"recover":   &GoFunction{Name: "recover", EvalFunc: (*Evaluator).recover},
+	}
+	evaluator.methods = map[string]map[string]*GoFunction{
+		"string":         stringMethods(),
+		"array":          arrayMethods(),
+		"file":           fileMethods(),
+		"map":            mapMethods(),
+		"regexp":         regexpMethods(),
+		"time":           timeMethods(),
+		"response":       httpResponseMethods(),
+		"request":        httpRequestMethods(),
+		"responsewriter": responseWriterMethods(),
+		"stdin":          stdinMethods(),
+		"channel":        channelMethods(),
+		"waitgroup":      waitGroupMethods(),
+		"mutex":          mutexMethods(),
+		"generator":      generatorMethods(),
+	}
+	evaluator.globals = &Frame{scope: map[string]Object{}}
+	evaluator.callStack = []*Frame{evaluator.globals}
 
 	return evaluator
 }
 
+// Evaluate evaluates a node, taking the interpreter lock first unless the
+// evaluator already holds it. Called without the lock it runs a program on
+// the backend set with SetVM, nodes evaluated with the lock held are part of
+// one being run by the tree walker.
 func (e *Evaluator) Evaluate(exp Node) (Object, error) {
+	if !e.locked {
+		return e.EvaluateContext(context.Background(), exp)
+	}
+	return e.evaluate(exp)
+}
+
+func (e *Evaluator) evaluate(exp Node) (Object, error) {
+	if e.limited {
+		if err := e.step(); err != nil {
+			return &Nil{}, err
+		}
+	}
 	switch n := exp.(type) {
 	case Object:
 		return n, nil
 	case *IdentifierLiteral:
-		variable, err := e.getIdentifier(n.String().value)
-		if err != nil {
-			return &Nil{}, fmt.Errorf("variable '%s' is not defined", n.String())
+		variable, ok := e.lookup(n, n.value)
+		if !ok {
+			return &Nil{}, newRuntimeError(NAME_ERROR, "variable '%s' is not defined", n.value)
 		}
 		return variable, nil
+	case *AttributeNode:
+		object, err := e.Evaluate(n.Object)
+		if err != nil {
+			return &Nil{}, err
+		}
+		return e.getAttribute(object, n.Attribute)
+	case *ArrayLiteral:
+		elements := make([]Object, 0, len(n.Elements))
+		for _, element := range n.Elements {
+			value, err := e.Evaluate(element)
+			if err != nil {
+				return &Nil{}, err
+			}
+			elements = append(elements, value)
+		}
+		return e.allocated(&Array{Elements: elements}, nil)
+	case *MapLiteral:
+		m := NewMap()
+		for i, keyNode := range n.Keys {
+			key, err := e.Evaluate(keyNode)
+			if err != nil {
+				return &Nil{}, err
+			}
+			name, err := mapKey(key)
+			if err != nil {
+				return &Nil{}, err
+			}
+			value, err := e.Evaluate(n.Values[i])
+			if err != nil {
+				return &Nil{}, err
+			}
+			m.Set(name, value)
+		}
+		return e.allocated(m, nil)
+	case *IndexNode:
+		object, err := e.Evaluate(n.Object)
+		if err != nil {
+			return &Nil{}, err
+		}
+		index, err := e.Evaluate(n.Index)
+		if err != nil {
+			return &Nil{}, err
+		}
+		return indexObject(object, index)
+	case *SliceNode:
+		object, err := e.Evaluate(n.Object)
+		if err != nil {
+			return &Nil{}, err
+		}
+		var start, end Object
+		if n.Start != nil {
+			if start, err = e.Evaluate(n.Start); err != nil {
+				return &Nil{}, err
+			}
+		}
+		if n.End != nil {
+			if end, err = e.Evaluate(n.End); err != nil {
+				return &Nil{}, err
+			}
+		}
+		return e.allocated(sliceObject(object, start, end))
+	case *ReturnStatement:
+		if call, ok := n.ReturnValue.(*FunctionCall); ok && n.Tail {
+			fn, args, err := e.evaluateCall(call)
+			if err != nil {
+				return &Nil{}, err
+			}
+			return &Nil{}, e.returnCall(fn, args, call.Line)
+		}
+		var value Object = &Nil{}
+		if n.ReturnValue != nil {
+			result, err := e.Evaluate(n.ReturnValue)
+			if err != nil {
+				return &Nil{}, err
+			}
+			value = result
+		}
+		return &Nil{}, &returnSignal{value: value}
+	case *ThrowStatement:
+		thrown, err := e.Evaluate(n.Thrown)
+		if err != nil {
+			return &Nil{}, err
+		}
+		return &Nil{}, throw(thrown)
+	case *TryStatement:
+		return e.evaluateTry(n)
+	case *RangeNode:
+		return e.evaluateRange(n)
 	case *ForNode:
-		e.pushFrame()
-		defer e.popFrame()
-		if _, err := e.Evaluate(n.Initialisation); err != nil {
+		if err := e.pushFrame(); err != nil {
 			return &Nil{}, err
 		}
+		defer e.popFrame()
+		if n.Initialisation != nil {
+			if _, err := e.Evaluate(n.Initialisation); err != nil {
+				return &Nil{}, err
+			}
+		}
 
 		for {
+			if err := e.checkpoint(); err != nil {
+				return &Nil{}, err
+			}
 			cond, err := e.Evaluate(n.Condition)
 			if err != nil {
 				return &Nil{}, err
@@ -58,15 +287,16 @@ func (e *Evaluator) Evaluate(exp Node) (Object, error) {
 				return &Nil{}, err
 			}
 
-			if _, err := e.Evaluate(n.Updater); err != nil {
-				return &Nil{}, err
+			if n.Updater != nil {
+				if _, err := e.Evaluate(n.Updater); err != nil {
+					return &Nil{}, err
+				}
 			}
 		}
 
 		return &Nil{}, nil
 	case *FunctionLiteral:
-		e.callStack[e.framePointer].scope[n.Name] = &Function{Name: n.Name, Body: n.Body, Arguments: n.Arguments}
-		return &Nil{}, nil
+		return e.allocated(e.makeFunction(n), nil)
 	case *BlockStatement:
 		for _, exp := range n.Statements {
 			_, err := e.Evaluate(exp)
@@ -76,46 +306,74 @@ func (e *Evaluator) Evaluate(exp Node) (Object, error) {
 		}
 		return &Nil{}, nil
 	case *FunctionCall:
-		fn, err := e.getIdentifier(n.Name)
+		fn, args, err := e.evaluateCall(n)
 		if err != nil {
-			return &Nil{}, fmt.Errorf("function '%s' is not defined", n.Name)
+			return &Nil{}, err
 		}
-		e.pushFrame()
-		defer e.popFrame()
-		switch fn := fn.(type) {
-		case *GoFunction:
-			var args []Object
-			for _, arg := range n.Arguments {
-				val, err := e.Evaluate(arg)
-				if err != nil {
-					return &Nil{}, err
-				}
-				args = append(args, val)
-			}
-
-			return fn.Call(args)
-		case *Function:
-			if len(n.Arguments) != len(fn.Arguments) {
-				return &Nil{}, fmt.Errorf("function '%s' takes %d arguments only %d was given", fn.Name, len(fn.Arguments), len(n.Arguments))
-			}
-			for i, argIdent := range fn.Arguments {
-				arg, err := e.Evaluate(n.Arguments[i])
-				if err != nil {
-					return &Nil{}, err
-				}
-				e.callStack[e.framePointer].scope[argIdent.value] = arg
+		return e.callObject(fn, args, n.Line)
+	case *GoStatement:
+		// like defer the function and its arguments are evaluated here
+		fn, args, err := e.evaluateCall(n.Call)
+		if err != nil {
+			return &Nil{}, err
+		}
+		e.startGoroutine(fn, args, n.Call.Line)
+		return &Nil{}, nil
+	case *SelectStatement:
+		return e.evaluateSelect(n)
+	case *YieldExpression:
+		var value Object = &Nil{}
+		if n.YieldValue != nil {
+			result, err := e.Evaluate(n.YieldValue)
+			if err != nil {
+				return &Nil{}, err
 			}
-			return e.Evaluate(fn.Body)
+			value = result
 		}
-
-		return &Nil{}, fmt.Errorf("function '%s' is not defined", n.Name)
+		if e.generator == nil {
+			return &Nil{}, fmt.Errorf("'yield' outside generator on line: %d", n.Line)
+		}
+		return e.generator.yield(e, value)
+	case *AwaitExpression:
+		value, err := e.Evaluate(n.Task)
+		if err != nil {
+			return &Nil{}, err
+		}
+		task, ok := value.(*Task)
+		if !ok {
+			return &Nil{}, newTypeError("object of type %s can't be awaited", value.Type())
+		}
+		return task.wait(e)
+	case *ImportStatement:
+		module, err := e.importModule(n.Path)
+		if err != nil {
+			return &Nil{}, err
+		}
+		name := n.Name
+		if name == "" {
+			name = module.Name
+		}
+		e.callStack[e.framePointer].scope[name] = module
+		return &Nil{}, nil
+	case *DeferStatement:
+		frame := e.functionFrame()
+		if frame == nil {
+			return &Nil{}, fmt.Errorf("'defer' outside function on line: %d", n.Line)
+		}
+		// like Go the function and its arguments are evaluated straight
+		// away, only the call itself waits until the function returns
+		fn, args, err := e.evaluateCall(n.Call)
+		if err != nil {
+			return &Nil{}, err
+		}
+		frame.deferred = append(frame.deferred, &deferredCall{function: fn, args: args, line: n.Call.Line})
+		return &Nil{}, nil
 	case *IfNode:
 		condition, err := e.Evaluate(n.Condition)
 		if err != nil {
 			return &Nil{}, err
 		}
-		boolean, _ := condition.(*Boolean)
-		if boolean.value {
+		if isTruthy(condition) {
 			return e.Evaluate(n.Consequence)
 		}
 
@@ -124,150 +382,772 @@ func (e *Evaluator) Evaluate(exp Node) (Object, error) {
 		}
 		return &Nil{}, nil
 	case *InfixNode:
-		switch n.Operator {
-		case "+":
-			left, err := e.Evaluate(n.Left)
-			if err != nil {
-				return &Nil{}, err
-			}
-			right, err := e.Evaluate(n.Right)
-			if err != nil {
-				return &Nil{}, err
-			}
-			return left.Add(right)
-		case "-":
-			left, err := e.Evaluate(n.Left)
-			if err != nil {
-				return &Nil{}, err
-			}
-			right, err := e.Evaluate(n.Right)
-			if err != nil {
-				return &Nil{}, err
-			}
-			return left.Sub(right)
-		case "*":
-			left, err := e.Evaluate(n.Left)
-			if err != nil {
-				return &Nil{}, err
-			}
+		if n.Operator == "=" || n.Operator == ":=" {
 			right, err := e.Evaluate(n.Right)
 			if err != nil {
 				return &Nil{}, err
 			}
-			return left.Multiply(right)
-		case "/":
-			left, err := e.Evaluate(n.Left)
-			if err != nil {
-				return &Nil{}, err
+			if index, ok := n.Left.(*IndexNode); ok && n.Operator == "=" {
+				return &Nil{}, e.assignIndex(index, right)
 			}
-			right, err := e.Evaluate(n.Right)
-			if err != nil {
-				return &Nil{}, err
+			if n.Operator == ":=" {
+				e.declare(n, n.Left.String().value, right)
+			} else {
+				e.assign(n, n.Left.String().value, right)
 			}
-			return left.Divide(right)
-		case "=":
-			right, err := e.Evaluate(n.Right)
-			if err != nil {
-				return &Nil{}, err
-			}
-			e.callStack[e.framePointer].scope[n.Left.String().value] = right
 			return &Nil{}, nil
-		case ">":
-			left, err := e.Evaluate(n.Left)
-			if err != nil {
-				return &Nil{}, err
+		}
+
+		operation, ok := infixOperators[n.Operator]
+		if !ok {
+			return &Nil{}, fmt.Errorf("unknown operator: %s", n.Operator)
+		}
+		left, err := e.Evaluate(n.Left)
+		if err != nil {
+			return &Nil{}, err
+		}
+		right, err := e.Evaluate(n.Right)
+		if err != nil {
+			return &Nil{}, err
+		}
+		return e.allocated(operation(left, right))
+	case *SufixNode:
+		if n.Operator != "++" && n.Operator != "--" {
+			return &Nil{}, fmt.Errorf("unknown operator: %s", n.Operator)
+		}
+		name := n.Left.String().value
+		left, ok := e.lookup(n, name)
+		if !ok {
+			return &Nil{}, newRuntimeError(NAME_ERROR, "variable '%s' is not defined", name)
+		}
+		newVal, err := e.allocated(increment(left, n.Operator))
+		if err != nil {
+			return &Nil{}, err
+		}
+		e.assign(n, name, newVal)
+		return newVal, nil
+	case *incrementStatement:
+		// the variable is in the current frame unless it has a slot
+		value, ok := e.callStack[e.framePointer].scope[n.Left.value]
+		if _, resolved := e.variable(n); resolved {
+			value, ok = e.lookup(n, n.Left.value)
+		}
+		if !ok {
+			return &Nil{}, newRuntimeError(NAME_ERROR, "variable '%s' is not defined", n.Left.value)
+		}
+		newVal, err := e.allocated(increment(value, n.Operator))
+		if err != nil {
+			return &Nil{}, err
+		}
+		e.declare(n, n.Left.value, newVal)
+		return &Nil{}, nil
+	default:
+		return nil, fmt.Errorf("Unknown %T", n)
+	}
+}
+
+// getAttribute returns the attribute of object called name, or one of the
+// methods of its type bound to it.
+func (e *Evaluator) getAttribute(object Object, name string) (Object, error) {
+	if getter, ok := object.(AttributeGetter); ok {
+		if attribute, ok := getter.GetAttribute(name); ok {
+			return attribute, nil
+		}
+	}
+	if method, ok := e.methods[object.Type()][name]; ok {
+		return bindMethod(object, method), nil
+	}
+	return &Nil{}, newTypeError("%s has no attribute '%s'", object.Type(), name)
+}
+
+// makeFunction creates the function a literal defines, closing over the
+// current scopes. A named function is declared in the current frame and
+// the literal's value is nil.
+func (e *Evaluator) makeFunction(n *FunctionLiteral) Object {
+	fn := &Function{Name: n.Name, Body: n.Body, Arguments: n.Arguments, Env: e.captureScopes(), Async: n.Async, Generator: n.Generator}
+	if n.Name == "" {
+		fn.Name = "anonymous"
+		return fn
+	}
+	e.callStack[e.framePointer].scope[n.Name] = fn
+	return &Nil{}
+}
+
+// throw returns the error a throw statement raises with thrown, an Error
+// object is raised as it is and anything else is wrapped in one.
+func throw(thrown Object) error {
+	if runtimeErr, ok := thrown.(*RuntimeError); ok {
+		return runtimeErr
+	}
+	return &RuntimeError{ErrorType: ERROR_TYPE, Message: thrown.String().value, Thrown: thrown}
+}
+
+// increment returns value plus one for ++ or minus one for --.
+func increment(value Object, operator string) (Object, error) {
+	var one Object
+	switch value.(type) {
+	case *Integer:
+		one = &Integer{value: 1}
+	case *Float:
+		one = &Float{value: 1.0}
+	default:
+		return &Nil{}, fmt.Errorf("operator '++' not supported for type %T", value)
+	}
+	// ignore the error as adding or subtracting one of the same type never fails
+	if operator == "--" {
+		newVal, _ := value.Sub(one)
+		return newVal, nil
+	}
+	newVal, _ := value.Add(one)
+	return newVal, nil
+}
+
+// infixOperators maps each binary operator onto the Object method that
+// implements it. Mixed numeric operands are promoted by the methods
+// themselves so 1 + 2.5 and 2n * 3 work without any help from here.
+var infixOperators = map[string]func(Object, Object) (Object, error){
+	"+":  Object.Add,
+	"-":  Object.Sub,
+	"*":  Object.Multiply,
+	"/":  Object.Divide,
+	"%":  Object.Modulo,
+	"==": Object.Equal,
+	"!=": Object.NotEqual,
+	">":  Object.GreaterThan,
+	"<":  Object.LessThan,
+	">=": Object.GreaterThanOrEqual,
+	"<=": Object.LessThanOrEqual,
+}
+
+// evaluateTry runs the body of a try statement, handing any catchable error
+// to the catch block and running the finally block on the way out whatever
+// happened. An error or return inside finally replaces the original outcome.
+func (e *Evaluator) evaluateTry(n *TryStatement) (result Object, err error) {
+	if n.Finally != nil {
+		defer func() {
+			if _, finallyErr := e.Evaluate(n.Finally); finallyErr != nil {
+				result, err = &Nil{}, finallyErr
 			}
-			right, err := e.Evaluate(n.Right)
-			if err != nil {
-				return &Nil{}, err
+		}()
+	}
+
+	_, err = e.Evaluate(n.Body)
+	if err == nil || n.Catch == nil || !isCatchable(err) {
+		return &Nil{}, err
+	}
+
+	if n.CatchName != nil {
+		e.callStack[e.framePointer].scope[n.CatchName.value] = toRuntimeError(err)
+	}
+	_, err = e.Evaluate(n.Catch)
+	return &Nil{}, err
+}
+
+// evaluateRange runs a range loop. Like Go, ranging over an array or string
+// gives the index and the element, strings counting in runes, ranging over a
+// map gives its keys and values in insertion order and ranging over an
+// integer n counts from 0 to n-1. Iterators give their values, or a
+// count and the value when the loop names two variables.
+func (e *Evaluator) evaluateRange(n *RangeNode) (Object, error) {
+	if n.Body == nil {
+		return &Nil{}, fmt.Errorf("'range' outside for loop on line: %d", n.Line)
+	}
+	iterable, err := e.Evaluate(n.Iterable)
+	if err != nil {
+		return &Nil{}, err
+	}
+	if err := e.pushFrame(); err != nil {
+		return &Nil{}, err
+	}
+	defer e.popFrame()
+
+	next, err := rangeIterator(iterable, n.Element != nil)
+	if err != nil {
+		return &Nil{}, err
+	}
+	for {
+		if err := e.checkpoint(); err != nil {
+			return &Nil{}, err
+		}
+		key, element, ok, err := next()
+		if err != nil {
+			return &Nil{}, err
+		}
+		if !ok {
+			return &Nil{}, nil
+		}
+		e.declare(n.Key, n.Key.value, key)
+		if n.Element != nil {
+			e.declare(n.Element, n.Element.value, element)
+		}
+		if _, err := e.Evaluate(n.Body); err != nil {
+			return &Nil{}, err
+		}
+	}
+}
+
+// rangeIterator returns a function giving the key and element of each step
+// of a range over iterable until ok is false. pair is set when the loop
+// names two variables.
+func rangeIterator(iterable Object, pair bool) (func() (key, element Object, ok bool, err error), error) {
+	switch iterable := iterable.(type) {
+	case *Array:
+		elements := iterable.Elements
+		i := -1
+		return func() (Object, Object, bool, error) {
+			if i++; i >= len(elements) {
+				return nil, nil, false, nil
 			}
-			return left.GreaterThan(right)
-		case "<":
-			left, err := e.Evaluate(n.Left)
-			if err != nil {
-				return &Nil{}, err
+			return &Integer{value: int64(i)}, elements[i], true, nil
+		}, nil
+	case *String:
+		runes := []rune(iterable.value)
+		i := -1
+		return func() (Object, Object, bool, error) {
+			if i++; i >= len(runes) {
+				return nil, nil, false, nil
 			}
-			right, err := e.Evaluate(n.Right)
-			if err != nil {
-				return &Nil{}, err
+			return &Integer{value: int64(i)}, &String{value: string(runes[i])}, true, nil
+		}, nil
+	case *Map:
+		keys := iterable.Keys()
+		return func() (Object, Object, bool, error) {
+			for len(keys) > 0 {
+				key := keys[0]
+				keys = keys[1:]
+				if value, ok := iterable.Get(key); ok {
+					return &String{value: key}, value, true, nil
+				}
+				// deleted by an earlier iteration
 			}
-			return left.LessThan(right)
-		default:
-			return &Nil{}, fmt.Errorf("unknown operator: %s", n.Operator)
+			return nil, nil, false, nil
+		}, nil
+	case *Integer:
+		if pair {
+			return nil, newTypeError("range over integer permits only one iteration variable")
 		}
-	case *SufixNode:
-		switch n.Operator {
-		case "++":
-			left, err := e.Evaluate(n.Left)
-			if err != nil {
-				return &Nil{}, err
+		i := int64(-1)
+		return func() (Object, Object, bool, error) {
+			if i++; i >= iterable.value {
+				return nil, nil, false, nil
 			}
-			switch left := left.(type) {
-			case *Integer:
-				newVal, _ := left.Add(&Integer{value: 1}) // ignore error as adding integer to integer should never fail 
-				e.callStack[e.framePointer].scope[n.Left.String().value] = newVal
-				return newVal, nil
-			case *Float:
-				newVal, _ := left.Add(&Float{value: 1.0}) // ignore error as adding float to float should never fail
-				e.callStack[e.framePointer].scope[n.Left.String().value] = newVal
-				return newVal, nil
-			default:
-				return &Nil{}, fmt.Errorf("operator '++' not supported for type %T", left)
+			return &Integer{value: i}, nil, true, nil
+		}, nil
+	case Iterator:
+		i := int64(-1)
+		return func() (Object, Object, bool, error) {
+			value, ok, err := iterable.Next()
+			if err != nil || !ok {
+				return nil, nil, false, err
 			}
-		case "--":
-			left, err := e.Evaluate(n.Left)
-			if err != nil {
-				return &Nil{}, err
-			}
-			switch left := left.(type) {
-			case *Integer:
-				newVal, _ := left.Sub(&Integer{value: 1}) // ignore error as subtracting integer from integer should never fail
-				e.callStack[e.framePointer].scope[n.Left.String().value] = newVal
-				return newVal, nil
-			case *Float:
-				newVal, _ := left.Sub(&Float{value: 1.0}) // ignore error as subtracting float from float should never fail
-				e.callStack[e.framePointer].scope[n.Left.String().value] = newVal
-				return newVal, nil
-			default:
-				return &Nil{}, fmt.Errorf("operator '++' not supported for type %T", left)
+			if !pair {
+				return value, nil, true, nil
 			}
+			i++
+			return &Integer{value: i}, value, true, nil
+		}, nil
+	}
+	return nil, newTypeError("cannot range over %s", iterable.Type())
+}
 
-		default:
-			return &Nil{}, fmt.Errorf("unknown operator: %s", n.Operator)
+// bindMethod returns method with receiver bound as its first argument.
+func bindMethod(receiver Object, method *GoFunction) *GoFunction {
+	if method.EvalFunc != nil {
+		return &GoFunction{Name: method.Name, EvalFunc: func(e *Evaluator, args []Object) (Object, error) {
+			return method.EvalFunc(e, append([]Object{receiver}, args...))
+		}}
+	}
+	return &GoFunction{Name: method.Name, Func: func(args []Object) (Object, error) {
+		return method.Func(append([]Object{receiver}, args...))
+	}}
+}
+
+// toIndex checks index is an integer between 0 and length, inclusive when
+// the index is a slice bound.
+func toIndex(index Object, length int, bound bool) (int, error) {
+	integer, ok := index.(*Integer)
+	if !ok {
+		return 0, newTypeError("indices must be integers, not %s", index.Type())
+	}
+	limit := length
+	if bound {
+		limit++
+	}
+	if integer.value < 0 || integer.value >= int64(limit) {
+		return 0, newRuntimeError(INDEX_ERROR, "index %d out of range [0:%d]", integer.value, length)
+	}
+	return int(integer.value), nil
+}
+
+// indexObject returns element index of an array, rune index of a string or
+// the value stored under a map key, nil if there is none.
+func indexObject(object, index Object) (Object, error) {
+	switch object := object.(type) {
+	case *Map:
+		key, err := mapKey(index)
+		if err != nil {
+			return &Nil{}, err
+		}
+		if value, ok := object.Get(key); ok {
+			return value, nil
 		}
+		return &Nil{}, nil
+	case *Array:
+		i, err := toIndex(index, len(object.Elements), false)
+		if err != nil {
+			return &Nil{}, err
+		}
+		return object.Elements[i], nil
+	case *String:
+		runes := []rune(object.value)
+		i, err := toIndex(index, len(runes), false)
+		if err != nil {
+			return &Nil{}, err
+		}
+		return &String{value: string(runes[i])}, nil
+	}
+	return &Nil{}, newTypeError("%s is not indexable", object.Type())
+}
+
+// sliceObject returns the part of an array or string from start up to but
+// not including end, strings are sliced by rune. Nil bounds default to the
+// start and the end.
+func sliceObject(object, start, end Object) (Object, error) {
+	var length int
+	switch object := object.(type) {
+	case *Array:
+		length = len(object.Elements)
+	case *String:
+		length = utf8.RuneCountInString(object.value)
 	default:
-		return nil, fmt.Errorf("Unknown %T", n)
+		return &Nil{}, newTypeError("%s cannot be sliced", object.Type())
+	}
+
+	from, to := 0, length
+	var err error
+	if start != nil {
+		if from, err = toIndex(start, length, true); err != nil {
+			return &Nil{}, err
+		}
 	}
+	if end != nil {
+		if to, err = toIndex(end, length, true); err != nil {
+			return &Nil{}, err
+		}
+	}
+	if from > to {
+		return &Nil{}, newRuntimeError(INDEX_ERROR, "invalid slice indices %d > %d", from, to)
+	}
+
+	if array, ok := object.(*Array); ok {
+		return &Array{Elements: append([]Object{}, array.Elements[from:to]...)}, nil
+	}
+	return &String{value: string([]rune(object.(*String).value)[from:to])}, nil
 }
 
-func (e *Evaluator) pushFrame() {
-	// create new scope and copy old scope to new scope
-	frame := Frame{scope: make(map[string]Object)}
+// assignIndex stores value as an element of an array or under a map key,
+// strings are immutable.
+func (e *Evaluator) assignIndex(n *IndexNode, value Object) error {
+	object, err := e.Evaluate(n.Object)
+	if err != nil {
+		return err
+	}
+	index, err := e.Evaluate(n.Index)
+	if err != nil {
+		return err
+	}
+	return setIndex(object, index, value)
+}
 
+// setIndex stores value as element index of an array or under the map key
+// index.
+func setIndex(object, index, value Object) error {
+	if m, ok := object.(*Map); ok {
+		key, err := mapKey(index)
+		if err != nil {
+			return err
+		}
+		m.Set(key, value)
+		return nil
+	}
+	array, ok := object.(*Array)
+	if !ok {
+		return newTypeError("%s does not support item assignment", object.Type())
+	}
+	i, err := toIndex(index, len(array.Elements), false)
+	if err != nil {
+		return err
+	}
+	array.Elements[i] = value
+	return nil
+}
+
+// evaluateCall resolves the function a call refers to and evaluates its
+// arguments in the caller's scope.
+func (e *Evaluator) evaluateCall(n *FunctionCall) (Object, []Object, error) {
+	var fn Object
+	switch callee := n.Function.(type) {
+	case nil, *IdentifierLiteral:
+		found, ok := e.lookup(n, n.Name)
+		if !ok {
+			return nil, nil, newRuntimeError(NAME_ERROR, "function '%s' is not defined", n.Name)
+		}
+		fn = found
+	default:
+		found, err := e.Evaluate(callee)
+		if err != nil {
+			return nil, nil, err
+		}
+		fn = found
+	}
+
+	args := make([]Object, 0, len(n.Arguments))
+	for _, arg := range n.Arguments {
+		val, err := e.Evaluate(arg)
+		if err != nil {
+			return nil, nil, err
+		}
+		args = append(args, val)
+	}
+	return fn, args, nil
+}
+
+func (e *Evaluator) callObject(fn Object, args []Object, line int) (Object, error) {
+	switch fn := fn.(type) {
+	case *GoFunction:
+		if fn.EvalFunc != nil {
+			return e.allocated(fn.EvalFunc(e, args))
+		}
+		return e.allocated(fn.Call(args))
+	case *Function:
+		if fn.Generator {
+			return e.newGenerator(fn, args, line)
+		}
+		if fn.Async {
+			return e.startTask(fn, args, line)
+		}
+		return e.callFunction(fn, args, line)
+	}
+	return &Nil{}, newTypeError("%s is not callable", fn.Type())
+}
+
+// callFunction calls a GoScript function, and then the function each call
+// in tail position hands over to. The stack trace of an error raised after
+// a tail call shows the function that failed and the one called here, the
+// calls in between are gone.
+func (e *Evaluator) callFunction(fn *Function, args []Object, line int) (Object, error) {
+	first, firstLine, tailed := fn, line, false
+	for {
+		result, err := e.runCall(fn, args, line)
+		tail, ok := err.(*tailCall)
+		if !ok {
+			if err != nil && tailed {
+				err = traceError(err, first.Name, firstLine)
+			}
+			return result, err
+		}
+		fn, args, line, tailed = tail.function, tail.args, tail.line, true
+	}
+}
+
+// returnCall returns the error a return statement calling fn in tail
+// position unwinds the function with. A GoScript function is called by
+// callFunction once the frame of the function returning is gone, unless
+// that function has deferred calls which have to run after the call.
+// Anything else is called straight away.
+func (e *Evaluator) returnCall(fn Object, args []Object, line int) error {
+	if function, ok := fn.(*Function); ok && !function.Generator && !function.Async {
+		if frame := e.functionFrame(); frame != nil && len(frame.deferred) == 0 {
+			return &tailCall{function: function, args: args, line: line}
+		}
+	}
+	result, err := e.callObject(fn, args, line)
+	if err != nil {
+		return err
+	}
+	return &returnSignal{value: result}
+}
+
+// runCall runs a GoScript function in a new function frame. Calls deferred
+// by the function run once its body finishes, whether it returned or
+// failed, and may recover from the failure.
+func (e *Evaluator) runCall(fn *Function, args []Object, line int) (Object, error) {
+	if len(args) != len(fn.Arguments) {
+		return &Nil{}, newTypeError("function '%s' takes %d arguments only %d was given", fn.Name, len(fn.Arguments), len(args))
+	}
+	if err := e.enter(); err != nil {
+		return &Nil{}, err
+	}
+	if err := e.pushFrame(); err != nil {
+		return &Nil{}, err
+	}
+	e.depth++
+	defer func() {
+		e.depth--
+		e.popFrame()
+	}()
+
+	frame := e.callStack[e.framePointer]
+	frame.function = true
+	frame.closure = fn.Env
+	for i, argIdent := range fn.Arguments {
+		frame.scope[argIdent.value] = args[i]
+	}
+
+	var result Object = &Nil{}
+	var err error
+	if e.vm {
+		result, err = e.runFunction(fn, args)
+	} else {
+		result, err = e.walkFunction(fn, args)
+	}
+	if ret, ok := err.(*returnSignal); ok {
+		result, err = ret.value, nil
+	}
+	if len(frame.deferred) > 0 {
+		result, err = e.runDeferred(frame, result, err)
+	}
+	if err != nil {
+		return &Nil{}, traceError(err, fn.Name, line)
+	}
+	return result, nil
+}
+
+// walkFunction runs the body of fn on the tree walker in the frame runCall
+// pushed for it. Bodies are resolved the first time they run.
+func (e *Evaluator) walkFunction(fn *Function, args []Object) (Object, error) {
+	res, ok := e.resolutions[fn.Body]
+	if !ok {
+		res = resolveBody(fn.Body, fn.Arguments, e.closures[fn.Body])
+		e.resolutions[fn.Body] = res
+	}
+	_, err := e.walk(fn.Body, res, newSlots(res.slots, res.params, args), e.closureGlobals())
+	return &Nil{}, err
+}
+
+// walk evaluates node with the variables res found, slots being the locals
+// of the function call running it and globals its global table.
+func (e *Evaluator) walk(node Node, res *resolution, slots []Object, globals map[string]Object) (Object, error) {
+	defer func(outer *resolved) {
+		e.resolved = outer
+	}(e.resolved)
+	e.resolved = &resolved{resolution: res, slots: slots, globals: globals}
+	return e.evaluate(node)
+}
+
+// runDeferred runs the calls deferred in frame last in first out. err is
+// what the function body failed with, a deferred function calling recover()
+// clears it in which case the function returns nil. A deferred call that
+// fails replaces the error being unwound.
+func (e *Evaluator) runDeferred(frame *Frame, result Object, err error) (Object, error) {
+	frame.panic = err
+	frame.deferring = true
+	for len(frame.deferred) > 0 {
+		call := frame.deferred[len(frame.deferred)-1]
+		frame.deferred = frame.deferred[:len(frame.deferred)-1]
+		if _, callErr := e.callObject(call.function, call.args, call.line); callErr != nil {
+			frame.panic = callErr
+		}
+	}
+	frame.deferring = false
+
+	if frame.panic != nil {
+		return &Nil{}, frame.panic
+	}
+	if err != nil {
+		return &Nil{}, nil
+	}
+	return result, nil
+}
+
+// recover is the builtin stopping a panic. Like Go it only has an effect
+// when called directly by a deferred function, in which case it returns
+// the value the panic was raised with, or the Error object of a runtime
+// error, and the panicking function returns normally.
+func (e *Evaluator) recover(args []Object) (Object, error) {
+	calls := 0
+	for i := e.framePointer; i > 0; i-- {
+		frame := e.callStack[i]
+		if !frame.function {
+			continue
+		}
+		calls++
+		// the first function frame belongs to the deferred function itself
+		if calls < 2 {
+			continue
+		}
+		if !frame.deferring || frame.panic == nil || !isCatchable(frame.panic) {
+			break
+		}
+		runtimeErr := toRuntimeError(frame.panic)
+		frame.panic = nil
+		if runtimeErr.Thrown != nil {
+			return runtimeErr.Thrown, nil
+		}
+		return runtimeErr, nil
+	}
+	return &Nil{}, nil
+}
+
+// functionFrame returns the frame of the innermost function call or nil at
+// the top level.
+func (e *Evaluator) functionFrame() *Frame {
+	for i := e.framePointer; i > 0; i-- {
+		if e.callStack[i].function {
+			return e.callStack[i]
+		}
+	}
+	return nil
+}
+
+// closureGlobals returns the global table of the function call running,
+// the last scope the function closes over.
+func (e *Evaluator) closureGlobals() map[string]Object {
+	if closure := e.callStack[e.framePointer].closure; len(closure) > 0 {
+		return closure[len(closure)-1]
+	}
+	return nil
+}
+
+// captureScopes returns the scopes a function literal closes over: every
+// frame down to the enclosing function call plus whatever that function had
+// closed over itself, or down to the globals at the top level. Modules run
+// in a function frame of their own so their functions close over the
+// module's globals rather than the main program's.
+func (e *Evaluator) captureScopes() []map[string]Object {
+	var scopes []map[string]Object
+	for i := e.framePointer; i >= 0; i-- {
+		scopes = append(scopes, e.callStack[i].scope)
+		if e.callStack[i].function {
+			scopes = append(scopes, e.callStack[i].closure...)
+			break
+		}
+	}
+	return scopes
+}
+
+// pushFrame pushes a new frame, the call stack grows as needed up to
+// CALL_STACK_SIZE frames.
+func (e *Evaluator) pushFrame() error {
+	if e.framePointer+1 >= CALL_STACK_SIZE {
+		return newRuntimeError(RECURSION_ERROR, "maximum call stack size of %d exceeded", CALL_STACK_SIZE)
+	}
 	e.framePointer++
-	e.callStack[e.framePointer] = frame
+	frame := &Frame{scope: make(map[string]Object)}
+	if e.framePointer == len(e.callStack) {
+		e.callStack = append(e.callStack, frame)
+	} else {
+		e.callStack[e.framePointer] = frame
+	}
+	return nil
 }
 
 func (e *Evaluator) popFrame() {
-	e.callStack = e.callStack[:len(e.callStack)-1]
+	e.callStack[e.framePointer] = nil // drop the scope so it can be collected
 	e.framePointer--
 }
 
-func (e *Evaluator) getIdentifier(name string) (Object, error) {
+// lookupScope finds the scope holding name. Lookups walk the frames down to
+// the innermost function call and then the scopes that function closed
+// over, so a function never sees its caller's locals.
+func (e *Evaluator) lookupScope(name string) (map[string]Object, bool) {
 	for i := e.framePointer; i >= 0; i-- {
-		if variable, ok := e.callStack[i].scope[name]; ok {
-			return variable, nil
+		frame := e.callStack[i]
+		if _, ok := frame.scope[name]; ok {
+			return frame.scope, true
 		}
+		if frame.function {
+			for _, scope := range frame.closure {
+				if _, ok := scope[name]; ok {
+					return scope, true
+				}
+			}
+			break
+		}
+	}
+	return nil, false
+}
+
+func (e *Evaluator) getIdentifier(name string) (Object, error) {
+	if scope, ok := e.lookupScope(name); ok {
+		return scope[name], nil
+	}
+	if builtin, ok := e.builtins[name]; ok {
+		return builtin, nil
 	}
 	return nil, fmt.Errorf("unable to find reference")
 }
 
+// setIdentifier assigns to the visible variable called name, declaring it in
+// the current frame if there is none. := always declares a new variable.
+// Assigning to a builtin's name shadows it rather than replacing it.
+func (e *Evaluator) setIdentifier(name string, value Object) {
+	if scope, ok := e.lookupScope(name); ok {
+		scope[name] = value
+		return
+	}
+	e.callStack[e.framePointer].scope[name] = value
+}
+
+// variable returns where the resolver found the variable node uses, if it
+// did.
+func (e *Evaluator) variable(node Node) (variable, bool) {
+	if e.resolved == nil {
+		return variable{}, false
+	}
+	v, ok := e.resolved.variables[node]
+	return v, ok
+}
+
+// lookup returns the variable called name that node reads, from its slot
+// or the global table when the resolver found it, or through the frames.
+func (e *Evaluator) lookup(node Node, name string) (Object, bool) {
+	v, ok := e.variable(node)
+	switch {
+	case !ok:
+		value, err := e.getIdentifier(name)
+		return value, err == nil
+	case !v.global:
+		value := e.resolved.slots[v.slot]
+		return value, value != nil
+	}
+	if value, ok := e.resolved.globals[name]; ok {
+		return value, true
+	}
+	value, ok := e.builtins[name]
+	return value, ok
+}
+
+// assign assigns to the variable called name that node sets, see
+// setIdentifier.
+func (e *Evaluator) assign(node Node, name string, value Object) {
+	if v, ok := e.variable(node); ok && !v.global {
+		e.resolved.slots[v.slot] = value
+		return
+	}
+	e.setIdentifier(name, value)
+}
+
+// declare declares the variable called name that node binds, in its slot or
+// the current frame.
+func (e *Evaluator) declare(node Node, name string, value Object) {
+	if v, ok := e.variable(node); ok && !v.global {
+		e.resolved.slots[v.slot] = value
+		return
+	}
+	e.callStack[e.framePointer].scope[name] = value
+}
+
 func isTruthy(obj Node) bool {
 	switch obj := obj.(type) {
 	case *Integer:
 		return obj.value != 0
 	case *Float:
 		return obj.value != 0.0
+	case *BigInt:
+		return obj.value.Sign() != 0
+	case *Decimal:
+		return obj.coefficient.Sign() != 0
 	case *String:
 		return obj.value != ""
 	case *Boolean:
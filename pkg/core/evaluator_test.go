@@ -2,6 +2,8 @@ package core
 
 import (
 	"fmt"
+	"math"
+	"math/big"
 	"reflect"
 	"testing"
 )
@@ -65,3 +67,427 @@ func TestEval(t *testing.T) {
 	}
 
 }
+
+// evalInput parses and evaluates a whole program, returning the result of
+// its last top level statement.
+func evalInput(input string) (Object, error) {
+	return evalInputWith(input, false)
+}
+
+// evalInputWith is evalInput on the virtual machine when vm is set.
+func evalInputWith(input string, vm bool) (Object, error) {
+	program, err := NewV1Parser(NewV1Lexer(input), false).ParseProgram()
+	if err != nil {
+		return nil, err
+	}
+	evaluator := NewEvaluator(false)
+	evaluator.SetVM(vm)
+	var result Object = &Nil{}
+	for _, stmt := range program.(*BlockStatement).Statements {
+		result, err = evaluator.Evaluate(stmt)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func TestEvalProgram(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected Object
+		err      string
+	}{
+		{
+			name:     "test hexadecimal arithmetic",
+			input:    "x = 0xFF\nx + 0b1",
+			expected: &Integer{value: 256},
+		},
+		{
+			name:     "test int64 boundary",
+			input:    "9223372036854775806 + 1",
+			expected: &Integer{value: math.MaxInt64},
+		},
+		{
+			name:     "test addition overflow promotes to bigint",
+			input:    "9223372036854775807 + 1",
+			expected: &BigInt{value: new(big.Int).Lsh(big.NewInt(1), 63)},
+		},
+		{
+			name:     "test subtraction overflow promotes to bigint",
+			input:    "x = -9223372036854775808\nx - 1",
+			expected: bigIntFromString("-9223372036854775809"),
+		},
+		{
+			name:     "test multiplication overflow promotes to bigint",
+			input:    "4294967296 * 4294967296",
+			expected: &BigInt{value: new(big.Int).Lsh(big.NewInt(1), 64)},
+		},
+		{
+			name:     "test division overflow promotes to bigint",
+			input:    "x = -9223372036854775808\nx / -1",
+			expected: &BigInt{value: new(big.Int).Lsh(big.NewInt(1), 63)},
+		},
+		{
+			name:     "test bigint literal arithmetic",
+			input:    "2n * 123456789012345678901234567890",
+			expected: bigIntFromString("246913578024691357802469135780"),
+		},
+		{
+			name:     "test bigint comparison with integer",
+			input:    "10n >= 10",
+			expected: &Boolean{value: true},
+		},
+		{
+			name:     "test bigint modulo",
+			input:    "bigint(\"0x10\") % 3",
+			expected: &BigInt{value: big.NewInt(1)},
+		},
+		{
+			name:     "test integer and float",
+			input:    "1 + 2.5",
+			expected: &Float{value: 3.5},
+		},
+		{
+			name:     "test decimal addition is exact",
+			input:    "decimal(\"0.1\") + decimal(0.2) == decimal(\"0.3\")",
+			expected: &Boolean{value: true},
+		},
+		{
+			name:     "test decimal keeps scale",
+			input:    "decimal(\"1.10\") * 3",
+			expected: &Decimal{coefficient: big.NewInt(330), scale: 2},
+		},
+		{
+			name:     "test decimal division terminates",
+			input:    "decimal(10) / 4",
+			expected: &Decimal{coefficient: big.NewInt(25), scale: 1},
+		},
+		{
+			name:     "test decimal division rounds",
+			input:    "decimal(-1) / decimal(\"6\")",
+			expected: &Decimal{coefficient: bigIntFromString("-1666666666666666666666666667").value, scale: 28},
+		},
+		{
+			name:     "test decimal and float",
+			input:    "decimal(\"0.5\") + 0.25",
+			expected: &Float{value: 0.75},
+		},
+		{
+			name:  "test decimal division by zero",
+			input: "decimal(1) / 0",
+			err:   "Division by zero",
+		},
+		{
+			name:  "test invalid decimal",
+			input: "decimal(\"1.2.3\")",
+			err:   `invalid decimal "1.2.3"`,
+		},
+		{
+			name:     "test function return value",
+			input:    "func add(a, b) {\n return a + b\n}\nadd(2, 3)",
+			expected: &Integer{value: 5},
+		},
+		{
+			name:     "test catch division by zero",
+			input:    "msg = \"\"\ntry {\n x = 1 / 0\n} catch e {\n msg = e.type\n}\nmsg",
+			expected: &String{value: "ZeroDivisionError"},
+		},
+		{
+			name:     "test catch undefined variable",
+			input:    "try { missing } catch e { msg = e.message }\nmsg",
+			expected: &String{value: "variable 'missing' is not defined"},
+		},
+		{
+			name:     "test catch type mismatch",
+			input:    "try { 1 + \"a\" } catch e { msg = e.type }\nmsg",
+			expected: &String{value: "TypeError"},
+		},
+		{
+			name:     "test throw value",
+			input:    "try { throw 42 } catch e { thrown = e.value }\nthrown",
+			expected: &Integer{value: 42},
+		},
+		{
+			name:     "test throw custom error",
+			input:    "try { throw error(\"bad input\", \"ValueError\") } catch e { msg = e.type + \": \" + e.message }\nmsg",
+			expected: &String{value: "ValueError: bad input"},
+		},
+		{
+			name:     "test finally runs after return",
+			input:    "func f() {\n try {\n  return 1\n } finally {\n  return 2\n }\n}\nf()",
+			expected: &Integer{value: 2},
+		},
+		{
+			name:     "test finally without catch rethrows",
+			input:    "try {\n try { throw \"inner\" } finally { cleaned = true }\n} catch e { msg = e.message }\ncleaned",
+			expected: &Boolean{value: true},
+		},
+		{
+			name:     "test stack trace",
+			input:    "func fail() {\n throw \"oops\"\n}\nfunc run() {\n fail()\n}\ntry { run() } catch e { trace = e.stack }\ntrace",
+			expected: &String{value: "at fail (line 5)\nat run (line 7)"},
+		},
+		{
+			name:  "test uncaught throw",
+			input: "throw \"unhandled\"",
+			err:   "unhandled",
+		},
+		{
+			name:     "test loop assigns outer variable",
+			input:    "total = 0\nfor i = 0; i < 5; i++ {\n total = total + i\n}\ntotal",
+			expected: &Integer{value: 10},
+		},
+		{
+			name:  "test function cannot see caller locals",
+			input: "func inner() {\n return secret\n}\nfunc outer() {\n secret = 1\n return inner()\n}\nouter()",
+			err:   "variable 'secret' is not defined",
+		},
+		{
+			name:     "test closure keeps its scope",
+			input:    "func counter() {\n count = 0\n return func() {\n  count = count + 1\n  return count\n }\n}\nc = counter()\nc()\nc()",
+			expected: &Integer{value: 2},
+		},
+		{
+			name:  "test call stack overflow",
+			input: "func f(n) {\n return 1 + f(n + 1)\n}\nf(0)",
+			err:   "maximum call stack size of 10000 exceeded",
+		},
+		{
+			name:     "test deferred calls run last in first out",
+			input:    "log = \"\"\nfunc add(s) {\n log = log + s\n}\nfunc run() {\n defer add(\"a\")\n defer add(\"b\")\n add(\"c\")\n}\nrun()\nlog",
+			expected: &String{value: "cba"},
+		},
+		{
+			name:     "test defer arguments are evaluated immediately",
+			input:    "x = 1\nshown = 0\nfunc show(v) {\n shown = v\n}\nfunc run() {\n defer show(x)\n x = 2\n}\nrun()\nshown",
+			expected: &Integer{value: 1},
+		},
+		{
+			name:     "test defer runs when function fails",
+			input:    "closed = false\nfunc close() {\n closed = true\n}\nfunc run() {\n defer close()\n throw \"boom\"\n}\ntry { run() } catch e { }\nclosed",
+			expected: &Boolean{value: true},
+		},
+		{
+			name:     "test deferred call keeps return value",
+			input:    "func run() {\n defer func() {\n  x = 1\n }()\n return 5\n}\nrun()",
+			expected: &Integer{value: 5},
+		},
+		{
+			name:     "test recover returns panic value",
+			input:    "recovered = \"\"\nfunc safe() {\n defer func() {\n  recovered = recover()\n }()\n panic(\"bad\")\n}\nsafe()\nrecovered",
+			expected: &String{value: "bad"},
+		},
+		{
+			name:     "test recover runtime error",
+			input:    "message = \"\"\nfunc safe() {\n defer func() {\n  message = recover().message\n }()\n 1 / 0\n}\nsafe()\nmessage",
+			expected: &String{value: "Division by zero"},
+		},
+		{
+			name:     "test recovered function returns nil",
+			input:    "func safe() {\n defer func() {\n  recover()\n }()\n panic(\"bad\")\n return 1\n}\nsafe()",
+			expected: &Nil{},
+		},
+		{
+			name:     "test recover outside deferred call",
+			input:    "recover()",
+			expected: &Nil{},
+		},
+		{
+			name:  "test unrecovered panic",
+			input: "func run() {\n panic(\"bad\")\n}\nrun()",
+			err:   "bad",
+		},
+		{
+			name:  "test panic in deferred call replaces error",
+			input: "func run() {\n defer func() {\n  panic(\"second\")\n }()\n panic(\"first\")\n}\nrun()",
+			err:   "second",
+		},
+		{
+			name:     "test array literal over several lines",
+			input:    "a = [\n 1,\n 2,\n]\na + [3]",
+			expected: &Array{Elements: []Object{&Integer{value: 1}, &Integer{value: 2}, &Integer{value: 3}}},
+		},
+		{
+			name:     "test array index assignment",
+			input:    "a = [1, 2, 3]\na[1] = 5\na[1:]",
+			expected: &Array{Elements: []Object{&Integer{value: 5}, &Integer{value: 3}}},
+		},
+		{
+			name:  "test string item assignment",
+			input: "s = \"abc\"\ns[0] = \"x\"",
+			err:   "string does not support item assignment",
+		},
+		{
+			name:     "test range over array",
+			input:    "total = 0\nfor i, x := range [10, 20, 30] {\n total = total + i * x\n}\ntotal",
+			expected: &Integer{value: 80},
+		},
+		{
+			name:     "test range over string gives runes",
+			input:    "out = \"\"\nfor i, r := range \"héllo\" {\n out = r + out\n}\nout",
+			expected: &String{value: "olléh"},
+		},
+		{
+			name:     "test range over integer",
+			input:    "total = 0\nfor i := range 5 {\n total = total + i\n}\ntotal",
+			expected: &Integer{value: 10},
+		},
+		{
+			name:  "test range over boolean",
+			input: "for x := range true {\n}",
+			err:   "cannot range over boolean",
+		},
+		{
+			name:     "test map literal index",
+			input:    "m = {\"a\": 1, \"b\": 2}\nm[\"b\"]",
+			expected: &Integer{value: 2},
+		},
+		{
+			name:     "test map missing key",
+			input:    "m = {}\nm[\"a\"]",
+			expected: &Nil{},
+		},
+		{
+			name:     "test map assignment keeps insertion order",
+			input:    "m = {\"z\": 1}\nm[\"a\"] = 2\nm[\"z\"] = 3\nm.keys()",
+			expected: newStringArray([]string{"z", "a"}),
+		},
+		{
+			name:     "test range over map",
+			input:    "m = {\n \"x\": 1,\n \"y\": 2,\n}\nkeys = \"\"\ntotal = 0\nfor k, v := range m {\n keys = keys + k\n total = total + v\n}\n[keys, total]",
+			expected: &Array{Elements: []Object{&String{value: "xy"}, &Integer{value: 3}}},
+		},
+		{
+			name:     "test map delete and has",
+			input:    "m = {\"a\": 1}\nm.delete(\"a\")\n[m.has(\"a\"), length(m)]",
+			expected: &Array{Elements: []Object{&Boolean{value: false}, &Integer{value: 0}}},
+		},
+		{
+			name:  "test map key must be string",
+			input: "m = {}\nm[1] = 2",
+			err:   "map keys must be strings, not integer",
+		},
+		{
+			name:  "test defer outside function",
+			input: "defer print(1)",
+			err:   "'defer' outside function on line: 1",
+		},
+	}
+
+	for _, backend := range backends {
+		for _, test := range cases {
+			t.Run(backend.name+"/"+test.name, func(t *testing.T) {
+				result, err := evalInputWith(test.input, backend.vm)
+				if test.err != "" {
+					if err == nil || err.Error() != test.err {
+						t.Fatalf("expected error %q, got %v", test.err, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !reflect.DeepEqual(result, test.expected) {
+					t.Fatalf("expected %v, got %v", test.expected, result)
+				}
+			})
+		}
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected Object
+		err      string
+	}{
+		{
+			name:     "test tail recursion deeper than the call stack",
+			input:    "func count(n, acc) {\n if n == 0 {\n  return acc\n }\n return count(n - 1, acc + 1)\n}\ncount(100000, 0)",
+			expected: &Integer{value: 100000},
+		},
+		{
+			name:     "test recursive list processing",
+			input:    "func grow(xs, n) {\n if length(xs) >= n {\n  return xs\n }\n return grow(xs + xs, n)\n}\nfunc sum(xs, i, acc) {\n if i == length(xs) {\n  return acc\n }\n return sum(xs, i + 1, acc + xs[i])\n}\nsum(grow([1, 2], 30000), 0, 0)",
+			expected: &Integer{value: 49152},
+		},
+		{
+			name:     "test mutual recursion",
+			input:    "func even(n) {\n if n == 0 {\n  return true\n }\n return odd(n - 1)\n}\nfunc odd(n) {\n if n == 0 {\n  return false\n }\n return even(n - 1)\n}\n[even(50000), odd(50001)]",
+			expected: &Array{Elements: []Object{&Boolean{value: true}, &Boolean{value: true}}},
+		},
+		{
+			name:     "test tail call inside a loop",
+			input:    "func find(n) {\n for i := 0; i < 1; i++ {\n  if n == 0 {\n   return \"found\"\n  }\n  return find(n - 1)\n }\n}\nfind(20000)",
+			expected: &String{value: "found"},
+		},
+		{
+			name:     "test tail call of anonymous function",
+			input:    "f = func(n) {\n if n == 0 {\n  return \"done\"\n }\n return f(n - 1)\n}\nf(20000)",
+			expected: &String{value: "done"},
+		},
+		{
+			name:     "test return inside try is not a tail call",
+			input:    "func f(n) {\n try {\n  return f(n + 1)\n } catch e {\n  return e.message\n }\n}\nf(0)",
+			expected: &String{value: "maximum call stack size of 10000 exceeded"},
+		},
+		{
+			name:     "test deferred calls run after the call",
+			input:    "log = \"\"\nfunc add(s) {\n log = log + s\n}\nfunc g(n) {\n add(\"g\")\n return n\n}\nfunc f(n) {\n defer add(\"d\")\n return g(n)\n}\n[f(3), log]",
+			expected: &Array{Elements: []Object{&Integer{value: 3}, &String{value: "gd"}}},
+		},
+		{
+			name:     "test tail call of builtin",
+			input:    "func f(xs) {\n return length(xs)\n}\nf([1, 2])",
+			expected: &Integer{value: 2},
+		},
+		{
+			name:     "test error in tail call",
+			input:    "func fail() {\n throw \"boom\"\n}\nfunc f() {\n return fail()\n}\nx = \"\"\ntry {\n f()\n} catch e {\n x = e.message\n}\nx",
+			expected: &String{value: "boom"},
+		},
+		{
+			name:  "test wrong number of arguments in tail call",
+			input: "func g(a) {\n return a\n}\nfunc f() {\n return g()\n}\nf()",
+			err:   "function 'g' takes 1 arguments only 0 was given",
+		},
+	}
+
+	for _, backend := range backends {
+		for _, test := range cases {
+			t.Run(backend.name+"/"+test.name, func(t *testing.T) {
+				result, err := evalInputWith(test.input, backend.vm)
+				if test.err != "" {
+					if err == nil || err.Error() != test.err {
+						t.Fatalf("expected error %q, got %v", test.err, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !reflect.DeepEqual(result, test.expected) {
+					t.Fatalf("expected %v, got %v", test.expected, result)
+				}
+			})
+		}
+	}
+}
+
+// backends are the ways of running a program, every one must give the same
+// results.
+var backends = []struct {
+	name string
+	vm   bool
+}{
+	{name: "tree", vm: false},
+	{name: "vm", vm: true},
+}
+
+func bigIntFromString(value string) *BigInt {
+	i, _ := new(big.Int).SetString(value, 10)
+	return &BigInt{value: i}
+}
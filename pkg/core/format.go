@@ -0,0 +1,160 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+)
+
+// SetOutput redirects what print, println and printf write, which goes to
+// stdout by default.
+func (e *Evaluator) SetOutput(out io.Writer) {
+	e.out = out
+}
+
+// SetErrorOutput redirects the tracebacks of errors nothing is there to
+// catch, those of goroutines and HTTP handlers, which go to stderr by
+// default.
+func (e *Evaluator) SetErrorOutput(out io.Writer) {
+	e.errOut = out
+}
+
+// joinArgs renders the arguments of print separated by spaces.
+func joinArgs(args []Object) string {
+	values := make([]string, len(args))
+	for i, arg := range args {
+		values[i] = arg.String().value
+	}
+	return strings.Join(values, " ")
+}
+
+// print writes its arguments separated by spaces and ends the line, println
+// is the same builtin under Go's name.
+func (e *Evaluator) print(args []Object) (Object, error) {
+	if _, err := fmt.Fprintln(e.out, joinArgs(args)); err != nil {
+		return &Nil{}, newOSError(err)
+	}
+	return &Nil{}, nil
+}
+
+// printf is printf(format, args...), writing the formatted string without
+// ending the line.
+func (e *Evaluator) printf(args []Object) (Object, error) {
+	str, err := formatArgs("printf", args)
+	if err != nil {
+		return &Nil{}, err
+	}
+	if _, err := io.WriteString(e.out, str); err != nil {
+		return &Nil{}, newOSError(err)
+	}
+	return &Nil{}, nil
+}
+
+// gssprintf is sprintf(format, args...) returning the formatted string.
+func gssprintf(args []Object) (Object, error) {
+	str, err := formatArgs("sprintf", args)
+	if err != nil {
+		return &Nil{}, err
+	}
+	return &String{value: str}, nil
+}
+
+// formatArgs formats the arguments of printf and sprintf, a format followed
+// by the values for its verbs. Formats take Go's verbs, flags, width and
+// precision:
+//
+//	%d %b %o %x %X  integers, %x and %X also take strings
+//	%f %e %g        numbers
+//	%s %v           any value as print shows it
+//	%q              a quoted string
+//	%t              a boolean
+//	%c              the character of a code point
+//	%%              a percent sign
+func formatArgs(name string, args []Object) (string, error) {
+	if len(args) == 0 {
+		return "", newTypeError("%s() takes at least 1 argument (0 given)", name)
+	}
+	format, err := stringArg(name, args, 0)
+	if err != nil {
+		return "", err
+	}
+	return formatString(name, format, args[1:])
+}
+
+func formatString(name, format string, args []Object) (string, error) {
+	var out strings.Builder
+	next := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			out.WriteByte(format[i])
+			continue
+		}
+		// the directive runs up to the verb, the first letter or %
+		start := i
+		i++
+		for i < len(format) && strings.IndexByte("+-# 0123456789.", format[i]) >= 0 {
+			i++
+		}
+		if i == len(format) {
+			return "", newRuntimeError(VALUE_ERROR, "%s() format ends in an incomplete verb %q", name, format[start:])
+		}
+		spec, verb := format[start:i+1], format[i]
+		if verb == '%' {
+			out.WriteByte('%')
+			continue
+		}
+		if next == len(args) {
+			return "", newTypeError("%s() format %s has no argument", name, spec)
+		}
+		value, err := formatValue(name, spec, verb, args[next])
+		if err != nil {
+			return "", err
+		}
+		next++
+		out.WriteString(fmt.Sprintf(spec, value))
+	}
+	if next < len(args) {
+		return "", newTypeError("%s() got %d arguments but the format uses %d", name, len(args), next)
+	}
+	return out.String(), nil
+}
+
+// formatValue converts arg to the Go value verb formats, checking the verb
+// applies to it.
+func formatValue(name, spec string, verb byte, arg Object) (interface{}, error) {
+	switch verb {
+	case 'd', 'b', 'o', 'x', 'X', 'c':
+		switch arg := arg.(type) {
+		case *Integer:
+			return arg.value, nil
+		case *BigInt:
+			if verb != 'c' {
+				return arg.value, nil
+			}
+		case *String:
+			if verb == 'x' || verb == 'X' {
+				return arg.value, nil
+			}
+		}
+		return nil, newTypeError("%s() format %s expects an integer, got %s", name, spec, arg.Type())
+	case 'f', 'F', 'e', 'E', 'g', 'G':
+		if bigInt, ok := arg.(*BigInt); ok {
+			return new(big.Float).SetInt(bigInt.value), nil
+		}
+		value, ok := toFloat64(arg)
+		if !ok {
+			return nil, newTypeError("%s() format %s expects a number, got %s", name, spec, arg.Type())
+		}
+		return value, nil
+	case 's', 'v', 'q':
+		return arg.String().value, nil
+	case 't':
+		boolean, ok := arg.(*Boolean)
+		if !ok {
+			return nil, newTypeError("%s() format %s expects a boolean, got %s", name, spec, arg.Type())
+		}
+		return boolean.value, nil
+	}
+	return nil, newRuntimeError(VALUE_ERROR, "%s() got an unknown verb %s", name, spec)
+}
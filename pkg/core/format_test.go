@@ -0,0 +1,127 @@
+package core
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestSprintf(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected Object
+		err      string
+	}{
+		{
+			name:     "test integer verbs",
+			input:    "sprintf(\"%d|%5d|%-4d|%05d|%x|%X|%b|%o\", 42, 42, 42, 42, 255, 255, 5, 8)",
+			expected: &String{value: "42|   42|42  |00042|ff|FF|101|10"},
+		},
+		{
+			name:     "test float precision",
+			input:    "sprintf(\"%.2f|%8.3f|%e|%g\", 3.14159, 2, 1500.0, 0.5)",
+			expected: &String{value: "3.14|   2.000|1.500000e+03|0.5"},
+		},
+		{
+			name:     "test strings",
+			input:    "sprintf(\"%s|%10s|%-6s|%q|%x\", \"go\", \"right\", \"left\", \"quoted\", \"hi\")",
+			expected: &String{value: "go|     right|left  |\"quoted\"|6869"},
+		},
+		{
+			name:     "test any value",
+			input:    "sprintf(\"%v and %v, %t%%\", [1, \"a\"], {\"k\": true}, false)",
+			expected: &String{value: "[1, a] and {\"k\": true}, false%"},
+		},
+		{
+			name:     "test bigint",
+			input:    "sprintf(\"%d %x\", 2n * 9223372036854775807, 16n)",
+			expected: &String{value: "18446744073709551614 10"},
+		},
+		{
+			name:  "test wrong argument type",
+			input: "sprintf(\"%d\", \"ten\")",
+			err:   "sprintf() format %d expects an integer, got string",
+		},
+		{
+			name:  "test missing argument",
+			input: "sprintf(\"%s and %s\", \"one\")",
+			err:   "sprintf() format %s has no argument",
+		},
+		{
+			name:  "test extra argument",
+			input: "sprintf(\"%s\", \"one\", \"two\")",
+			err:   "sprintf() got 2 arguments but the format uses 1",
+		},
+		{
+			name:  "test unknown verb",
+			input: "sprintf(\"%y\", 1)",
+			err:   "sprintf() got an unknown verb %y",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := evalInput(test.input)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestPrintOutput(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "test print separates arguments",
+			input:    "print(1, \"Test\", true)",
+			expected: "1 Test true\n",
+		},
+		{
+			name:     "test print without arguments",
+			input:    "print()",
+			expected: "\n",
+		},
+		{
+			name:     "test println",
+			input:    "println(\"a\", \"b\")",
+			expected: "a b\n",
+		},
+		{
+			name:     "test printf does not end the line",
+			input:    "printf(\"%03d;\", 7)\nprintf(\"%s\", \"done\")",
+			expected: "007;done",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			program, err := NewV1Parser(NewV1Lexer(test.input), false).ParseProgram()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			evaluator := NewEvaluator(false)
+			evaluator.SetOutput(&out)
+			if _, err := evaluator.Evaluate(program); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != test.expected {
+				t.Fatalf("expected output %q, got %q", test.expected, out.String())
+			}
+		})
+	}
+}
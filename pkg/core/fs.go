@@ -0,0 +1,402 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// default permissions of the files and directories the fs module creates
+const (
+	FILE_PERM      = 0o644
+	DIRECTORY_PERM = 0o755
+)
+
+// fsModule builds the native fs module.
+func fsModule(e *Evaluator) map[string]Object {
+	functions := map[string]func([]Object) (Object, error){
+		"readFile":   fsReadFile,
+		"writeFile":  fsWriter("writeFile", os.O_TRUNC),
+		"appendFile": fsWriter("appendFile", os.O_APPEND),
+		"exists":     fsExists,
+		"stat":       fsStat,
+		"listDir":    fsListDir,
+		"mkdirAll":   fsMkdirAll,
+		"remove":     fsRemove,
+		"rename":     fsRename,
+		"open":       fsOpen,
+	}
+	module := map[string]Object{}
+	for name, fn := range functions {
+		module[name] = &GoFunction{Name: name, Func: fn}
+	}
+	module["glob"] = &GoFunction{Name: "glob", EvalFunc: (*Evaluator).fsGlob}
+	module["walk"] = &GoFunction{Name: "walk", EvalFunc: (*Evaluator).fsWalk}
+	return module
+}
+
+func fsReadFile(args []Object) (Object, error) {
+	values, err := stringArgs("readFile", args, 1, 1, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	data, err := os.ReadFile(values[0])
+	if err != nil {
+		return &Nil{}, newOSError(err)
+	}
+	return &String{value: string(data)}, nil
+}
+
+// fsWriter builds writeFile and appendFile, both create the file if it does
+// not exist.
+func fsWriter(name string, flag int) func([]Object) (Object, error) {
+	return func(args []Object) (Object, error) {
+		values, err := stringArgs(name, args, 2, 2, 2)
+		if err != nil {
+			return &Nil{}, err
+		}
+		file, err := os.OpenFile(values[0], os.O_WRONLY|os.O_CREATE|flag, FILE_PERM)
+		if err != nil {
+			return &Nil{}, newOSError(err)
+		}
+		if _, err := file.WriteString(values[1]); err != nil {
+			file.Close()
+			return &Nil{}, newOSError(err)
+		}
+		if err := file.Close(); err != nil {
+			return &Nil{}, newOSError(err)
+		}
+		return &Nil{}, nil
+	}
+}
+
+func fsExists(args []Object) (Object, error) {
+	values, err := stringArgs("exists", args, 1, 1, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	_, err = os.Stat(values[0])
+	return &Boolean{value: err == nil}, nil
+}
+
+func fsStat(args []Object) (Object, error) {
+	values, err := stringArgs("stat", args, 1, 1, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	info, err := os.Stat(values[0])
+	if err != nil {
+		return &Nil{}, newOSError(err)
+	}
+	return newFileInfo(info), nil
+}
+
+// fsListDir returns the names of the entries of a directory, sorted.
+func fsListDir(args []Object) (Object, error) {
+	values, err := stringArgs("listDir", args, 1, 1, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	entries, err := os.ReadDir(values[0])
+	if err != nil {
+		return &Nil{}, newOSError(err)
+	}
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	return newStringArray(names), nil
+}
+
+func fsMkdirAll(args []Object) (Object, error) {
+	values, err := stringArgs("mkdirAll", args, 1, 1, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	if err := os.MkdirAll(values[0], DIRECTORY_PERM); err != nil {
+		return &Nil{}, newOSError(err)
+	}
+	return &Nil{}, nil
+}
+
+// fsRemove removes a file or an empty directory.
+func fsRemove(args []Object) (Object, error) {
+	values, err := stringArgs("remove", args, 1, 1, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	if err := os.Remove(values[0]); err != nil {
+		return &Nil{}, newOSError(err)
+	}
+	return &Nil{}, nil
+}
+
+func fsRename(args []Object) (Object, error) {
+	values, err := stringArgs("rename", args, 2, 2, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	if err := os.Rename(values[0], values[1]); err != nil {
+		return &Nil{}, newOSError(err)
+	}
+	return &Nil{}, nil
+}
+
+// fsGlob is glob(pattern), the paths matching pattern in lexical order.
+// Matches the policy does not allow are left out, the pattern may reach
+// them through symbolic links it does not resolve.
+func (e *Evaluator) fsGlob(args []Object) (Object, error) {
+	values, err := stringArgs("glob", args, 1, 1, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	matches, err := filepath.Glob(values[0])
+	if err != nil {
+		return &Nil{}, newRuntimeError(VALUE_ERROR, "%s", err.Error())
+	}
+	allowed := matches[:0]
+	for _, match := range matches {
+		if e.policy.allowsPath(match) {
+			allowed = append(allowed, match)
+		}
+	}
+	sort.Strings(allowed)
+	return newStringArray(allowed), nil
+}
+
+// fsWalk is walk(root, fn), calling fn(path, info) for every file and
+// directory under root in lexical order. An error raised by fn stops the
+// walk.
+func (e *Evaluator) fsWalk(args []Object) (Object, error) {
+	if err := checkArgs("walk", args, 2, 2); err != nil {
+		return &Nil{}, err
+	}
+	root, err := stringArg("walk", args, 0)
+	if err != nil {
+		return &Nil{}, err
+	}
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return newOSError(err)
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return newOSError(err)
+		}
+		_, err = e.callObject(args[1], []Object{&String{value: path}, newFileInfo(info)}, 0)
+		return err
+	})
+	if err != nil {
+		return &Nil{}, err
+	}
+	return &Nil{}, nil
+}
+
+// fsOpen is open(path) for reading or open(path, mode) where mode is "r",
+// "w" to truncate or create, or "a" to append.
+func fsOpen(args []Object) (Object, error) {
+	values, err := stringArgs("open", args, 1, 2, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	mode := "r"
+	if len(values) == 2 {
+		mode = values[1]
+	}
+	var flag int
+	switch mode {
+	case "r":
+		flag = os.O_RDONLY
+	case "w":
+		flag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	case "a":
+		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	default:
+		return &Nil{}, newRuntimeError(VALUE_ERROR, "invalid file mode %q", mode)
+	}
+	file, err := os.OpenFile(values[0], flag, FILE_PERM)
+	if err != nil {
+		return &Nil{}, newOSError(err)
+	}
+	return &File{nativeObject: nativeObject{"file"}, Name: values[0], file: file, reader: bufio.NewReader(file)}, nil
+}
+
+// File is an open file handle. Ranging over it reads it line by line.
+type File struct {
+	nativeObject
+	Name   string
+	file   *os.File
+	reader *bufio.Reader
+}
+
+// fileMethods is the method table of File.
+func fileMethods() map[string]*GoFunction {
+	return map[string]*GoFunction{
+		"readLine": {Name: "readLine", Func: fileReadLine},
+		"read":     {Name: "read", Func: fileRead},
+		"lines":    {Name: "lines", Func: fileLines},
+		"write":    {Name: "write", Func: fileWrite},
+		"close":    {Name: "close", Func: fileClose},
+	}
+}
+
+func fileArg(name string, args []Object, min, max int) (*File, error) {
+	if err := checkArgs(name, args, min, max); err != nil {
+		return nil, err
+	}
+	file, ok := args[0].(*File)
+	if !ok {
+		return nil, newTypeError("%s() expects a file, got %s", name, args[0].Type())
+	}
+	return file, nil
+}
+
+// Next returns the next line without its line ending.
+func (f *File) Next() (Object, bool, error) {
+	line, err := f.reader.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return &Nil{}, false, nil
+	}
+	if err != nil && err != io.EOF {
+		return &Nil{}, false, newOSError(err)
+	}
+	line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+	return &String{value: line}, true, nil
+}
+
+// fileReadLine returns the next line or nil at the end of the file.
+func fileReadLine(args []Object) (Object, error) {
+	file, err := fileArg("readLine", args, 1, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	line, _, err := file.Next()
+	return line, err
+}
+
+// fileRead returns the rest of the file.
+func fileRead(args []Object) (Object, error) {
+	file, err := fileArg("read", args, 1, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	data, err := io.ReadAll(file.reader)
+	if err != nil {
+		return &Nil{}, newOSError(err)
+	}
+	return &String{value: string(data)}, nil
+}
+
+// fileLines returns the file itself, for line := range f.lines() reads as
+// well as ranging over the file.
+func fileLines(args []Object) (Object, error) {
+	file, err := fileArg("lines", args, 1, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	return file, nil
+}
+
+func fileWrite(args []Object) (Object, error) {
+	file, err := fileArg("write", args, 2, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	data, err := stringArg("write", args, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	if _, err := file.file.WriteString(data); err != nil {
+		return &Nil{}, newOSError(err)
+	}
+	return &Nil{}, nil
+}
+
+func fileClose(args []Object) (Object, error) {
+	file, err := fileArg("close", args, 1, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	if err := file.file.Close(); err != nil {
+		return &Nil{}, newOSError(err)
+	}
+	return &Nil{}, nil
+}
+
+func (f *File) GetAttribute(name string) (Object, bool) {
+	if name == "name" {
+		return &String{value: f.Name}, true
+	}
+	return nil, false
+}
+
+func (f *File) Type() string {
+	return "file"
+}
+
+func (f *File) Value() interface{} {
+	return f.file
+}
+
+func (f *File) String() *String {
+	return &String{value: fmt.Sprintf("<file %s>", f.Name)}
+}
+
+func (f *File) Equal(other Object) (Object, error) {
+	return &Boolean{value: f == other}, nil
+}
+
+func (f *File) NotEqual(other Object) (Object, error) {
+	return &Boolean{value: f != other}, nil
+}
+
+// FileInfo describes a file as returned by stat and passed to walk.
+type FileInfo struct {
+	nativeObject
+	info fs.FileInfo
+}
+
+func newFileInfo(info fs.FileInfo) *FileInfo {
+	return &FileInfo{nativeObject: nativeObject{"fileinfo"}, info: info}
+}
+
+func (fi *FileInfo) GetAttribute(name string) (Object, bool) {
+	switch name {
+	case "name":
+		return &String{value: fi.info.Name()}, true
+	case "size":
+		return &Integer{value: fi.info.Size()}, true
+	case "mode":
+		return &String{value: fi.info.Mode().String()}, true
+	case "modified":
+		return &Integer{value: fi.info.ModTime().Unix()}, true
+	case "isDir":
+		return &Boolean{value: fi.info.IsDir()}, true
+	}
+	return nil, false
+}
+
+func (fi *FileInfo) Type() string {
+	return "fileinfo"
+}
+
+func (fi *FileInfo) Value() interface{} {
+	return fi.info
+}
+
+func (fi *FileInfo) String() *String {
+	return &String{value: fmt.Sprintf("<fileinfo %s>", fi.info.Name())}
+}
+
+func (fi *FileInfo) Equal(other Object) (Object, error) {
+	return &Boolean{value: fi == other}, nil
+}
+
+func (fi *FileInfo) NotEqual(other Object) (Object, error) {
+	return &Boolean{value: fi != other}, nil
+}
@@ -0,0 +1,198 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFsModule(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected Object
+		err      string
+	}{
+		{
+			name:     "test write and read file",
+			input:    "fs.writeFile(dir + \"/new.txt\", \"hello\")\nfs.readFile(dir + \"/new.txt\")",
+			expected: &String{value: "hello"},
+		},
+		{
+			name:     "test append file",
+			input:    "fs.appendFile(dir + \"/lines.txt\", \"three\")\nfs.readFile(dir + \"/lines.txt\")",
+			expected: &String{value: "one\ntwo\nthree"},
+		},
+		{
+			name:     "test exists",
+			input:    "fs.exists(dir + \"/lines.txt\")",
+			expected: &Boolean{value: true},
+		},
+		{
+			name:     "test exists missing file",
+			input:    "fs.exists(dir + \"/missing.txt\")",
+			expected: &Boolean{value: false},
+		},
+		{
+			name:     "test stat",
+			input:    "info = fs.stat(dir + \"/lines.txt\")\n[info.name, info.size, info.isDir]",
+			expected: &Array{Elements: []Object{&String{value: "lines.txt"}, &Integer{value: 8}, &Boolean{value: false}}},
+		},
+		{
+			name:     "test list dir",
+			input:    "fs.listDir(dir)",
+			expected: newStringArray([]string{"lines.txt", "sub"}),
+		},
+		{
+			name:     "test mkdir all and rename",
+			input:    "fs.mkdirAll(dir + \"/a/b\")\nfs.rename(dir + \"/a/b\", dir + \"/a/c\")\nfs.listDir(dir + \"/a\")",
+			expected: newStringArray([]string{"c"}),
+		},
+		{
+			name:     "test remove",
+			input:    "fs.remove(dir + \"/lines.txt\")\nfs.exists(dir + \"/lines.txt\")",
+			expected: &Boolean{value: false},
+		},
+		{
+			name:     "test glob",
+			input:    "import \"path\"\nfiles = fs.glob(dir + \"/*.txt\")\npath.base(files[0])",
+			expected: &String{value: "lines.txt"},
+		},
+		{
+			name:     "test walk",
+			input:    "names = []\nfs.walk(dir, func(p, info) {\n names = names + [info.name]\n})\nnames[1:]",
+			expected: newStringArray([]string{"lines.txt", "sub", "inner.txt"}),
+		},
+		{
+			name:  "test walk stops on error",
+			input: "fs.walk(dir, func(p, info) {\n throw \"stop\"\n})",
+			err:   "stop",
+		},
+		{
+			name:     "test range over file",
+			input:    "f = fs.open(dir + \"/lines.txt\")\nout = \"\"\nfor line := range f {\n out = out + line + \";\"\n}\nf.close()\nout",
+			expected: &String{value: "one;two;"},
+		},
+		{
+			name:     "test range over lines with count",
+			input:    "f = fs.open(dir + \"/lines.txt\")\nlast = 0\nfor i, line := range f.lines() {\n last = i\n}\nlast",
+			expected: &Integer{value: 1},
+		},
+		{
+			name:     "test deferred close",
+			input:    "func first(p) {\n f = fs.open(p)\n defer f.close()\n return f.readLine()\n}\nfirst(dir + \"/lines.txt\")",
+			expected: &String{value: "one"},
+		},
+		{
+			name:     "test read line at end of file",
+			input:    "f = fs.open(dir + \"/sub/inner.txt\")\nf.readLine()\nf.readLine()",
+			expected: &Nil{},
+		},
+		{
+			name:     "test write to file handle",
+			input:    "f = fs.open(dir + \"/out.txt\", \"w\")\nf.write(\"data\")\nf.close()\nfs.readFile(dir + \"/out.txt\")",
+			expected: &String{value: "data"},
+		},
+		{
+			name:  "test read after close",
+			input: "f = fs.open(dir + \"/lines.txt\")\nf.close()\nf.readLine()",
+			err:   "read DIR/lines.txt: file already closed",
+		},
+		{
+			name:  "test open missing file",
+			input: "fs.open(dir + \"/missing.txt\")",
+			err:   "open DIR/missing.txt: no such file or directory",
+		},
+		{
+			name:  "test invalid mode",
+			input: "fs.open(dir + \"/lines.txt\", \"x\")",
+			err:   "invalid file mode \"x\"",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "lines.txt"), []byte("one\ntwo\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "sub", "inner.txt"), []byte("inner"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			input := fmt.Sprintf("import \"fs\"\ndir = %q\n%s", dir, test.input)
+			result, err := evalInput(input)
+			if test.err != "" {
+				// DIR stands for the test's directory in expected messages
+				expected := strings.ReplaceAll(test.err, "DIR", dir)
+				if err == nil || err.Error() != expected {
+					t.Fatalf("expected error %q, got %v", expected, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestPathModule(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		input    string
+		expected Object
+	}{
+		{
+			name:     "test join",
+			input:    "path.join(\"a\", \"b/\", \"../c\", \"d.gs\")",
+			expected: &String{value: "a/c/d.gs"},
+		},
+		{
+			name:     "test base",
+			input:    "path.base(\"/tmp/config.json\")",
+			expected: &String{value: "config.json"},
+		},
+		{
+			name:     "test dir",
+			input:    "path.dir(\"/tmp/config.json\")",
+			expected: &String{value: "/tmp"},
+		},
+		{
+			name:     "test ext",
+			input:    "path.ext(\"/tmp/config.json\")",
+			expected: &String{value: ".json"},
+		},
+		{
+			name:     "test abs",
+			input:    "path.abs(\"config.json\")",
+			expected: &String{value: filepath.Join(wd, "config.json")},
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := evalInput("import \"path\"\n" + test.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package core
+
+import (
+	"runtime"
+	"sync"
+)
+
+// generatorMethods is the method table of Generator.
+func generatorMethods() map[string]*GoFunction {
+	return map[string]*GoFunction{
+		"next":  {Name: "next", Func: generatorNext},
+		"send":  {Name: "send", Func: generatorSend},
+		"close": {Name: "close", Func: generatorClose},
+	}
+}
+
+// Generator is what calling a function that yields returns. Its body runs
+// lazily, up to the next yield each time a value is asked for, on a
+// goroutine and an evaluator of its own. Ranging over a generator gives
+// the values it yields.
+type Generator struct {
+	nativeObject
+	interpreter *interpreter
+	name        string
+	co          *coroutine
+	started     bool
+	running     bool
+	finished    bool
+}
+
+// coroutine is the goroutine running a generator's body, handing values to
+// the generator and back at every yield. It is kept apart from Generator so
+// that the goroutine does not keep the generator alive, a generator that
+// is no longer referenced is closed when it is collected.
+type coroutine struct {
+	resume chan Object
+	// yields is buffered so the goroutine can always hand over its final
+	// step and end, even when nobody is waiting for it any more
+	yields chan generatorStep
+	stop   sync.Once
+}
+
+// generatorStep is what a generator's body hands back when it yields, or
+// when it finishes in which case done is set and err is what it failed
+// with.
+type generatorStep struct {
+	value Object
+	done  bool
+	err   error
+}
+
+// newGenerator calls a generator function, which returns a generator
+// without running any of its body.
+func (e *Evaluator) newGenerator(fn *Function, args []Object, line int) (Object, error) {
+	if len(args) != len(fn.Arguments) {
+		return &Nil{}, newTypeError("function '%s' takes %d arguments only %d was given", fn.Name, len(fn.Arguments), len(args))
+	}
+	co := &coroutine{resume: make(chan Object), yields: make(chan generatorStep, 1)}
+	child := e.spawn()
+	child.generator = co
+	go func() {
+		// the body starts with the first value asked for, or never if the
+		// generator is closed before that
+		if _, ok := <-co.resume; !ok {
+			return
+		}
+		child.acquire()
+		_, err := child.callFunction(fn, args, line)
+		child.release()
+		if _, closed := err.(*generatorExit); closed {
+			err = nil
+		}
+		co.yields <- generatorStep{done: true, err: err}
+	}()
+
+	g := &Generator{nativeObject: nativeObject{"generator"}, interpreter: e.interpreter, name: fn.Name, co: co}
+	runtime.AddCleanup(g, (*coroutine).close, co)
+	return g, nil
+}
+
+// yield is run by the generator's body, on its own evaluator, to hand value
+// over and wait to be resumed.
+func (co *coroutine) yield(e *Evaluator, value Object) (Object, error) {
+	var sent Object
+	ok := true
+	e.blocking(func() {
+		co.yields <- generatorStep{value: value}
+		sent, ok = <-co.resume
+	})
+	if !ok {
+		return &Nil{}, &generatorExit{}
+	}
+	return sent, nil
+}
+
+func (co *coroutine) close() {
+	co.stop.Do(func() {
+		close(co.resume)
+	})
+}
+
+// resume runs the generator's body up to its next yield, sent being the
+// value of the yield it is waiting at. ok is false once the body has
+// finished.
+func (g *Generator) resume(sent Object) (Object, bool, error) {
+	if g.finished {
+		return &Nil{}, false, nil
+	}
+	if g.running {
+		return &Nil{}, false, newRuntimeError(VALUE_ERROR, "generator '%s' is already running", g.name)
+	}
+	g.started, g.running = true, true
+	var step generatorStep
+	g.interpreter.blocking(func() {
+		g.co.resume <- sent
+		step = <-g.co.yields
+	})
+	g.running = false
+	if step.done {
+		g.finished = true
+		return &Nil{}, false, step.err
+	}
+	return step.value, true, nil
+}
+
+// Next gives the next value the generator yields.
+func (g *Generator) Next() (Object, bool, error) {
+	return g.resume(&Nil{})
+}
+
+// close stops the generator. One waiting at a yield unwinds, running its
+// deferred calls and finally blocks, before close returns.
+func (g *Generator) close() error {
+	if g.finished {
+		return nil
+	}
+	if g.running {
+		return newRuntimeError(VALUE_ERROR, "generator '%s' is already running", g.name)
+	}
+	g.finished = true
+	g.co.close()
+	if !g.started {
+		return nil
+	}
+	var step generatorStep
+	g.interpreter.blocking(func() {
+		step = <-g.co.yields
+	})
+	return step.err
+}
+
+func generatorArg(name string, args []Object, count int) (*Generator, error) {
+	if err := checkArgs(name, args, count, count); err != nil {
+		return nil, err
+	}
+	g, ok := args[0].(*Generator)
+	if !ok {
+		return nil, newTypeError("%s() expects a generator, got %s", name, args[0].Type())
+	}
+	return g, nil
+}
+
+// generatorNext is gen.next(), the next value the generator yields or nil
+// once it has finished.
+func generatorNext(args []Object) (Object, error) {
+	g, err := generatorArg("next", args, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	value, _, err := g.resume(&Nil{})
+	return value, err
+}
+
+// generatorSend is gen.send(value), resuming the generator with value as
+// the result of the yield it is waiting at and giving the next value it
+// yields. A generator that has not started is not waiting at a yield so it
+// must be started with next() first.
+func generatorSend(args []Object) (Object, error) {
+	g, err := generatorArg("send", args, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	if !g.started {
+		if _, isNil := args[1].(*Nil); !isNil {
+			return &Nil{}, newTypeError("can't send a value to generator '%s' before it has started, call next() first", g.name)
+		}
+	}
+	value, _, err := g.resume(args[1])
+	return value, err
+}
+
+// generatorClose is gen.close().
+func generatorClose(args []Object) (Object, error) {
+	g, err := generatorArg("close", args, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	return &Nil{}, g.close()
+}
+
+// GetAttribute gives done, whether the generator has finished.
+func (g *Generator) GetAttribute(name string) (Object, bool) {
+	if name == "done" {
+		return &Boolean{value: g.finished}, true
+	}
+	return nil, false
+}
+
+func (g *Generator) Type() string {
+	return "generator"
+}
+
+func (g *Generator) Value() interface{} {
+	return g
+}
+
+func (g *Generator) String() *String {
+	return &String{value: "<generator " + g.name + ">"}
+}
+
+func (g *Generator) Equal(other Object) (Object, error) {
+	return &Boolean{value: g == other}, nil
+}
+
+func (g *Generator) NotEqual(other Object) (Object, error) {
+	return &Boolean{value: g != other}, nil
+}
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGenerators(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected Object
+		err      string
+	}{
+		{
+			name:     "test range over generator",
+			input:    "func count(n) {\n for i := 0; i < n; i++ {\n  yield i\n }\n}\ntotal = 0\nfor v := range count(5) {\n total = total + v\n}\ntotal",
+			expected: &Integer{value: 10},
+		},
+		{
+			name:     "test generator body runs lazily",
+			input:    "steps = 0\nfunc gen() {\n steps = steps + 1\n yield 1\n steps = steps + 1\n yield 2\n}\ng = gen()\nbefore = steps\nfirst = g.next()\n[before, first, steps]",
+			expected: &Array{Elements: []Object{&Integer{value: 0}, &Integer{value: 1}, &Integer{value: 1}}},
+		},
+		{
+			name:     "test next after the end",
+			input:    "func one() {\n yield 1\n}\ng = one()\n[g.done, g.next(), g.next(), g.done]",
+			expected: &Array{Elements: []Object{&Boolean{value: false}, &Integer{value: 1}, &Nil{}, &Boolean{value: true}}},
+		},
+		{
+			name:     "test bare yield",
+			input:    "func gen() {\n yield\n}\ng = gen()\n[g.next(), g.done]",
+			expected: &Array{Elements: []Object{&Nil{}, &Boolean{value: false}}},
+		},
+		{
+			name:     "test send resumes yield with a value",
+			input:    "func acc() {\n total = 0\n for i := 0; i < 10; i++ {\n  v = yield total\n  total = total + v\n }\n}\ng = acc()\ng.next()\ng.send(5)\ng.send(10)",
+			expected: &Integer{value: 15},
+		},
+		{
+			name:     "test generator over generator",
+			input:    "func count(n) {\n for i := 0; i < n; i++ {\n  yield i\n }\n}\nfunc evens(g) {\n for v := range g {\n  if v % 2 == 0 {\n   yield v\n  }\n }\n}\ntotal = 0\nfor v := range evens(count(7)) {\n total = total + v\n}\ntotal",
+			expected: &Integer{value: 12},
+		},
+		{
+			name:     "test close runs deferred calls",
+			input:    "closed = false\nfunc gen() {\n defer func() {\n  closed = true\n }()\n yield 1\n yield 2\n}\ng = gen()\ng.next()\ng.close()\n[closed, g.done, g.next()]",
+			expected: &Array{Elements: []Object{&Boolean{value: true}, &Boolean{value: true}, &Nil{}}},
+		},
+		{
+			name:  "test error raised by the body",
+			input: "func bad() {\n yield 1\n throw \"broken\"\n}\ng = bad()\ng.next()\ng.next()",
+			err:   "broken",
+		},
+		{
+			name:  "test send before start",
+			input: "func gen() {\n yield 1\n}\ngen().send(1)",
+			err:   "can't send a value to generator 'gen' before it has started, call next() first",
+		},
+		{
+			name:  "test yield outside function",
+			input: "yield 1",
+			err:   "'yield' outside function on line: 1",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := evalInput(test.input)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestGeneratorStreamsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("INFO start\nERROR disk full\nINFO retry\nERROR disk still full\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	input := fmt.Sprintf("import \"fs\"\nfunc errors(path) {\n for line := range fs.open(path) {\n  if line.contains(\"ERROR\") {\n   yield line\n  }\n }\n}\nfound = []\nfor line := range errors(%q) {\n found = found + [line]\n}\nfound", path)
+	result, err := evalInput(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &Array{Elements: []Object{&String{value: "ERROR disk full"}, &String{value: "ERROR disk still full"}}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("expected %v, got %v", expected, result)
+	}
+}
@@ -0,0 +1,534 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HTTP_TIMEOUT is how long client requests wait for a response unless they
+// set a timeout of their own.
+const HTTP_TIMEOUT = 30 * time.Second
+
+// httpModule builds the native http module.
+func httpModule(e *Evaluator) map[string]Object {
+	functions := map[string]func(*Evaluator, []Object) (Object, error){
+		"get":     (*Evaluator).httpGet,
+		"post":    (*Evaluator).httpPost,
+		"request": (*Evaluator).httpRequest,
+		"serve":   (*Evaluator).httpServe,
+	}
+	module := map[string]Object{}
+	for name, fn := range functions {
+		module[name] = &GoFunction{Name: name, EvalFunc: fn}
+	}
+	return module
+}
+
+// httpResponseMethods is the method table of HTTPResponse.
+func httpResponseMethods() map[string]*GoFunction {
+	return map[string]*GoFunction{
+		"json": {Name: "json", Func: httpResponseJSON},
+	}
+}
+
+// httpRequestMethods is the method table of HTTPRequest.
+func httpRequestMethods() map[string]*GoFunction {
+	return map[string]*GoFunction{
+		"json": {Name: "json", Func: httpRequestJSON},
+	}
+}
+
+// responseWriterMethods is the method table of ResponseWriter.
+func responseWriterMethods() map[string]*GoFunction {
+	return map[string]*GoFunction{
+		"status": {Name: "status", Func: responseWriterStatus},
+		"header": {Name: "header", Func: responseWriterHeader},
+		"write":  {Name: "write", Func: responseWriterWrite},
+		"json":   {Name: "json", Func: responseWriterJSON},
+	}
+}
+
+// requestOptions are read from the options map of a client request, which
+// may set "headers", a map, "timeout", a duration or number of seconds, and
+// the body as either "body", sent as it is, or "json", a value encoded as
+// JSON.
+type requestOptions struct {
+	headers     http.Header
+	body        io.Reader
+	contentType string
+	timeout     time.Duration
+}
+
+func newRequestOptions(name string, args []Object, i int) (*requestOptions, error) {
+	options := &requestOptions{headers: http.Header{}, timeout: HTTP_TIMEOUT}
+	if len(args) <= i {
+		return options, nil
+	}
+	m, ok := args[i].(*Map)
+	if !ok {
+		return nil, newTypeError("%s() expects a map of options, got %s", name, args[i].Type())
+	}
+	for _, key := range m.Keys() {
+		value, _ := m.Get(key)
+		switch key {
+		case "headers":
+			headers, ok := value.(*Map)
+			if !ok {
+				return nil, newTypeError("%s() expects headers to be a map, got %s", name, value.Type())
+			}
+			for _, header := range headers.Keys() {
+				headerValue, _ := headers.Get(header)
+				str, err := stringArg(name, []Object{headerValue}, 0)
+				if err != nil {
+					return nil, err
+				}
+				options.headers.Add(header, str)
+			}
+		case "timeout":
+			timeout, err := secondsArg(name, value)
+			if err != nil {
+				return nil, err
+			}
+			options.timeout = timeout
+		case "body", "json":
+			if err := options.setBody(name, value, key == "json"); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, newRuntimeError(VALUE_ERROR, "%s() got an unknown option %q", name, key)
+		}
+	}
+	return options, nil
+}
+
+// setBody sets the body of a request. Strings are sent as they are unless
+// asJSON is set, anything else is encoded as JSON.
+func (o *requestOptions) setBody(name string, value Object, asJSON bool) error {
+	if str, ok := value.(*String); ok && !asJSON {
+		o.body = strings.NewReader(str.value)
+		o.contentType = "text/plain; charset=utf-8"
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := encodeJSON(&buf, value, map[Object]bool{}); err != nil {
+		return err
+	}
+	o.body = &buf
+	o.contentType = "application/json"
+	return nil
+}
+
+// secondsArg reads a timeout given as a duration or a number of seconds.
+func secondsArg(name string, value Object) (time.Duration, error) {
+	if d, ok := value.(*Duration); ok {
+		return d.duration, nil
+	}
+	seconds, ok := toFloat64(value)
+	if !ok {
+		return 0, newTypeError("%s() expects a duration or number of seconds, got %s", name, value.Type())
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// doRequest sends a request and reads the whole response. Error statuses
+// are not errors, only failing to get a response is.
+func (e *Evaluator) doRequest(method, url string, options *requestOptions) (Object, error) {
+	// the request is cancelled when the program is stopped
+	request, err := http.NewRequestWithContext(e.stop, method, url, options.body)
+	if err != nil {
+		return &Nil{}, newRuntimeError(VALUE_ERROR, "%s", err.Error())
+	}
+	if options.contentType != "" {
+		request.Header.Set("Content-Type", options.contentType)
+	}
+	for header, values := range options.headers {
+		request.Header[header] = values
+	}
+
+	client := &http.Client{Timeout: options.timeout}
+	var response *http.Response
+	var body []byte
+	waitErr := e.wait(func(<-chan struct{}) {
+		if response, err = client.Do(request); err != nil {
+			return
+		}
+		defer response.Body.Close()
+		body, err = io.ReadAll(response.Body)
+	})
+	if waitErr != nil {
+		return &Nil{}, waitErr
+	}
+	if err != nil {
+		return &Nil{}, newRuntimeError(HTTP_ERROR, "%s", err.Error())
+	}
+	return &HTTPResponse{
+		nativeObject: nativeObject{"response"},
+		Status:       response.StatusCode,
+		headers:      response.Header,
+		body:         string(body),
+	}, nil
+}
+
+// httpGet is get(url) or get(url, options).
+func (e *Evaluator) httpGet(args []Object) (Object, error) {
+	values, err := stringArgs("get", args, 1, 2, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	options, err := newRequestOptions("get", args, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	return e.doRequest(http.MethodGet, values[0], options)
+}
+
+// httpPost is post(url, body) or post(url, body, options). A string body is
+// sent as text, anything else as JSON.
+func (e *Evaluator) httpPost(args []Object) (Object, error) {
+	values, err := stringArgs("post", args, 2, 3, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	options, err := newRequestOptions("post", args, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	if err := options.setBody("post", args[1], false); err != nil {
+		return &Nil{}, err
+	}
+	return e.doRequest(http.MethodPost, values[0], options)
+}
+
+// httpRequest is request(method, url) or request(method, url, options).
+func (e *Evaluator) httpRequest(args []Object) (Object, error) {
+	values, err := stringArgs("request", args, 2, 3, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	options, err := newRequestOptions("request", args, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	return e.doRequest(strings.ToUpper(values[0]), values[1], options)
+}
+
+// headerMap turns headers into a map from their canonical names to their
+// values joined by commas, sorted by name.
+func headerMap(headers http.Header) *Map {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	m := NewMap()
+	for _, name := range names {
+		m.Set(name, &String{value: strings.Join(headers[name], ", ")})
+	}
+	return m
+}
+
+// HTTPResponse is the response to a client request.
+type HTTPResponse struct {
+	nativeObject
+	Status  int
+	headers http.Header
+	body    string
+}
+
+func httpResponseJSON(args []Object) (Object, error) {
+	if err := checkArgs("json", args, 1, 1); err != nil {
+		return &Nil{}, err
+	}
+	response, ok := args[0].(*HTTPResponse)
+	if !ok {
+		return &Nil{}, newTypeError("json() expects a response, got %s", args[0].Type())
+	}
+	return jsonParse([]Object{&String{value: response.body}})
+}
+
+func (r *HTTPResponse) GetAttribute(name string) (Object, bool) {
+	switch name {
+	case "status":
+		return &Integer{value: int64(r.Status)}, true
+	case "statusText":
+		return &String{value: http.StatusText(r.Status)}, true
+	case "ok":
+		return &Boolean{value: r.Status >= 200 && r.Status < 300}, true
+	case "headers":
+		return headerMap(r.headers), true
+	case "body":
+		return &String{value: r.body}, true
+	}
+	return nil, false
+}
+
+func (r *HTTPResponse) Type() string {
+	return "response"
+}
+
+func (r *HTTPResponse) Value() interface{} {
+	return r.body
+}
+
+func (r *HTTPResponse) String() *String {
+	return &String{value: fmt.Sprintf("<response %d %s>", r.Status, http.StatusText(r.Status))}
+}
+
+func (r *HTTPResponse) Equal(other Object) (Object, error) {
+	return &Boolean{value: r == other}, nil
+}
+
+func (r *HTTPResponse) NotEqual(other Object) (Object, error) {
+	return &Boolean{value: r != other}, nil
+}
+
+// httpServe is serve(addr, handler), serving HTTP on addr until it fails or
+// the program is stopped.
+// handler is called with a request and a response writer for every request.
+func (e *Evaluator) httpServe(args []Object) (Object, error) {
+	if err := checkArgs("serve", args, 2, 2); err != nil {
+		return &Nil{}, err
+	}
+	addr, err := stringArg("serve", args, 0)
+	if err != nil {
+		return &Nil{}, err
+	}
+	server := &http.Server{Addr: addr, Handler: e.httpHandler(args[1])}
+	waitErr := e.wait(func(stop <-chan struct{}) {
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-stop:
+				server.Close()
+			case <-done:
+			}
+		}()
+		err = server.ListenAndServe()
+	})
+	if waitErr != nil {
+		return &Nil{}, waitErr
+	}
+	if err != nil {
+		return &Nil{}, newRuntimeError(HTTP_ERROR, "%s", err.Error())
+	}
+	return &Nil{}, nil
+}
+
+// httpHandler adapts a GoScript function to an http.Handler. Every request
+// is handled by a task of its own, so a handler waiting on something lets
+// the others run. A string returned by the function is written as the body
+// if the function wrote nothing itself, an error raised by it is a 500
+// response and its traceback goes to the error output.
+func (e *Evaluator) httpHandler(handler Object) http.Handler {
+	server := e.spawn()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		request := &HTTPRequest{nativeObject: nativeObject{"request"}, request: r, body: string(body)}
+		writer := &ResponseWriter{nativeObject: nativeObject{"responsewriter"}, writer: w, status: http.StatusOK}
+
+		task := server.spawn()
+		task.acquire()
+		defer task.release()
+		result, err := task.callObject(handler, []Object{request, writer}, 0)
+		if err != nil {
+			// the traceback is for whoever runs the server, not its clients
+			fmt.Fprintln(task.errOut, toRuntimeError(err).Traceback())
+			if !writer.written {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+			return
+		}
+		if str, ok := result.(*String); ok && !writer.written {
+			writer.write(str.value)
+		}
+		if !writer.written {
+			w.WriteHeader(writer.status)
+		}
+	})
+}
+
+// HTTPRequest is a request received by http.serve.
+type HTTPRequest struct {
+	nativeObject
+	request *http.Request
+	body    string
+}
+
+func httpRequestJSON(args []Object) (Object, error) {
+	if err := checkArgs("json", args, 1, 1); err != nil {
+		return &Nil{}, err
+	}
+	request, ok := args[0].(*HTTPRequest)
+	if !ok {
+		return &Nil{}, newTypeError("json() expects a request, got %s", args[0].Type())
+	}
+	return jsonParse([]Object{&String{value: request.body}})
+}
+
+func (r *HTTPRequest) GetAttribute(name string) (Object, bool) {
+	switch name {
+	case "method":
+		return &String{value: r.request.Method}, true
+	case "path":
+		return &String{value: r.request.URL.Path}, true
+	case "url":
+		return &String{value: r.request.URL.RequestURI()}, true
+	case "query":
+		query := r.request.URL.Query()
+		names := make([]string, 0, len(query))
+		for name := range query {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		m := NewMap()
+		for _, name := range names {
+			m.Set(name, &String{value: query.Get(name)})
+		}
+		return m, true
+	case "headers":
+		return headerMap(r.request.Header), true
+	case "body":
+		return &String{value: r.body}, true
+	case "remoteAddr":
+		return &String{value: r.request.RemoteAddr}, true
+	}
+	return nil, false
+}
+
+func (r *HTTPRequest) Type() string {
+	return "request"
+}
+
+func (r *HTTPRequest) Value() interface{} {
+	return r.request
+}
+
+func (r *HTTPRequest) String() *String {
+	return &String{value: fmt.Sprintf("<request %s %s>", r.request.Method, r.request.URL.RequestURI())}
+}
+
+func (r *HTTPRequest) Equal(other Object) (Object, error) {
+	return &Boolean{value: r == other}, nil
+}
+
+func (r *HTTPRequest) NotEqual(other Object) (Object, error) {
+	return &Boolean{value: r != other}, nil
+}
+
+// ResponseWriter builds the response to a request received by http.serve.
+// The status and headers must be set before the body is written.
+type ResponseWriter struct {
+	nativeObject
+	writer  http.ResponseWriter
+	status  int
+	written bool
+}
+
+func (w *ResponseWriter) write(body string) error {
+	if !w.written {
+		w.writer.WriteHeader(w.status)
+		w.written = true
+	}
+	if _, err := io.WriteString(w.writer, body); err != nil {
+		return newRuntimeError(HTTP_ERROR, "%s", err.Error())
+	}
+	return nil
+}
+
+func responseWriterArg(name string, args []Object, count int) (*ResponseWriter, error) {
+	if err := checkArgs(name, args, count, count); err != nil {
+		return nil, err
+	}
+	writer, ok := args[0].(*ResponseWriter)
+	if !ok {
+		return nil, newTypeError("%s() expects a response writer, got %s", name, args[0].Type())
+	}
+	return writer, nil
+}
+
+func responseWriterStatus(args []Object) (Object, error) {
+	writer, err := responseWriterArg("status", args, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	status, err := intArg("status", args, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	if status < 100 || status > 999 {
+		return &Nil{}, newRuntimeError(VALUE_ERROR, "invalid status code %d", status)
+	}
+	writer.status = status
+	return &Nil{}, nil
+}
+
+func responseWriterHeader(args []Object) (Object, error) {
+	writer, err := responseWriterArg("header", args, 3)
+	if err != nil {
+		return &Nil{}, err
+	}
+	values, err := stringArgs("header", args[1:], 2, 2, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	writer.writer.Header().Add(values[0], values[1])
+	return &Nil{}, nil
+}
+
+func responseWriterWrite(args []Object) (Object, error) {
+	writer, err := responseWriterArg("write", args, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	body, err := stringArg("write", args, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	return &Nil{}, writer.write(body)
+}
+
+// responseWriterJSON writes a value as a JSON body.
+func responseWriterJSON(args []Object) (Object, error) {
+	writer, err := responseWriterArg("json", args, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	var buf bytes.Buffer
+	if err := encodeJSON(&buf, args[1], map[Object]bool{}); err != nil {
+		return &Nil{}, err
+	}
+	if !writer.written {
+		writer.writer.Header().Set("Content-Type", "application/json")
+	}
+	return &Nil{}, writer.write(buf.String())
+}
+
+func (w *ResponseWriter) Type() string {
+	return "responsewriter"
+}
+
+func (w *ResponseWriter) Value() interface{} {
+	return w.writer
+}
+
+func (w *ResponseWriter) String() *String {
+	return &String{value: "<responsewriter>"}
+}
+
+func (w *ResponseWriter) Equal(other Object) (Object, error) {
+	return &Boolean{value: w == other}, nil
+}
+
+func (w *ResponseWriter) NotEqual(other Object) (Object, error) {
+	return &Boolean{value: w != other}, nil
+}
@@ -0,0 +1,215 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// echoHandler answers with the method, the X-Test header, the content type
+// and the body of the request, apart from the paths it handles specially.
+func echoHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/slow":
+		time.Sleep(200 * time.Millisecond)
+	case "/json":
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id": 1, "tags": ["a", "b"]}`)
+		return
+	case "/missing":
+		http.NotFound(w, r)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	fmt.Fprintf(w, "%s|%s|%s|%s", r.Method, r.Header.Get("X-Test"), r.Header.Get("Content-Type"), body)
+}
+
+func TestHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(echoHandler))
+	defer server.Close()
+
+	cases := []struct {
+		name     string
+		input    string
+		expected Object
+		err      string
+	}{
+		{
+			name:     "test get",
+			input:    "r = http.get(url + \"/echo\", {\"headers\": {\"X-Test\": \"yes\"}})\n[r.status, r.ok, r.body]",
+			expected: &Array{Elements: []Object{&Integer{value: 200}, &Boolean{value: true}, &String{value: "GET|yes||"}}},
+		},
+		{
+			name:     "test post string",
+			input:    "http.post(url, \"hello\").body",
+			expected: &String{value: "POST||text/plain; charset=utf-8|hello"},
+		},
+		{
+			name:     "test post json",
+			input:    "http.post(url, {\"a\": [1, true]}).body",
+			expected: &String{value: `POST||application/json|{"a":[1,true]}`},
+		},
+		{
+			name:     "test request with json option",
+			input:    "http.request(\"put\", url, {\"json\": \"text\"}).body",
+			expected: &String{value: `PUT||application/json|"text"`},
+		},
+		{
+			name:     "test response json",
+			input:    "r = http.get(url + \"/json\")\ndoc = r.json()\n[r.headers[\"Content-Type\"], doc[\"id\"], doc[\"tags\"][1]]",
+			expected: &Array{Elements: []Object{&String{value: "application/json"}, &Integer{value: 1}, &String{value: "b"}}},
+		},
+		{
+			name:     "test error status is not an error",
+			input:    "r = http.get(url + \"/missing\")\n[r.status, r.statusText, r.ok]",
+			expected: &Array{Elements: []Object{&Integer{value: 404}, &String{value: "Not Found"}, &Boolean{value: false}}},
+		},
+		{
+			name:     "test timeout",
+			input:    "try {\n http.get(url + \"/slow\", {\"timeout\": 0.05})\n} catch e {\n kind = e.type\n}\nkind",
+			expected: &String{value: "HTTPError"},
+		},
+		{
+			name:  "test unknown option",
+			input: "http.get(url, {\"retries\": 3})",
+			err:   "get() got an unknown option \"retries\"",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			input := fmt.Sprintf("import \"http\"\nurl = %q\n%s", server.URL, test.input)
+			result, err := evalInput(input)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestHTTPServer(t *testing.T) {
+	script := `
+func handle(req, res) {
+ if req.path == "/json" {
+  doc = req.json()
+  res.status(201)
+  res.json({"name": doc["name"], "method": req.method})
+  return
+ }
+ if req.path == "/fail" {
+  throw "handler failed"
+ }
+ res.header("X-Handled", "yes")
+ return "hello " + req.query["name"]
+}
+`
+	program, err := NewV1Parser(NewV1Lexer(script), false).ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	evaluator := NewEvaluator(false)
+	var errOut bytes.Buffer
+	evaluator.SetErrorOutput(&errOut)
+	if _, err := evaluator.Evaluate(program); err != nil {
+		t.Fatal(err)
+	}
+	handler, err := evaluator.getIdentifier("handle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(evaluator.httpHandler(handler))
+	defer server.Close()
+
+	cases := []struct {
+		name        string
+		method      string
+		path        string
+		body        string
+		status      int
+		contentType string
+		header      string
+		expected    string
+		traceback   string
+	}{
+		{
+			name:        "test returned string is the body",
+			method:      http.MethodGet,
+			path:        "/greet?name=world",
+			status:      http.StatusOK,
+			contentType: "text/plain; charset=utf-8",
+			header:      "yes",
+			expected:    "hello world",
+		},
+		{
+			name:        "test json request and response",
+			method:      http.MethodPost,
+			path:        "/json",
+			body:        `{"name": "goscript"}`,
+			status:      http.StatusCreated,
+			contentType: "application/json",
+			expected:    `{"name":"goscript","method":"POST"}`,
+		},
+		{
+			name:        "test error is a server error",
+			method:      http.MethodGet,
+			path:        "/fail",
+			status:      http.StatusInternalServerError,
+			contentType: "text/plain; charset=utf-8",
+			expected:    "Internal Server Error\n",
+			traceback:   "Error: handler failed\n    at handle (line 0)\n",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			request, err := http.NewRequest(test.method, server.URL+test.path, strings.NewReader(test.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			response, err := http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer response.Body.Close()
+			body, err := io.ReadAll(response.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response.StatusCode != test.status {
+				t.Fatalf("expected status %d, got %d", test.status, response.StatusCode)
+			}
+			if contentType := response.Header.Get("Content-Type"); contentType != test.contentType {
+				t.Fatalf("expected content type %q, got %q", test.contentType, contentType)
+			}
+			if header := response.Header.Get("X-Handled"); header != test.header {
+				t.Fatalf("expected X-Handled %q, got %q", test.header, header)
+			}
+			if string(body) != test.expected {
+				t.Fatalf("expected body %q, got %q", test.expected, body)
+			}
+			// handlers write under the interpreter lock
+			evaluator.acquire()
+			traceback := errOut.String()
+			errOut.Reset()
+			evaluator.release()
+			if traceback != test.traceback {
+				t.Fatalf("expected traceback %q, got %q", test.traceback, traceback)
+			}
+		})
+	}
+}
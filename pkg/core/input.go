@@ -0,0 +1,181 @@
+package core
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// SetInput sets where input and stdin read from, os.Stdin by default.
+func (e *Evaluator) SetInput(in io.Reader) {
+	e.in = bufio.NewReader(in)
+}
+
+// read runs fn reading the input without the interpreter lock, so other
+// tasks run while it waits, and under the input lock so that reads from
+// several tasks do not interleave. A program stopped while it waits does
+// not wait for the read to finish, what it reads is lost.
+func (i *interpreter) read(fn func()) error {
+	return i.wait(func(stop <-chan struct{}) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			i.inputLock.Lock()
+			defer i.inputLock.Unlock()
+			fn()
+		}()
+		select {
+		case <-done:
+		case <-stop:
+		}
+	})
+}
+
+// ReadLine reads the next line of input without its line ending. Programs
+// embedding the evaluator that read from its input themselves, like the
+// REPL, use it so that scripts reading input see the lines that follow
+// rather than losing them to a second buffer.
+func (e *Evaluator) ReadLine() (string, error) {
+	e.inputLock.Lock()
+	defer e.inputLock.Unlock()
+	return e.readLine()
+}
+
+func (i *interpreter) readLine() (string, error) {
+	line, err := i.in.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+// input is input() or input(prompt), writing the prompt and returning the
+// line read, or nil at the end of the input.
+func (e *Evaluator) input(args []Object) (Object, error) {
+	if err := checkArgs("input", args, 0, 1); err != nil {
+		return &Nil{}, err
+	}
+	if len(args) == 1 {
+		if _, err := io.WriteString(e.out, args[0].String().value); err != nil {
+			return &Nil{}, newOSError(err)
+		}
+	}
+	return e.stdin.readLine()
+}
+
+// stdinMethods is the method table of Stdin.
+func stdinMethods() map[string]*GoFunction {
+	return map[string]*GoFunction{
+		"readLine": {Name: "readLine", Func: stdinReadLine},
+		"lines":    {Name: "lines", Func: stdinLines},
+		"readAll":  {Name: "readAll", Func: stdinReadAll},
+	}
+}
+
+// Stdin is the stdin builtin reading the evaluator's input. Ranging over it
+// reads it line by line.
+type Stdin struct {
+	nativeObject
+	interpreter *interpreter
+}
+
+func (s *Stdin) readLine() (Object, error) {
+	var line string
+	var err error
+	if waitErr := s.interpreter.read(func() {
+		line, err = s.interpreter.readLine()
+	}); waitErr != nil {
+		return &Nil{}, waitErr
+	}
+	if err == io.EOF {
+		return &Nil{}, nil
+	}
+	if err != nil {
+		return &Nil{}, newOSError(err)
+	}
+	return &String{value: line}, nil
+}
+
+// Next returns the next line, the iteration ends with the input.
+func (s *Stdin) Next() (Object, bool, error) {
+	line, err := s.readLine()
+	if err != nil {
+		return &Nil{}, false, err
+	}
+	if _, ok := line.(*Nil); ok {
+		return line, false, nil
+	}
+	return line, true, nil
+}
+
+func stdinArg(name string, args []Object) (*Stdin, error) {
+	if err := checkArgs(name, args, 1, 1); err != nil {
+		return nil, err
+	}
+	stdin, ok := args[0].(*Stdin)
+	if !ok {
+		return nil, newTypeError("%s() expects stdin, got %s", name, args[0].Type())
+	}
+	return stdin, nil
+}
+
+// stdinReadLine returns the next line or nil at the end of the input.
+func stdinReadLine(args []Object) (Object, error) {
+	stdin, err := stdinArg("readLine", args)
+	if err != nil {
+		return &Nil{}, err
+	}
+	return stdin.readLine()
+}
+
+// stdinLines returns stdin itself, for line := range stdin.lines() reads as
+// well as ranging over stdin.
+func stdinLines(args []Object) (Object, error) {
+	stdin, err := stdinArg("lines", args)
+	if err != nil {
+		return &Nil{}, err
+	}
+	return stdin, nil
+}
+
+// stdinReadAll returns the rest of the input.
+func stdinReadAll(args []Object) (Object, error) {
+	stdin, err := stdinArg("readAll", args)
+	if err != nil {
+		return &Nil{}, err
+	}
+	var data []byte
+	var readErr error
+	if err := stdin.interpreter.read(func() {
+		data, readErr = io.ReadAll(stdin.interpreter.in)
+	}); err != nil {
+		return &Nil{}, err
+	}
+	if readErr != nil {
+		return &Nil{}, newOSError(readErr)
+	}
+	return &String{value: string(data)}, nil
+}
+
+func (s *Stdin) Type() string {
+	return "stdin"
+}
+
+func (s *Stdin) Value() interface{} {
+	return s.interpreter.in
+}
+
+func (s *Stdin) String() *String {
+	return &String{value: "<stdin>"}
+}
+
+func (s *Stdin) Equal(other Object) (Object, error) {
+	return &Boolean{value: s == other}, nil
+}
+
+func (s *Stdin) NotEqual(other Object) (Object, error) {
+	return &Boolean{value: s != other}, nil
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInput(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		stdin    string
+		expected Object
+		output   string
+	}{
+		{
+			name:     "test input with prompt",
+			input:    "name = input(\"name: \")\n\"hello \" + name",
+			stdin:    "world\nignored\n",
+			expected: &String{value: "hello world"},
+			output:   "name: ",
+		},
+		{
+			name:     "test input at end of input",
+			input:    "input()",
+			stdin:    "",
+			expected: &Nil{},
+		},
+		{
+			name:     "test read line strips line endings",
+			input:    "[stdin.readLine(), stdin.readLine(), stdin.readLine()]",
+			stdin:    "one\r\ntwo",
+			expected: &Array{Elements: []Object{&String{value: "one"}, &String{value: "two"}, &Nil{}}},
+		},
+		{
+			name:     "test range over stdin lines",
+			input:    "total = 0\nfor i, line := range stdin.lines() {\n total = total + length(line)\n}\ntotal",
+			stdin:    "a\nbb\nccc\n",
+			expected: &Integer{value: 6},
+		},
+		{
+			name:     "test read all after read line",
+			input:    "stdin.readLine()\nstdin.readAll()",
+			stdin:    "header\nrow 1\nrow 2\n",
+			expected: &String{value: "row 1\nrow 2\n"},
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			program, err := NewV1Parser(NewV1Lexer(test.input), false).ParseProgram()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			evaluator := NewEvaluator(false)
+			evaluator.SetOutput(&out)
+			evaluator.SetInput(strings.NewReader(test.stdin))
+			var result Object
+			for _, stmt := range program.(*BlockStatement).Statements {
+				if result, err = evaluator.Evaluate(stmt); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, result)
+			}
+			if out.String() != test.output {
+				t.Fatalf("expected output %q, got %q", test.output, out.String())
+			}
+		})
+	}
+}
+
+func TestReadLineSharesInput(t *testing.T) {
+	program, err := NewV1Parser(NewV1Lexer("answer = input()"), false).ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	evaluator := NewEvaluator(false)
+	evaluator.SetInput(strings.NewReader("answer = input()\n42\nnext\n"))
+
+	// the first line is read like the REPL reads a statement
+	if line, err := evaluator.ReadLine(); err != nil || line != "answer = input()" {
+		t.Fatalf("expected the statement, got %q, %v", line, err)
+	}
+	if _, err := evaluator.Evaluate(program); err != nil {
+		t.Fatal(err)
+	}
+	answer, err := evaluator.getIdentifier("answer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(answer, &String{value: "42"}) {
+		t.Fatalf("expected input() to read the following line, got %v", answer)
+	}
+	if line, err := evaluator.ReadLine(); err != nil || line != "next" {
+		t.Fatalf("expected the line after the input, got %q, %v", line, err)
+	}
+}
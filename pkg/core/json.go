@@ -0,0 +1,225 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// jsonModule builds the native json module.
+func jsonModule(e *Evaluator) map[string]Object {
+	return map[string]Object{
+		"parse":     &GoFunction{Name: "parse", Func: jsonParse},
+		"stringify": &GoFunction{Name: "stringify", Func: jsonStringify},
+	}
+}
+
+// jsonParse decodes a JSON document. Objects become maps keeping the order
+// of their keys and numbers written without a fraction or exponent become
+// integers, or bigints when they do not fit in 64 bits.
+func jsonParse(args []Object) (Object, error) {
+	values, err := stringArgs("parse", args, 1, 1, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	decoder := json.NewDecoder(strings.NewReader(values[0]))
+	decoder.UseNumber()
+	value, err := decodeJSON(decoder)
+	if err != nil {
+		return &Nil{}, newJSONError(err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return &Nil{}, newRuntimeError(JSON_ERROR, "invalid JSON: unexpected data after top-level value")
+	}
+	return value, nil
+}
+
+func newJSONError(err error) *RuntimeError {
+	var runtimeErr *RuntimeError
+	if errors.As(err, &runtimeErr) {
+		return runtimeErr
+	}
+	if err == io.EOF {
+		return newRuntimeError(JSON_ERROR, "invalid JSON: unexpected end of JSON input")
+	}
+	return newRuntimeError(JSON_ERROR, "invalid JSON: %s", err.Error())
+}
+
+func decodeJSON(decoder *json.Decoder) (Object, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token := token.(type) {
+	case json.Delim:
+		switch token {
+		case '{':
+			m := NewMap()
+			for decoder.More() {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := decodeJSON(decoder)
+				if err != nil {
+					return nil, err
+				}
+				m.Set(key.(string), value)
+			}
+			_, err := decoder.Token() // closing brace
+			return m, err
+		case '[':
+			array := &Array{Elements: []Object{}}
+			for decoder.More() {
+				value, err := decodeJSON(decoder)
+				if err != nil {
+					return nil, err
+				}
+				array.Elements = append(array.Elements, value)
+			}
+			_, err := decoder.Token() // closing bracket
+			return array, err
+		}
+	case json.Number:
+		return jsonNumber(token)
+	case string:
+		return &String{value: token}, nil
+	case bool:
+		return &Boolean{value: token}, nil
+	case nil:
+		return &Nil{}, nil
+	}
+	return nil, newRuntimeError(JSON_ERROR, "invalid JSON: unexpected %v", token)
+}
+
+func jsonNumber(number json.Number) (Object, error) {
+	literal := number.String()
+	if !strings.ContainsAny(literal, ".eE") {
+		if value, err := strconv.ParseInt(literal, 10, 64); err == nil {
+			return &Integer{value: value}, nil
+		}
+		value, _ := new(big.Int).SetString(literal, 10)
+		return &BigInt{value: value}, nil
+	}
+	value, err := strconv.ParseFloat(literal, 64)
+	if err != nil {
+		return nil, newRuntimeError(JSON_ERROR, "number %s is out of range", literal)
+	}
+	return &Float{value: value}, nil
+}
+
+// MAX_JSON_INDENT is the largest number of spaces stringify indents with.
+const MAX_JSON_INDENT = 10
+
+// jsonStringify is stringify(value) or stringify(value, indent) where indent
+// is a number of spaces or the string to indent with.
+func jsonStringify(args []Object) (Object, error) {
+	if err := checkArgs("stringify", args, 1, 2); err != nil {
+		return &Nil{}, err
+	}
+	var buf bytes.Buffer
+	if err := encodeJSON(&buf, args[0], map[Object]bool{}); err != nil {
+		return &Nil{}, err
+	}
+	if len(args) == 1 {
+		return &String{value: buf.String()}, nil
+	}
+
+	var indent string
+	switch arg := args[1].(type) {
+	case *Integer:
+		if arg.value < 0 || arg.value > MAX_JSON_INDENT {
+			return &Nil{}, newRuntimeError(VALUE_ERROR, "stringify() indent must be between 0 and %d, got %d", MAX_JSON_INDENT, arg.value)
+		}
+		indent = strings.Repeat(" ", int(arg.value))
+	case *String:
+		indent = arg.value
+	default:
+		return &Nil{}, newTypeError("stringify() indent must be an integer or string, got %s", arg.Type())
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, buf.Bytes(), "", indent); err != nil {
+		return &Nil{}, newJSONError(err)
+	}
+	return &String{value: indented.String()}, nil
+}
+
+// encodeJSON writes obj as compact JSON. seen holds the arrays and maps
+// being encoded so values containing themselves are reported rather than
+// recursing forever.
+func encodeJSON(buf *bytes.Buffer, obj Object, seen map[Object]bool) error {
+	switch obj := obj.(type) {
+	case *Nil:
+		buf.WriteString("null")
+	case *Boolean:
+		buf.WriteString(strconv.FormatBool(obj.value))
+	case *Integer:
+		buf.WriteString(strconv.FormatInt(obj.value, 10))
+	case *BigInt:
+		buf.WriteString(obj.value.String())
+	case *Decimal:
+		buf.WriteString(obj.String().value)
+	case *Float:
+		if math.IsNaN(obj.value) || math.IsInf(obj.value, 0) {
+			return newRuntimeError(JSON_ERROR, "cannot serialise %s to JSON", obj.String().value)
+		}
+		// a fraction or exponent is always written so the number parses
+		// back as a float
+		literal := strconv.FormatFloat(obj.value, 'g', -1, 64)
+		if !strings.ContainsAny(literal, ".eE") {
+			literal += ".0"
+		}
+		buf.WriteString(literal)
+	case *String:
+		encodeJSONString(buf, obj.value)
+	case *Array:
+		if seen[obj] {
+			return newRuntimeError(JSON_ERROR, "cannot serialise an array that contains itself")
+		}
+		seen[obj] = true
+		defer delete(seen, obj)
+		buf.WriteByte('[')
+		for i, element := range obj.Elements {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeJSON(buf, element, seen); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case *Map:
+		if seen[obj] {
+			return newRuntimeError(JSON_ERROR, "cannot serialise a map that contains itself")
+		}
+		seen[obj] = true
+		defer delete(seen, obj)
+		buf.WriteByte('{')
+		for i, key := range obj.keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			encodeJSONString(buf, key)
+			buf.WriteByte(':')
+			if err := encodeJSON(buf, obj.values[key], seen); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return newTypeError("cannot serialise %s to JSON", obj.Type())
+	}
+	return nil
+}
+
+func encodeJSONString(buf *bytes.Buffer, value string) {
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(value) // strings always encode
+	buf.Truncate(buf.Len() - 1)
+}
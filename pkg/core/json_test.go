@@ -0,0 +1,141 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJSONModule(t *testing.T) {
+	nested := NewMap()
+	nested.Set("name", &String{value: "goscript"})
+	nested.Set("tags", &Array{Elements: []Object{&String{value: "a"}, &Nil{}, &Boolean{value: true}}})
+
+	cases := []struct {
+		name  string
+		input string
+		// doc is bound to a variable of the same name, script strings
+		// cannot hold the quotes JSON needs
+		doc      string
+		expected Object
+		err      string
+	}{
+		{
+			name:     "test parse nested document",
+			doc:      `{"name": "goscript", "tags": ["a", null, true]}`,
+			input:    "json.parse(doc)",
+			expected: nested,
+		},
+		{
+			name:     "test parse keeps integers and floats apart",
+			input:    "json.parse(\"[1, 1.0, 1e2, -0]\")",
+			expected: &Array{Elements: []Object{&Integer{value: 1}, &Float{value: 1}, &Float{value: 100}, &Integer{value: 0}}},
+		},
+		{
+			name:     "test parse large integer",
+			input:    "json.parse(\"123456789012345678901234567890\")",
+			expected: bigIntFromString("123456789012345678901234567890"),
+		},
+		{
+			name:     "test parse keeps key order",
+			doc:      `{"z": 1, "a": 2, "m": 3}`,
+			input:    "json.parse(doc).keys()",
+			expected: newStringArray([]string{"z", "a", "m"}),
+		},
+		{
+			name:  "test parse invalid document",
+			input: "json.parse(\"[1, 2\")",
+			err:   "invalid JSON: unexpected end of JSON input",
+		},
+		{
+			name:  "test parse trailing data",
+			input: "json.parse(\"1 2\")",
+			err:   "invalid JSON: unexpected data after top-level value",
+		},
+		{
+			name:     "test stringify",
+			input:    "json.stringify({\"b\": [1, 2.5, false], \"a\": \"<x>\"})",
+			expected: &String{value: `{"b":[1,2.5,false],"a":"<x>"}`},
+		},
+		{
+			name:     "test stringify with indent",
+			input:    "json.stringify({\"a\": [1]}, 2)",
+			expected: &String{value: "{\n  \"a\": [\n    1\n  ]\n}"},
+		},
+		{
+			name:     "test round trip",
+			doc:      `{"id":7,"score":0.5,"ok":false,"items":[{"k":"v"}],"none":null,"big":123456789012345678901234567890}`,
+			input:    "json.stringify(json.parse(doc)) == doc",
+			expected: &Boolean{value: true},
+		},
+		{
+			name:     "test integral float round trip",
+			input:    "x = json.parse(json.stringify([1.0, -3.0, 1e21, 1]))\n[json.stringify(1.0), x[0], x[1], x[2], x[3]]",
+			expected: &Array{Elements: []Object{&String{value: "1.0"}, &Float{value: 1}, &Float{value: -3}, &Float{value: 1e21}, &Integer{value: 1}}},
+		},
+		{
+			name:  "test stringify negative indent",
+			input: "json.stringify([1], -1)",
+			err:   "stringify() indent must be between 0 and 10, got -1",
+		},
+		{
+			name:  "test stringify huge indent",
+			input: "json.stringify([1], 9223372036854775807)",
+			err:   "stringify() indent must be between 0 and 10, got 9223372036854775807",
+		},
+		{
+			name:  "test stringify function",
+			input: "func f() {\n}\njson.stringify({\"f\": f})",
+			err:   "cannot serialise function to JSON",
+		},
+		{
+			name:  "test stringify builtin function",
+			input: "json.stringify(print)",
+			err:   "cannot serialise gofunction to JSON",
+		},
+		{
+			name:  "test stringify self referencing array",
+			input: "a = [1]\na[0] = a\njson.stringify(a)",
+			err:   "cannot serialise an array that contains itself",
+		},
+		{
+			name:  "test stringify nan",
+			input: "import \"math\"\njson.stringify(math.nan)",
+			err:   "cannot serialise NaN to JSON",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := evalJSONInput(test.input, test.doc)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func evalJSONInput(input, doc string) (Object, error) {
+	program, err := NewV1Parser(NewV1Lexer("import \"json\"\n"+input), false).ParseProgram()
+	if err != nil {
+		return nil, err
+	}
+	evaluator := NewEvaluator(false)
+	evaluator.setIdentifier("doc", &String{value: doc})
+	var result Object = &Nil{}
+	for _, stmt := range program.(*BlockStatement).Statements {
+		result, err = evaluator.Evaluate(stmt)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
@@ -3,6 +3,7 @@ package core
 import (
 	"fmt"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 )
 
@@ -23,7 +24,7 @@ type V1Lexer struct {
 
 func NewV1Lexer(input string) Lexer {
 	l := &V1Lexer{
-		input:       input + "\n",
+		input:       strings.TrimPrefix(input, "\uFEFF") + "\n", // drop a leading byte order mark
 		indentStack: []int{0},
 		line:        1,
 		column:      0,
@@ -32,18 +33,23 @@ func NewV1Lexer(input string) Lexer {
 	return l
 }
 
+// readChar decodes the next rune from the input. position and readPosition
+// are byte offsets while column counts runes, so multi-byte characters take
+// up a single column just as they do in an editor.
 func (l *V1Lexer) readChar() {
 	if l.ch == '\n' {
 		l.line++
-		l.column = -1
+		l.column = 0
 	}
 
 	if l.readPosition >= len(l.input) {
 		l.ch = -1
+		l.position = len(l.input)
 	} else {
-		l.ch = []rune(string(l.input[l.readPosition]))[0]
+		ch, width := utf8.DecodeRuneInString(l.input[l.readPosition:])
+		l.ch = ch
 		l.position = l.readPosition
-		l.readPosition++
+		l.readPosition += width
 		l.column++
 	}
 }
@@ -71,14 +77,10 @@ func (l *V1Lexer) NextToken() Token {
 			tok = newToken(DEC, "--", l.line, l.column)
 			l.readChar()
 		} else if isDigit(l.peekChar()) {
-			tok.Type = INT
-			tok.Value = "-"
 			l.readChar() // Skip the "-"
-			l.skipWhitespace()
-			if isDigit(l.ch) {
-				tok.Value += l.readNumber()
-				return tok
-			}
+			tok.Type, tok.Value, tok.Error = l.readNumber()
+			tok.Value = "-" + tok.Value
+			return tok
 		} else if l.peekChar() == '=' {
 			tok = newToken(SUB_ASSIGN, "-=", l.line, l.column)
 			l.readChar()
@@ -179,6 +181,10 @@ func (l *V1Lexer) NextToken() Token {
 		l.skipComment()
 		return l.NextToken()
 	case '.':
+		if isDigit(l.peekChar()) {
+			tok.Type, tok.Value, tok.Error = l.readNumber()
+			return tok
+		}
 		tok = newToken(DOT, ".", l.line, l.column)
 	default:
 		if isLetter(l.ch) {
@@ -190,17 +196,20 @@ func (l *V1Lexer) NextToken() Token {
 			}
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Value = l.readNumber()
-			if strings.Contains(tok.Value, ".") {
-				tok.Type = FLOAT
-			} else {
-				tok.Type = INT
-			}
+			tok.Type, tok.Value, tok.Error = l.readNumber()
 			return tok
 		} else if l.ch == -1 {
 			tok.Type = EOF
 			tok.Value = "EOF"
 			return tok
+		} else if l.ch == utf8.RuneError {
+			tok = Token{
+				Type:   ERROR,
+				Value:  string(l.ch),
+				Line:   l.line,
+				Column: l.column,
+				Error:  "Invalid UTF-8 encoding",
+			}
 		} else {
 			tok = Token{
 				Type:   ERROR,
@@ -265,24 +274,135 @@ func (l *V1Lexer) skipWhitespace() {
 
 func (l *V1Lexer) readIdentifier() string {
 	position := l.position
-	for isLetter(l.ch) {
+	for isLetter(l.ch) || unicode.IsDigit(l.ch) {
 		l.readChar()
 	}
 	return l.input[position:l.position]
 }
 
-func (l *V1Lexer) readNumber() string {
+// readNumber scans a numeric literal using Go's literal syntax: 0x, 0o and 0b
+// prefixed integers, decimal integers and floats with an optional fraction
+// and exponent, and '_' separators between digits. An integer followed by n
+// is a BigInt literal. It returns the token type
+// (INT, BIGINT or FLOAT), the literal text without any n suffix and, for a
+// malformed literal, an error message in which case the type is ERROR.
+func (l *V1Lexer) readNumber() (TokenType, string, string) {
 	position := l.position
-	for isDigit(l.ch) || l.ch == '.' {
+	tokType := INT
+	errMsg := ""
+
+	if l.ch == '0' && strings.ContainsRune("xXoObB", l.peekChar()) {
+		var name string
+		var valid func(rune) bool
+		switch unicode.ToLower(l.peekChar()) {
+		case 'x':
+			name, valid = "hexadecimal", isHexDigit
+		case 'o':
+			name, valid = "octal", isOctalDigit
+		default:
+			name, valid = "binary", isBinaryDigit
+		}
+		l.readChar()
+		l.readChar()
+		if l.ch == '_' {
+			l.readChar() // Go allows a separator straight after the prefix
+		}
+		count, msg := l.readDigits(valid)
+		errMsg = msg
+		if errMsg == "" && count == 0 {
+			errMsg = fmt.Sprintf("%s literal has no digits", name)
+		}
+		if errMsg == "" && (isHexDigit(l.ch) || l.ch == '.') {
+			errMsg = fmt.Sprintf("invalid digit %q in %s literal", l.ch, name)
+		}
+	} else {
+		count := 0
+		if l.ch != '.' {
+			count, errMsg = l.readDigits(isDigit)
+			if errMsg == "" && count > 1 && l.input[position] == '0' && l.ch != '.' && l.ch != 'e' && l.ch != 'E' {
+				errMsg = "invalid leading zero in decimal literal, use the 0o prefix for octal"
+			}
+		}
+		if errMsg == "" && l.ch == '.' {
+			tokType = FLOAT
+			l.readChar()
+			count, errMsg = l.readDigits(isDigit)
+			if errMsg == "" && count == 0 {
+				errMsg = "fractional part has no digits"
+			}
+		}
+		if errMsg == "" && (l.ch == 'e' || l.ch == 'E') {
+			tokType = FLOAT
+			l.readChar()
+			if l.ch == '+' || l.ch == '-' {
+				l.readChar()
+			}
+			count, errMsg = l.readDigits(isDigit)
+			if errMsg == "" && count == 0 {
+				errMsg = "exponent has no digits"
+			}
+		}
+	}
+
+	literal := l.input[position:l.position]
+	if errMsg == "" && l.ch == 'n' {
+		if tokType == FLOAT {
+			errMsg = "a float literal cannot have the bigint suffix"
+		} else {
+			tokType = BIGINT
+			l.readChar()
+		}
+	}
+
+	if errMsg == "" && (isLetter(l.ch) || isDigit(l.ch) || l.ch == '.') {
+		errMsg = fmt.Sprintf("unexpected %q after number", l.ch)
+	}
+
+	if errMsg != "" {
+		// swallow the rest of the literal so the error covers all of it
+		for isLetter(l.ch) || isDigit(l.ch) || l.ch == '.' {
+			l.readChar()
+		}
+		literal = l.input[position:l.position]
+		return ERROR, literal, fmt.Sprintf("Malformed number literal %q: %s", literal, errMsg)
+	}
+
+	return tokType, literal, ""
+}
+
+// readDigits consumes a run of digits accepted by valid, allowing single '_'
+// separators between them, and returns how many digits it read.
+func (l *V1Lexer) readDigits(valid func(rune) bool) (int, string) {
+	count := 0
+	for valid(l.ch) || l.ch == '_' {
+		if l.ch == '_' && !valid(l.peekChar()) {
+			l.readChar()
+			return count, "'_' must separate successive digits"
+		}
+		if l.ch != '_' {
+			count++
+		}
 		l.readChar()
 	}
-	return l.input[position:l.position]
+	return count, ""
 }
 
 func isLetter(ch rune) bool {
-	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || ch == '_'
+	return unicode.IsLetter(ch) || ch == '_'
 }
 
 func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
+
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || ('a' <= ch && ch <= 'f') || ('A' <= ch && ch <= 'F')
+}
+
+func isOctalDigit(ch rune) bool {
+	return '0' <= ch && ch <= '7'
+}
+
+func isBinaryDigit(ch rune) bool {
+	return ch == '0' || ch == '1'
+}
@@ -66,6 +66,81 @@ func TestLexer(t *testing.T) {
 				{Value: "}", Type: RBRACE, Line: 1, Column: 30},
 			},
 		},
+		{
+			name:  "test unicode identifier and string",
+			input: `café = "naïve ☕" + x`,
+			expected: []Token{
+				{Value: "café", Type: IDENT, Line: 1, Column: 1},
+				{Value: "=", Type: ASSIGN, Line: 1, Column: 6},
+				{Value: "naïve ☕", Type: STRING, Line: 1, Column: 8},
+				{Value: "+", Type: ADD, Line: 1, Column: 18},
+				{Value: "x", Type: IDENT, Line: 1, Column: 20},
+			},
+		},
+		{
+			name:  "test identifier with digits",
+			input: "x1 = 変数2",
+			expected: []Token{
+				{Value: "x1", Type: IDENT, Line: 1, Column: 1},
+				{Value: "=", Type: ASSIGN, Line: 1, Column: 4},
+				{Value: "変数2", Type: IDENT, Line: 1, Column: 6},
+			},
+		},
+		{
+			name:  "test columns reset on new line",
+			input: "// ünïcödé comment\nπ = 1",
+			expected: []Token{
+				{Value: "\n", Type: NEWLINE, Line: 1, Column: 19},
+				{Value: "π", Type: IDENT, Line: 2, Column: 1},
+				{Value: "=", Type: ASSIGN, Line: 2, Column: 3},
+				{Value: "1", Type: INT, Line: 2, Column: 5},
+			},
+		},
+		{
+			name:  "test byte order mark is skipped",
+			input: "\uFEFFx",
+			expected: []Token{
+				{Value: "x", Type: IDENT, Line: 1, Column: 1},
+			},
+		},
+		{
+			name:  "test invalid utf-8",
+			input: "x = \xff",
+			expected: []Token{
+				{Value: "x", Type: IDENT, Line: 1, Column: 1},
+				{Value: "=", Type: ASSIGN, Line: 1, Column: 3},
+				{Value: "\uFFFD", Type: ERROR, Line: 1, Column: 5, Error: "Invalid UTF-8 encoding"},
+			},
+		},
+		{
+			name:  "test numeric literals",
+			input: "0xFF 0o755 0b1010 1_000_000 1e9 2.5E-3 .5 -0x10 -1.5",
+			expected: []Token{
+				{Value: "0xFF", Type: INT, Line: 1, Column: 1},
+				{Value: "0o755", Type: INT, Line: 1, Column: 6},
+				{Value: "0b1010", Type: INT, Line: 1, Column: 12},
+				{Value: "1_000_000", Type: INT, Line: 1, Column: 19},
+				{Value: "1e9", Type: FLOAT, Line: 1, Column: 29},
+				{Value: "2.5E-3", Type: FLOAT, Line: 1, Column: 33},
+				{Value: ".5", Type: FLOAT, Line: 1, Column: 40},
+				{Value: "-0x10", Type: INT, Line: 1, Column: 43},
+				{Value: "-1.5", Type: FLOAT, Line: 1, Column: 49},
+			},
+		},
+		{
+			name:  "test malformed numeric literals",
+			input: "1.2.3 0x 1__0 0o78 0755 12ab 1e 3_",
+			expected: []Token{
+				{Value: "1.2.3", Type: ERROR, Line: 1, Column: 1, Error: `Malformed number literal "1.2.3": unexpected '.' after number`},
+				{Value: "0x", Type: ERROR, Line: 1, Column: 7, Error: `Malformed number literal "0x": hexadecimal literal has no digits`},
+				{Value: "1__0", Type: ERROR, Line: 1, Column: 10, Error: `Malformed number literal "1__0": '_' must separate successive digits`},
+				{Value: "0o78", Type: ERROR, Line: 1, Column: 15, Error: `Malformed number literal "0o78": invalid digit '8' in octal literal`},
+				{Value: "0755", Type: ERROR, Line: 1, Column: 20, Error: `Malformed number literal "0755": invalid leading zero in decimal literal, use the 0o prefix for octal`},
+				{Value: "12ab", Type: ERROR, Line: 1, Column: 25, Error: `Malformed number literal "12ab": unexpected 'a' after number`},
+				{Value: "1e", Type: ERROR, Line: 1, Column: 30, Error: `Malformed number literal "1e": exponent has no digits`},
+				{Value: "3_", Type: ERROR, Line: 1, Column: 33, Error: `Malformed number literal "3_": '_' must separate successive digits`},
+			},
+		},
 	}
 
 	for _, test := range cases {
@@ -0,0 +1,169 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// Limits bound what a program may use, for running code that is not
+// trusted. A zero field leaves that resource unlimited. Going over a limit
+// raises an error of a type of its own, which try can catch, but the
+// program stays over the limit so it fails again at the next check.
+type Limits struct {
+	// Steps is the number of nodes the tree walker may evaluate or
+	// instructions the virtual machine may execute
+	Steps int
+	// CallDepth is the number of function calls that may be in progress
+	// at once on a task or goroutine
+	CallDepth int
+	// Time is how long a program may run
+	Time time.Duration
+	// Allocations is the number of objects a program may create, counting
+	// the values of operations, literals, closures and the results of
+	// builtins, an array or map counting one for each element as well
+	Allocations int
+}
+
+// SetLimits sets the limits the programs evaluated from now on are held to.
+func (e *Evaluator) SetLimits(limits Limits) {
+	e.limits = limits
+}
+
+// EvaluateContext evaluates a program like Evaluate, stopping it with a
+// CancelledError once ctx is done. Cancellation and the time limit are
+// checked at every loop iteration and function call and stop builtins that
+// wait, like sleep and channel receives. What the program uses is counted
+// against the limits from the start of each call.
+func (e *Evaluator) EvaluateContext(ctx context.Context, exp Node) (Object, error) {
+	if e.locked {
+		return e.evaluate(exp)
+	}
+	e.acquire()
+	defer e.release()
+	if exit := e.exited(); exit != nil {
+		return &Nil{}, exit
+	}
+	defer e.begin(ctx)()
+	result, err := e.execute(exp)
+	if exit := e.exited(); exit != nil {
+		return &Nil{}, exit
+	}
+	return result, err
+}
+
+// exited takes the exit of a goroutine that ended the program, see
+// exitProgram.
+func (e *Evaluator) exited() *ExitError {
+	exit := e.exit
+	e.exit = nil
+	return exit
+}
+
+// begin starts counting what the program about to run uses and returns the
+// function to call once it is done.
+func (e *Evaluator) begin(ctx context.Context) (end func()) {
+	e.steps, e.allocations = 0, 0
+	// stop is not derived from ctx so that it outlives the run, for the
+	// goroutines still running after it
+	stop, halt := context.WithCancelCause(context.Background())
+	e.stop, e.halt = stop, halt
+	cancelled := func() {
+		halt(newRuntimeError(CANCELLED_ERROR, "evaluation cancelled: %v", context.Cause(ctx)))
+	}
+	if ctx.Err() != nil {
+		cancelled()
+	}
+	detach := context.AfterFunc(ctx, cancelled)
+	var timer *time.Timer
+	if e.limits.Time > 0 {
+		timer = time.AfterFunc(e.limits.Time, func() {
+			halt(newRuntimeError(TIME_LIMIT_ERROR, "time limit of %s exceeded", e.limits.Time))
+		})
+	}
+	e.limited = e.limits != (Limits{}) || ctx.Done() != nil
+	return func() {
+		detach()
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// step counts a node evaluated or an instruction executed.
+func (e *Evaluator) step() error {
+	e.steps++
+	if e.limits.Steps > 0 && e.steps > e.limits.Steps {
+		return newRuntimeError(STEP_LIMIT_ERROR, "step limit of %d exceeded", e.limits.Steps)
+	}
+	return nil
+}
+
+// checkpoint is reached at every loop iteration and function call, it
+// stops a program whose context is done or whose time is up.
+func (e *Evaluator) checkpoint() error {
+	if !e.limited {
+		return nil
+	}
+	select {
+	case <-e.stop.Done():
+		return stopped(e.stop)
+	default:
+		return nil
+	}
+}
+
+// stopped gives the error a program stops with once stop is done, a new
+// one every time as errors collect a traceback on the way up.
+func stopped(stop context.Context) error {
+	cause := context.Cause(stop)
+	if runtimeErr, ok := cause.(*RuntimeError); ok {
+		return newRuntimeError(runtimeErr.ErrorType, "%s", runtimeErr.Message)
+	}
+	return cause
+}
+
+// wait runs fn without the interpreter lock like blocking, for builtins
+// that wait on something that may take any time. fn must return as soon as
+// the channel it is given is closed, which happens when the program has to
+// stop, and wait then gives the error it stops with.
+func (i *interpreter) wait(fn func(stop <-chan struct{})) error {
+	stop := i.stop
+	i.blocking(func() {
+		fn(stop.Done())
+	})
+	if stop.Err() != nil {
+		return stopped(stop)
+	}
+	return nil
+}
+
+// enter is reached at the start of every function call, before the call
+// is counted in the depth.
+func (e *Evaluator) enter() error {
+	if !e.limited {
+		return nil
+	}
+	if e.limits.CallDepth > 0 && e.depth >= e.limits.CallDepth {
+		return newRuntimeError(CALL_DEPTH_LIMIT_ERROR, "call depth limit of %d exceeded", e.limits.CallDepth)
+	}
+	return e.checkpoint()
+}
+
+// allocated counts value, the result of an operation that created it,
+// against the allocation limit and passes the result on.
+func (e *Evaluator) allocated(value Object, err error) (Object, error) {
+	if err != nil || e.limits.Allocations == 0 {
+		return value, err
+	}
+	e.allocations++
+	switch value := value.(type) {
+	case *Array:
+		e.allocations += len(value.Elements)
+	case *Map:
+		e.allocations += len(value.keys)
+	}
+	if e.allocations > e.limits.Allocations {
+		return &Nil{}, newRuntimeError(ALLOCATION_LIMIT_ERROR, "allocation limit of %d objects exceeded", e.limits.Allocations)
+	}
+	return value, nil
+}
@@ -0,0 +1,194 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLimits(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		limits   Limits
+		expected Object
+		err      string
+	}{
+		{
+			name:   "test step limit",
+			input:  "for i := 0; true; i++ {\n}",
+			limits: Limits{Steps: 1000},
+			err:    STEP_LIMIT_ERROR,
+		},
+		{
+			name:   "test call depth limit",
+			input:  "func f(n) {\n return 1 + f(n + 1)\n}\nf(0)",
+			limits: Limits{CallDepth: 50},
+			err:    CALL_DEPTH_LIMIT_ERROR,
+		},
+		{
+			name:   "test time limit",
+			input:  "for i := 0; true; i++ {\n}",
+			limits: Limits{Time: 20 * time.Millisecond},
+			err:    TIME_LIMIT_ERROR,
+		},
+		{
+			name:   "test allocation limit",
+			input:  "xs = []\nfor i := 0; i < 1000; i++ {\n xs = xs + [i]\n}",
+			limits: Limits{Allocations: 500},
+			err:    ALLOCATION_LIMIT_ERROR,
+		},
+		{
+			name:   "test time limit stops sleep",
+			input:  "import \"time\"\ntime.sleep(5)",
+			limits: Limits{Time: 50 * time.Millisecond},
+			err:    TIME_LIMIT_ERROR,
+		},
+		{
+			name:   "test time limit stops channel receive",
+			input:  "make_chan().recv()",
+			limits: Limits{Time: 50 * time.Millisecond},
+			err:    TIME_LIMIT_ERROR,
+		},
+		{
+			name:   "test time limit stops select",
+			input:  "c = make_chan()\nselect {\ncase v := c.recv():\n print(v)\n}",
+			limits: Limits{Time: 50 * time.Millisecond},
+			err:    TIME_LIMIT_ERROR,
+		},
+		{
+			name:   "test time limit stops await",
+			input:  "import \"time\"\nasync func slow() {\n time.sleep(5)\n}\nawait slow()",
+			limits: Limits{Time: 50 * time.Millisecond},
+			err:    TIME_LIMIT_ERROR,
+		},
+		{
+			name:   "test time limit stops wait group",
+			input:  "import \"sync\"\nwg = sync.waitGroup()\nwg.add(1)\nwg.wait()",
+			limits: Limits{Time: 50 * time.Millisecond},
+			err:    TIME_LIMIT_ERROR,
+		},
+		{
+			name:     "test within limits",
+			input:    "func f(n) {\n if n == 0 {\n  return 0\n }\n return 1 + f(n - 1)\n}\nx = [f(20), \"a\" + \"b\"]",
+			limits:   Limits{Steps: 10000, CallDepth: 25, Time: time.Minute, Allocations: 100},
+			expected: &Array{Elements: []Object{&Integer{value: 20}, &String{value: "ab"}}},
+		},
+		{
+			name:     "test call depth limit is catchable",
+			input:    "func f(n) {\n return 1 + f(n + 1)\n}\nx = \"\"\ntry {\n f(0)\n} catch e {\n x = e.type + \": \" + e.message\n}",
+			limits:   Limits{CallDepth: 50},
+			expected: &String{value: "CallDepthLimitError: call depth limit of 50 exceeded"},
+		},
+		{
+			name:   "test catching the step limit does not reset it",
+			input:  "x = 0\ntry {\n for i := 0; true; i++ {\n }\n} catch e {\n x = 1\n}",
+			limits: Limits{Steps: 1000},
+			err:    STEP_LIMIT_ERROR,
+		},
+	}
+
+	for _, backend := range backends {
+		for _, test := range cases {
+			t.Run(backend.name+"/"+test.name, func(t *testing.T) {
+				program, err := NewV1Parser(NewV1Lexer(test.input), false).ParseProgram()
+				if err != nil {
+					t.Fatal(err)
+				}
+				evaluator := NewEvaluator(false)
+				evaluator.SetVM(backend.vm)
+				evaluator.SetLimits(test.limits)
+				start := time.Now()
+				_, err = evaluator.EvaluateContext(context.Background(), program)
+				if test.err != "" {
+					if err == nil || toRuntimeError(err).ErrorType != test.err {
+						t.Fatalf("expected %s, got %v", test.err, err)
+					}
+					if test.limits.Time > 0 && time.Since(start) > time.Second {
+						t.Fatalf("stopped after %s, expected about %s", time.Since(start), test.limits.Time)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				result, err := evaluator.Evaluate(NewIdentifierLiteral("x", 0, 0))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !reflect.DeepEqual(result, test.expected) {
+					t.Fatalf("expected %v, got %v", test.expected, result)
+				}
+			})
+		}
+	}
+}
+
+func TestEvaluateContextCancel(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend.name+"/test cancelled while running", func(t *testing.T) {
+			program, err := NewV1Parser(NewV1Lexer("func spin() {\n for i := 0; true; i++ {\n }\n}\nspin()"), false).ParseProgram()
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithCancelCause(context.Background())
+			time.AfterFunc(20*time.Millisecond, func() {
+				cancel(errors.New("stopped by host"))
+			})
+			evaluator := NewEvaluator(false)
+			evaluator.SetVM(backend.vm)
+			_, err = evaluator.EvaluateContext(ctx, program)
+			if err == nil || toRuntimeError(err).ErrorType != CANCELLED_ERROR {
+				t.Fatalf("expected %s, got %v", CANCELLED_ERROR, err)
+			}
+			if toRuntimeError(err).Message != "evaluation cancelled: stopped by host" {
+				t.Fatalf("unexpected message %q", toRuntimeError(err).Message)
+			}
+		})
+
+		waits := []struct {
+			name  string
+			input string
+		}{
+			{name: "sleep", input: "import \"time\"\ntime.sleep(5)"},
+			{name: "channel receive", input: "make_chan().recv()"},
+		}
+		for _, wait := range waits {
+			t.Run(backend.name+"/test cancelled while waiting on "+wait.name, func(t *testing.T) {
+				program, err := NewV1Parser(NewV1Lexer(wait.input), false).ParseProgram()
+				if err != nil {
+					t.Fatal(err)
+				}
+				ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+				defer cancel()
+				evaluator := NewEvaluator(false)
+				evaluator.SetVM(backend.vm)
+				start := time.Now()
+				_, err = evaluator.EvaluateContext(ctx, program)
+				if err == nil || toRuntimeError(err).ErrorType != CANCELLED_ERROR {
+					t.Fatalf("expected %s, got %v", CANCELLED_ERROR, err)
+				}
+				if time.Since(start) > time.Second {
+					t.Fatalf("stopped after %s, expected about 50ms", time.Since(start))
+				}
+			})
+		}
+
+		t.Run(backend.name+"/test cancelled before a call", func(t *testing.T) {
+			program, err := NewV1Parser(NewV1Lexer("func f() {\n return 1\n}\nf()"), false).ParseProgram()
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			evaluator := NewEvaluator(false)
+			evaluator.SetVM(backend.vm)
+			_, err = evaluator.EvaluateContext(ctx, program)
+			if err == nil || toRuntimeError(err).ErrorType != CANCELLED_ERROR {
+				t.Fatalf("expected %s, got %v", CANCELLED_ERROR, err)
+			}
+		})
+	}
+}
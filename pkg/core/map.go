@@ -0,0 +1,224 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Map is a string keyed dictionary created by a map literal {"a": 1}. It
+// remembers the order keys were first inserted in, which is the order it is
+// ranged over and printed in.
+type Map struct {
+	keys   []string
+	values map[string]Object
+}
+
+func NewMap() *Map {
+	return &Map{values: map[string]Object{}}
+}
+
+// Get returns the value stored under key.
+func (m *Map) Get(key string) (Object, bool) {
+	value, ok := m.values[key]
+	return value, ok
+}
+
+// Set stores value under key, new keys go last.
+func (m *Map) Set(key string, value Object) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// Delete removes key, it reports whether the key was present.
+func (m *Map) Delete(key string) bool {
+	if _, ok := m.values[key]; !ok {
+		return false
+	}
+	delete(m.values, key)
+	for i, k := range m.keys {
+		if k == key {
+			m.keys = append(m.keys[:i], m.keys[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// Keys returns the keys in insertion order.
+func (m *Map) Keys() []string {
+	return append([]string{}, m.keys...)
+}
+
+// mapKey checks an object used as a map key is a string.
+func mapKey(key Object) (string, error) {
+	str, ok := key.(*String)
+	if !ok {
+		return "", newTypeError("map keys must be strings, not %s", key.Type())
+	}
+	return str.value, nil
+}
+
+// mapMethods is the method table of Map.
+func mapMethods() map[string]*GoFunction {
+	return map[string]*GoFunction{
+		"keys":   {Name: "keys", Func: mapKeys},
+		"values": {Name: "values", Func: mapValues},
+		"has":    {Name: "has", Func: mapHas},
+		"delete": {Name: "delete", Func: mapDelete},
+	}
+}
+
+func mapArg(name string, args []Object, count int) (*Map, error) {
+	if err := checkArgs(name, args, count, count); err != nil {
+		return nil, err
+	}
+	m, ok := args[0].(*Map)
+	if !ok {
+		return nil, newTypeError("%s() expects a map, got %s", name, args[0].Type())
+	}
+	return m, nil
+}
+
+func mapKeys(args []Object) (Object, error) {
+	m, err := mapArg("keys", args, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	return newStringArray(m.Keys()), nil
+}
+
+func mapValues(args []Object) (Object, error) {
+	m, err := mapArg("values", args, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	values := make([]Object, len(m.keys))
+	for i, key := range m.keys {
+		values[i] = m.values[key]
+	}
+	return &Array{Elements: values}, nil
+}
+
+func mapHas(args []Object) (Object, error) {
+	m, err := mapArg("has", args, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	key, err := mapKey(args[1])
+	if err != nil {
+		return &Nil{}, err
+	}
+	_, ok := m.Get(key)
+	return &Boolean{value: ok}, nil
+}
+
+func mapDelete(args []Object) (Object, error) {
+	m, err := mapArg("delete", args, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	key, err := mapKey(args[1])
+	if err != nil {
+		return &Nil{}, err
+	}
+	return &Boolean{value: m.Delete(key)}, nil
+}
+
+func (m *Map) Type() string {
+	return "map"
+}
+
+func (m *Map) Value() interface{} {
+	values := make(map[string]interface{}, len(m.values))
+	for key, value := range m.values {
+		values[key] = value.Value()
+	}
+	return values
+}
+
+func (m *Map) String() *String {
+	entries := make([]string, len(m.keys))
+	for i, key := range m.keys {
+		entries[i] = fmt.Sprintf("%s: %s", strconv.Quote(key), m.values[key].String().value)
+	}
+	return &String{value: fmt.Sprintf("{%s}", strings.Join(entries, ", "))}
+}
+
+func (m *Map) Add(other Object) (Object, error) {
+	return nil, newTypeError("Addition operation not supported for map")
+}
+
+func (m *Map) Sub(other Object) (Object, error) {
+	return nil, newTypeError("Subtraction operation not supported for map")
+}
+
+func (m *Map) Multiply(other Object) (Object, error) {
+	return nil, newTypeError("Multiplication operation not supported for map")
+}
+
+func (m *Map) Divide(other Object) (Object, error) {
+	return nil, newTypeError("Division operation not supported for map")
+}
+
+func (m *Map) Modulo(other Object) (Object, error) {
+	return nil, newTypeError("Modulo operation not supported for map")
+}
+
+// Equal compares the entries of two maps, their order does not matter.
+func (m *Map) Equal(other Object) (Object, error) {
+	otherMap, ok := other.(*Map)
+	if !ok {
+		return nil, newTypeError("Invalid type: cannot compare %s with %s", m.Type(), other.Type())
+	}
+	if len(m.values) != len(otherMap.values) {
+		return &Boolean{value: false}, nil
+	}
+	for key, value := range m.values {
+		otherValue, ok := otherMap.values[key]
+		if !ok {
+			return &Boolean{value: false}, nil
+		}
+		equal, err := value.Equal(otherValue)
+		if err != nil {
+			return nil, err
+		}
+		if !isTruthy(equal) {
+			return &Boolean{value: false}, nil
+		}
+	}
+	return &Boolean{value: true}, nil
+}
+
+func (m *Map) NotEqual(other Object) (Object, error) {
+	equal, err := m.Equal(other)
+	if err != nil {
+		return nil, err
+	}
+	return &Boolean{value: !isTruthy(equal)}, nil
+}
+
+func (m *Map) GreaterThan(other Object) (Object, error) {
+	return nil, newTypeError("Comparison operation not supported for map")
+}
+
+func (m *Map) LessThan(other Object) (Object, error) {
+	return nil, newTypeError("Comparison operation not supported for map")
+}
+
+func (m *Map) GreaterThanOrEqual(other Object) (Object, error) {
+	return nil, newTypeError("Comparison operation not supported for map")
+}
+
+func (m *Map) LessThanOrEqual(other Object) (Object, error) {
+	return nil, newTypeError("Comparison operation not supported for map")
+}
+
+func (m *Map) GetColumn() int {
+	return 0
+}
+func (m *Map) GetLine() int {
+	return 0
+}
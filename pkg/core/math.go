@@ -0,0 +1,332 @@
+package core
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// MAX_POW_BITS bounds the size of the exact integer results of pow, larger
+// powers would take the memory and time of the whole process.
+const MAX_POW_BITS = 1 << 20
+
+// mathModule builds the native math module.
+func mathModule(e *Evaluator) map[string]Object {
+	module := map[string]Object{
+		"pi":  &Float{value: math.Pi},
+		"e":   &Float{value: math.E},
+		"inf": &Float{value: math.Inf(1)},
+		"nan": &Float{value: math.NaN()},
+	}
+	functions := map[string]func([]Object) (Object, error){
+		"sqrt":  mathUnary("sqrt", math.Sqrt, func(x float64) bool { return x >= 0 }),
+		"exp":   mathUnary("exp", math.Exp, nil),
+		"log2":  mathUnary("log2", math.Log2, isPositive),
+		"log10": mathUnary("log10", math.Log10, isPositive),
+		"sin":   mathUnary("sin", math.Sin, isFinite),
+		"cos":   mathUnary("cos", math.Cos, isFinite),
+		"tan":   mathUnary("tan", math.Tan, isFinite),
+		"asin":  mathUnary("asin", math.Asin, isUnitRange),
+		"acos":  mathUnary("acos", math.Acos, isUnitRange),
+		"atan":  mathUnary("atan", math.Atan, nil),
+		"sinh":  mathUnary("sinh", math.Sinh, nil),
+		"cosh":  mathUnary("cosh", math.Cosh, nil),
+		"tanh":  mathUnary("tanh", math.Tanh, nil),
+		"floor": mathRounding("floor", math.Floor),
+		"ceil":  mathRounding("ceil", math.Ceil),
+		"round": mathRounding("round", math.Round),
+		"trunc": mathRounding("trunc", math.Trunc),
+		"log":   mathLog,
+		"pow":   mathPow,
+		"atan2": mathAtan2,
+		"hypot": mathHypot,
+		"abs":   mathAbs,
+		"min":   mathExtreme("min", Object.LessThan),
+		"max":   mathExtreme("max", Object.GreaterThan),
+		"gcd":   mathGcd,
+		"lcm":   mathLcm,
+		"isnan": mathIsNaN,
+		"isinf": mathIsInf,
+	}
+	for name, fn := range functions {
+		module[name] = &GoFunction{Name: name, Func: fn}
+	}
+	return module
+}
+
+func isPositive(x float64) bool {
+	return x > 0
+}
+
+func isFinite(x float64) bool {
+	return !math.IsInf(x, 0)
+}
+
+func isUnitRange(x float64) bool {
+	return x >= -1 && x <= 1
+}
+
+// toFloat64 converts any number to a float64, ok is false for other objects.
+func toFloat64(obj Object) (float64, bool) {
+	if _, ok := numericRank(obj); !ok {
+		return 0, false
+	}
+	return promoteNumber(obj, FLOAT_RANK).(*Float).value, true
+}
+
+// floatArgs checks a math function got count numbers and returns them as
+// float64s.
+func floatArgs(name string, args []Object, count int) ([]float64, error) {
+	if len(args) != count {
+		return nil, newTypeError("%s() takes %d arguments (%d given)", name, count, len(args))
+	}
+	values := make([]float64, len(args))
+	for i, arg := range args {
+		value, ok := toFloat64(arg)
+		if !ok {
+			return nil, newTypeError("%s() expects a number, got %s", name, arg.Type())
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func newDomainError(name string, args ...float64) *RuntimeError {
+	formatted := make([]string, len(args))
+	for i, arg := range args {
+		formatted[i] = strconv.FormatFloat(arg, 'g', -1, 64)
+	}
+	return newRuntimeError(VALUE_ERROR, "math domain error in %s(%s)", name, strings.Join(formatted, ", "))
+}
+
+// mathUnary wraps a float64 function, inDomain reports whether an argument is
+// valid. NaN arguments are passed through so NaN propagates as in Go.
+func mathUnary(name string, fn func(float64) float64, inDomain func(float64) bool) func([]Object) (Object, error) {
+	return func(args []Object) (Object, error) {
+		values, err := floatArgs(name, args, 1)
+		if err != nil {
+			return &Nil{}, err
+		}
+		if inDomain != nil && !math.IsNaN(values[0]) && !inDomain(values[0]) {
+			return &Nil{}, newDomainError(name, values[0])
+		}
+		return &Float{value: fn(values[0])}, nil
+	}
+}
+
+// mathRounding wraps floor, ceil, round and trunc. Integers are returned
+// unchanged and rounded floats become integers when they fit.
+func mathRounding(name string, fn func(float64) float64) func([]Object) (Object, error) {
+	return func(args []Object) (Object, error) {
+		if len(args) == 1 {
+			switch args[0].(type) {
+			case *Integer, *BigInt:
+				return args[0], nil
+			}
+		}
+		values, err := floatArgs(name, args, 1)
+		if err != nil {
+			return &Nil{}, err
+		}
+		result := fn(values[0])
+		if math.IsNaN(result) || math.IsInf(result, 0) {
+			return &Float{value: result}, nil
+		}
+		if result >= math.MinInt64 && result < math.MaxInt64 {
+			return &Integer{value: int64(result)}, nil
+		}
+		integer, _ := big.NewFloat(result).Int(nil)
+		return &BigInt{value: integer}, nil
+	}
+}
+
+// mathLog is log(x) for the natural logarithm or log(x, base).
+func mathLog(args []Object) (Object, error) {
+	if len(args) == 2 {
+		values, err := floatArgs("log", args, 2)
+		if err != nil {
+			return &Nil{}, err
+		}
+		if values[0] <= 0 || values[1] <= 0 || values[1] == 1 {
+			return &Nil{}, newDomainError("log", values...)
+		}
+		return &Float{value: math.Log(values[0]) / math.Log(values[1])}, nil
+	}
+	return mathUnary("log", math.Log, isPositive)(args)
+}
+
+// mathPow raises integers to non negative integer powers exactly, everything
+// else is computed with floats.
+func mathPow(args []Object) (Object, error) {
+	values, err := floatArgs("pow", args, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	base, baseIsInt := toBigInt(args[0])
+	exponent, exponentIsInt := toBigInt(args[1])
+	if baseIsInt && exponentIsInt && exponent.Sign() >= 0 {
+		// 0, 1 and -1 stay small whatever the exponent
+		if base.CmpAbs(big.NewInt(1)) > 0 {
+			bits := new(big.Int).Mul(exponent, big.NewInt(int64(base.BitLen()-1)))
+			if bits.Cmp(big.NewInt(MAX_POW_BITS)) > 0 {
+				return &Nil{}, newRuntimeError(VALUE_ERROR, "pow(%s, %s) is too large, results are limited to %d bits", base, exponent, MAX_POW_BITS)
+			}
+		}
+		return normaliseBigInt(new(big.Int).Exp(base, exponent, nil), args...), nil
+	}
+
+	x, y := values[0], values[1]
+	if x == 0 && y < 0 {
+		return &Nil{}, newZeroDivisionError()
+	}
+	if x < 0 && y != math.Trunc(y) {
+		return &Nil{}, newDomainError("pow", x, y)
+	}
+	return &Float{value: math.Pow(x, y)}, nil
+}
+
+func mathAtan2(args []Object) (Object, error) {
+	values, err := floatArgs("atan2", args, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	return &Float{value: math.Atan2(values[0], values[1])}, nil
+}
+
+func mathHypot(args []Object) (Object, error) {
+	values, err := floatArgs("hypot", args, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	return &Float{value: math.Hypot(values[0], values[1])}, nil
+}
+
+// mathAbs keeps the type of its argument.
+func mathAbs(args []Object) (Object, error) {
+	if len(args) != 1 {
+		return &Nil{}, newTypeError("abs() takes 1 arguments (%d given)", len(args))
+	}
+	switch n := args[0].(type) {
+	case *Integer:
+		if n.value < 0 {
+			return (&Integer{}).Sub(n)
+		}
+		return n, nil
+	case *BigInt:
+		return &BigInt{value: new(big.Int).Abs(n.value)}, nil
+	case *Decimal:
+		return &Decimal{coefficient: new(big.Int).Abs(n.coefficient), scale: n.scale}, nil
+	case *Float:
+		return &Float{value: math.Abs(n.value)}, nil
+	}
+	return &Nil{}, newTypeError("abs() expects a number, got %s", args[0].Type())
+}
+
+// mathExtreme builds min and max, which take any number of values that can
+// be compared with each other and return the winning one unchanged.
+func mathExtreme(name string, beats func(Object, Object) (Object, error)) func([]Object) (Object, error) {
+	return func(args []Object) (Object, error) {
+		if len(args) == 0 {
+			return &Nil{}, newTypeError("%s() expects at least 1 argument", name)
+		}
+		result := args[0]
+		for _, arg := range args[1:] {
+			better, err := beats(arg, result)
+			if err != nil {
+				return &Nil{}, err
+			}
+			if isTruthy(better) {
+				result = arg
+			}
+		}
+		return result, nil
+	}
+}
+
+// toBigInt returns the value of an Integer or BigInt, ok is false for any
+// other object.
+func toBigInt(obj Object) (*big.Int, bool) {
+	switch n := obj.(type) {
+	case *Integer:
+		return big.NewInt(n.value), true
+	case *BigInt:
+		return n.value, true
+	}
+	return nil, false
+}
+
+// normaliseBigInt returns result as an Integer unless one of the operands it
+// was computed from was a BigInt or it does not fit.
+func normaliseBigInt(result *big.Int, operands ...Object) Object {
+	for _, operand := range operands {
+		if _, ok := operand.(*BigInt); ok {
+			return &BigInt{value: result}
+		}
+	}
+	if result.IsInt64() {
+		return &Integer{value: result.Int64()}
+	}
+	return &BigInt{value: result}
+}
+
+func integerArgs(name string, args []Object) ([]*big.Int, error) {
+	if len(args) < 2 {
+		return nil, newTypeError("%s() expects at least 2 arguments", name)
+	}
+	values := make([]*big.Int, len(args))
+	for i, arg := range args {
+		value, ok := toBigInt(arg)
+		if !ok {
+			return nil, newTypeError("%s() expects integers, got %s", name, arg.Type())
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func mathGcd(args []Object) (Object, error) {
+	values, err := integerArgs("gcd", args)
+	if err != nil {
+		return &Nil{}, err
+	}
+	result := new(big.Int).Abs(values[0])
+	for _, value := range values[1:] {
+		result.GCD(nil, nil, result, new(big.Int).Abs(value))
+	}
+	return normaliseBigInt(result, args...), nil
+}
+
+func mathLcm(args []Object) (Object, error) {
+	values, err := integerArgs("lcm", args)
+	if err != nil {
+		return &Nil{}, err
+	}
+	result := new(big.Int).Abs(values[0])
+	for _, value := range values[1:] {
+		value = new(big.Int).Abs(value)
+		if result.Sign() == 0 || value.Sign() == 0 {
+			result.SetInt64(0)
+			continue
+		}
+		gcd := new(big.Int).GCD(nil, nil, result, value)
+		result.Mul(result, value).Quo(result, gcd)
+	}
+	return normaliseBigInt(result, args...), nil
+}
+
+func mathIsNaN(args []Object) (Object, error) {
+	values, err := floatArgs("isnan", args, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	return &Boolean{value: math.IsNaN(values[0])}, nil
+}
+
+func mathIsInf(args []Object) (Object, error) {
+	values, err := floatArgs("isinf", args, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	return &Boolean{value: math.IsInf(values[0], 0)}, nil
+}
@@ -0,0 +1,165 @@
+package core
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestMathModule(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected Object
+		err      string
+	}{
+		{
+			name:     "test pi",
+			input:    "math.pi",
+			expected: &Float{value: math.Pi},
+		},
+		{
+			name:     "test sqrt",
+			input:    "math.sqrt(16)",
+			expected: &Float{value: 4},
+		},
+		{
+			name:  "test sqrt of negative number",
+			input: "math.sqrt(-1)",
+			err:   "math domain error in sqrt(-1)",
+		},
+		{
+			name:  "test log of zero",
+			input: "math.log(0)",
+			err:   "math domain error in log(0)",
+		},
+		{
+			name:     "test log with base",
+			input:    "math.log(8, 2)",
+			expected: &Float{value: 3},
+		},
+		{
+			name:  "test asin out of range",
+			input: "math.asin(2)",
+			err:   "math domain error in asin(2)",
+		},
+		{
+			name:     "test integer pow is exact",
+			input:    "math.pow(3, 40)",
+			expected: bigIntFromString("12157665459056928801"),
+		},
+		{
+			name:     "test integer pow at the size limit",
+			input:    "math.pow(2, 1048576) > 0",
+			expected: &Boolean{value: true},
+		},
+		{
+			name:  "test integer pow too large",
+			input: "math.pow(10, 1000000000000)",
+			err:   "pow(10, 1000000000000) is too large, results are limited to 1048576 bits",
+		},
+		{
+			name:     "test pow of one to huge exponent",
+			input:    "[math.pow(1, 1000000000000), math.pow(-1, 1000000000001)]",
+			expected: &Array{Elements: []Object{&Integer{value: 1}, &Integer{value: -1}}},
+		},
+		{
+			name:     "test float pow",
+			input:    "math.pow(4, 0.5)",
+			expected: &Float{value: 2},
+		},
+		{
+			name:  "test pow of negative base to fraction",
+			input: "math.pow(-8, 0.5)",
+			err:   "math domain error in pow(-8, 0.5)",
+		},
+		{
+			name:     "test floor returns integer",
+			input:    "math.floor(-2.5)",
+			expected: &Integer{value: -3},
+		},
+		{
+			name:     "test round",
+			input:    "math.round(2.5)",
+			expected: &Integer{value: 3},
+		},
+		{
+			name:     "test trunc of integer",
+			input:    "math.trunc(7)",
+			expected: &Integer{value: 7},
+		},
+		{
+			name:     "test abs keeps type",
+			input:    "math.abs(decimal(\"-1.50\"))",
+			expected: &Decimal{coefficient: bigIntFromString("150").value, scale: 2},
+		},
+		{
+			name:     "test abs of smallest integer",
+			input:    "math.abs(-9223372036854775808)",
+			expected: bigIntFromString("9223372036854775808"),
+		},
+		{
+			name:     "test min",
+			input:    "math.min(3, 1.5, 2)",
+			expected: &Float{value: 1.5},
+		},
+		{
+			name:     "test max",
+			input:    "math.max(3, 10, 2)",
+			expected: &Integer{value: 10},
+		},
+		{
+			name:  "test max without arguments",
+			input: "math.max()",
+			err:   "max() expects at least 1 argument",
+		},
+		{
+			name:     "test gcd",
+			input:    "math.gcd(12, -18, 30)",
+			expected: &Integer{value: 6},
+		},
+		{
+			name:     "test lcm",
+			input:    "math.lcm(4, 6)",
+			expected: &Integer{value: 12},
+		},
+		{
+			name:  "test gcd of float",
+			input: "math.gcd(4, 2.5)",
+			err:   "gcd() expects integers, got float",
+		},
+		{
+			name:     "test isnan",
+			input:    "math.isnan(math.nan)",
+			expected: &Boolean{value: true},
+		},
+		{
+			name:     "test isinf",
+			input:    "math.isinf(math.inf)",
+			expected: &Boolean{value: true},
+		},
+		{
+			name:  "test wrong argument type",
+			input: "math.sqrt(\"4\")",
+			err:   "sqrt() expects a number, got string",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := evalInput("import \"math\"\n" + test.input)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
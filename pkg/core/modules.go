@@ -0,0 +1,261 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/hyperioxx/goscript/pkg/utils"
+)
+
+const MODULE_EXTENSION = ".gs"
+
+// nativeModules are the standard library modules implemented in Go, keyed by
+// the path they are imported with. They take precedence over .gs files.
+var nativeModules map[string]func(e *Evaluator) map[string]Object
+
+// the table is filled in by init because modules like fs call back into the
+// evaluator, which refers to the table when importing
+func init() {
+	nativeModules = map[string]func(e *Evaluator) map[string]Object{
+		"math":    mathModule,
+		"strings": stringsModule,
+		"fs":      fsModule,
+		"path":    pathModule,
+		"json":    jsonModule,
+		"re":      reModule,
+		"time":    timeModule,
+		"os":      osModule,
+		"http":    httpModule,
+		"sync":    syncModule,
+	}
+}
+
+// Module is the namespace an import binds. Only the exported names of the
+// module, those starting with an upper case letter, can be reached through
+// it. Every name of a native module is exported.
+type Module struct {
+	Name   string
+	Path   string
+	scope  map[string]Object
+	native bool
+}
+
+func isExported(name string) bool {
+	r, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsUpper(r)
+}
+
+// SetSearchPath sets the directories searched for imports that are not
+// relative to the importing file, in order.
+func (e *Evaluator) SetSearchPath(paths []string) {
+	e.searchPath = paths
+}
+
+// SetFilename tells the evaluator which file the program it evaluates was
+// read from, relative imports are resolved from its directory.
+func (e *Evaluator) SetFilename(filename string) error {
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return err
+	}
+	e.moduleDir = filepath.Dir(path)
+	e.scriptDir = e.moduleDir
+	return nil
+}
+
+// importModule returns the module at path, loading and evaluating it the
+// first time it is imported.
+func (e *Evaluator) importModule(path string) (*Module, error) {
+	if load, ok := nativeModules[path]; ok {
+		if module, ok := e.modules[path]; ok {
+			return module, nil
+		}
+		module := &Module{Name: path, Path: path, scope: guardModule(path, load(e)), native: true}
+		e.modules[path] = module
+		return module, nil
+	}
+
+	filename, err := e.resolveModule(path)
+	if err != nil {
+		return nil, err
+	}
+	if module, ok := e.modules[filename]; ok {
+		return module, nil
+	}
+	for i, importing := range e.importing {
+		if importing == filename {
+			cycle := append(append([]string{}, e.importing[i:]...), filename)
+			for j := range cycle {
+				cycle[j] = filepath.Base(cycle[j])
+			}
+			return nil, newRuntimeError(IMPORT_ERROR, "import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	source, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, newRuntimeError(IMPORT_ERROR, "unable to read module %s: %v", path, err)
+	}
+	program, err := NewV1Parser(NewV1Lexer(string(source)), e.debug).ParseProgram()
+	if err != nil {
+		return nil, newRuntimeError(IMPORT_ERROR, "%s: %v", filepath.Base(filename), err)
+	}
+	// a module sees the builtins and the names it binds, not the importer's
+	if err := e.checkDefined(program, bindings(program)); err != nil {
+		return nil, newRuntimeError(IMPORT_ERROR, "%s: %v", filepath.Base(filename), toRuntimeError(err).Message)
+	}
+	program = Optimise(program, e.optimisation)
+
+	e.importing = append(e.importing, filename)
+	moduleDir := e.moduleDir
+	e.moduleDir = filepath.Dir(filename)
+	defer func() {
+		e.importing = e.importing[:len(e.importing)-1]
+		e.moduleDir = moduleDir
+	}()
+
+	scope, err := e.evaluateModule(program)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSuffix(filepath.Base(filename), MODULE_EXTENSION)
+	module := &Module{Name: name, Path: filename, scope: scope}
+	e.modules[filename] = module
+	return module, nil
+}
+
+// evaluateModule runs a module's top level in a frame of its own. The frame
+// is marked as a function call so the module cannot see the importer's
+// variables, functions defined in it close over its scope.
+func (e *Evaluator) evaluateModule(program Node) (map[string]Object, error) {
+	if err := e.pushFrame(); err != nil {
+		return nil, err
+	}
+	defer e.popFrame()
+
+	frame := e.callStack[e.framePointer]
+	frame.function = true
+	if _, err := e.execute(program); err != nil {
+		return nil, err
+	}
+	return frame.scope, nil
+}
+
+// resolveModule finds the file an import path refers to. Paths starting with
+// ./ or ../ are relative to the importing file, other paths are looked up in
+// the importing file's directory and then in the search path.
+func (e *Evaluator) resolveModule(path string) (string, error) {
+	name := path
+	if filepath.Ext(name) != MODULE_EXTENSION {
+		name += MODULE_EXTENSION
+	}
+	if filepath.IsAbs(name) {
+		if err := e.permitModule(name); err != nil {
+			return "", err
+		}
+		if _, found := utils.CheckFileExistsInDir("", name); found {
+			return filepath.Clean(name), nil
+		}
+		return "", newRuntimeError(IMPORT_ERROR, "module %q not found", path)
+	}
+
+	dir := e.moduleDir
+	if dir == "" {
+		wd, err := utils.GetWorkingDirectory()
+		if err != nil {
+			return "", err
+		}
+		dir = wd
+	}
+	dirs := []string{dir}
+	if !strings.HasPrefix(path, "./") && !strings.HasPrefix(path, "../") {
+		dirs = append(dirs, e.searchPath...)
+	}
+
+	for _, dir := range dirs {
+		// the policy is checked before looking, a denied import does not tell
+		// whether the file exists
+		if err := e.permitModule(filepath.Join(dir, name)); err != nil {
+			return "", err
+		}
+		if filename, found := utils.CheckFileExistsInDir(dir, name); found {
+			return filepath.Abs(filename)
+		}
+	}
+	return "", newRuntimeError(IMPORT_ERROR, "module %q not found", path)
+}
+
+func (m *Module) GetAttribute(name string) (Object, bool) {
+	if !m.native && !isExported(name) {
+		return nil, false
+	}
+	value, ok := m.scope[name]
+	return value, ok
+}
+
+func (m *Module) Type() string {
+	return "module"
+}
+
+func (m *Module) Value() interface{} {
+	return m.scope
+}
+
+func (m *Module) String() *String {
+	return &String{value: fmt.Sprintf("<module %s>", m.Name)}
+}
+
+func (m *Module) Add(other Object) (Object, error) {
+	return nil, newTypeError("Addition operation not supported for module")
+}
+
+func (m *Module) Sub(other Object) (Object, error) {
+	return nil, newTypeError("Subtraction operation not supported for module")
+}
+
+func (m *Module) Multiply(other Object) (Object, error) {
+	return nil, newTypeError("Multiplication operation not supported for module")
+}
+
+func (m *Module) Divide(other Object) (Object, error) {
+	return nil, newTypeError("Division operation not supported for module")
+}
+
+func (m *Module) Modulo(other Object) (Object, error) {
+	return nil, newTypeError("Modulo operation not supported for module")
+}
+
+func (m *Module) Equal(other Object) (Object, error) {
+	return &Boolean{value: m == other}, nil
+}
+
+func (m *Module) NotEqual(other Object) (Object, error) {
+	return &Boolean{value: m != other}, nil
+}
+
+func (m *Module) GreaterThan(other Object) (Object, error) {
+	return nil, newTypeError("Comparison operation not supported for module")
+}
+
+func (m *Module) LessThan(other Object) (Object, error) {
+	return nil, newTypeError("Comparison operation not supported for module")
+}
+
+func (m *Module) GreaterThanOrEqual(other Object) (Object, error) {
+	return nil, newTypeError("Comparison operation not supported for module")
+}
+
+func (m *Module) LessThanOrEqual(other Object) (Object, error) {
+	return nil, newTypeError("Comparison operation not supported for module")
+}
+
+func (m *Module) GetColumn() int {
+	return 0
+}
+func (m *Module) GetLine() int {
+	return 0
+}
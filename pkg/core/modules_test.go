@@ -0,0 +1,114 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestImport(t *testing.T) {
+	cases := []struct {
+		name     string
+		files    map[string]string
+		input    string
+		expected Object
+		err      string
+	}{
+		{
+			name:     "test import with name",
+			files:    map[string]string{"util.gs": "func Add(a, b) {\n return a + b\n}"},
+			input:    "import util from \"./util.gs\"\nutil.Add(1, 2)",
+			expected: &Integer{value: 3},
+		},
+		{
+			name:     "test import from search path",
+			files:    map[string]string{"lib/lib/strings_util.gs": "Greeting = \"hello\""},
+			input:    "import \"lib/strings_util\"\nstrings_util.Greeting",
+			expected: &String{value: "hello"},
+		},
+		{
+			name:     "test module functions see module scope",
+			files:    map[string]string{"counter.gs": "count = 0\nfunc Next() {\n count = count + 1\n return count\n}"},
+			input:    "import counter from \"./counter\"\ncounter.Next()\ncounter.Next()",
+			expected: &Integer{value: 2},
+		},
+		{
+			name:     "test module is loaded once",
+			files:    map[string]string{"state.gs": "value = 0\nfunc Set(v) {\n value = v\n}\nfunc Get() {\n return value\n}"},
+			input:    "import a from \"./state.gs\"\nimport b from \"./state.gs\"\na.Set(5)\nb.Get()",
+			expected: &Integer{value: 5},
+		},
+		{
+			name:  "test unexported names are hidden",
+			files: map[string]string{"util.gs": "func helper() {\n return 1\n}"},
+			input: "import util from \"./util.gs\"\nutil.helper()",
+			err:   "module has no attribute 'helper'",
+		},
+		{
+			name:  "test module cannot see importer variables",
+			files: map[string]string{"peek.gs": "func Peek() {\n return secret\n}"},
+			input: "secret = 1\nimport peek from \"./peek.gs\"\npeek.Peek()",
+			err:   "peek.gs: undefined variable 'secret' on line: 2",
+		},
+		{
+			name: "test import cycle",
+			files: map[string]string{
+				"a.gs": "import b from \"./b.gs\"",
+				"b.gs": "import a from \"./a.gs\"",
+			},
+			input: "import \"a\"",
+			err:   "import cycle: a.gs -> b.gs -> a.gs",
+		},
+		{
+			name:  "test module not found",
+			input: "import missing from \"./missing.gs\"",
+			err:   "module \"./missing.gs\" not found",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, source := range test.files {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			program, err := NewV1Parser(NewV1Lexer(test.input), false).ParseProgram()
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			evaluator := NewEvaluator(false)
+			evaluator.SetSearchPath([]string{filepath.Join(dir, "lib")})
+			if err := evaluator.SetFilename(filepath.Join(dir, "main.gs")); err != nil {
+				t.Fatal(err)
+			}
+			var result Object
+			for _, stmt := range program.(*BlockStatement).Statements {
+				result, err = evaluator.Evaluate(stmt)
+				if err != nil {
+					break
+				}
+			}
+
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package core
+
+// nativeObject implements the operators of Object for native types such as
+// file handles that support none of them. Types embedding it provide Type,
+// Value, String, Equal and NotEqual themselves.
+type nativeObject struct {
+	typeName string
+}
+
+func (n nativeObject) Add(other Object) (Object, error) {
+	return nil, newTypeError("Addition operation not supported for %s", n.typeName)
+}
+
+func (n nativeObject) Sub(other Object) (Object, error) {
+	return nil, newTypeError("Subtraction operation not supported for %s", n.typeName)
+}
+
+func (n nativeObject) Multiply(other Object) (Object, error) {
+	return nil, newTypeError("Multiplication operation not supported for %s", n.typeName)
+}
+
+func (n nativeObject) Divide(other Object) (Object, error) {
+	return nil, newTypeError("Division operation not supported for %s", n.typeName)
+}
+
+func (n nativeObject) Modulo(other Object) (Object, error) {
+	return nil, newTypeError("Modulo operation not supported for %s", n.typeName)
+}
+
+func (n nativeObject) GreaterThan(other Object) (Object, error) {
+	return nil, newTypeError("Comparison operation not supported for %s", n.typeName)
+}
+
+func (n nativeObject) LessThan(other Object) (Object, error) {
+	return nil, newTypeError("Comparison operation not supported for %s", n.typeName)
+}
+
+func (n nativeObject) GreaterThanOrEqual(other Object) (Object, error) {
+	return nil, newTypeError("Comparison operation not supported for %s", n.typeName)
+}
+
+func (n nativeObject) LessThanOrEqual(other Object) (Object, error) {
+	return nil, newTypeError("Comparison operation not supported for %s", n.typeName)
+}
+
+func (n nativeObject) GetColumn() int {
+	return 0
+}
+func (n nativeObject) GetLine() int {
+	return 0
+}
@@ -2,6 +2,9 @@ package core
 
 import (
 	"fmt"
+	"math"
+	"math/big"
+	"strings"
 )
 
 type Node interface {
@@ -36,8 +39,22 @@ type Error interface {
 	Error() string
 }
 
+// AttributeGetter is implemented by objects with named attributes that
+// scripts read with the dot operator, e.g. err.message
+type AttributeGetter interface {
+	GetAttribute(name string) (Object, bool)
+}
+
+// Iterator is implemented by objects a range loop walks over lazily, such as
+// file handles. ok is false once there are no values left.
+type Iterator interface {
+	Next() (value Object, ok bool, err error)
+}
+
+// Integer is a 64-bit signed integer. Arithmetic that would overflow an
+// int64 is automatically promoted to a BigInt.
 type Integer struct {
-	value int
+	value int64
 }
 
 func (i *Integer) Type() string {
@@ -54,90 +71,129 @@ func (i *Integer) String() *String {
 
 func (i *Integer) Add(other Object) (Object, error) {
 	if otherInt, ok := other.(*Integer); ok {
-		return &Integer{i.value + otherInt.value}, nil
+		sum := i.value + otherInt.value
+		if (sum > i.value) != (otherInt.value > 0) {
+			return i.toBigInt().Add(otherInt)
+		}
+		return &Integer{sum}, nil
+	} else if left, right, ok := coerceNumeric(i, other); ok {
+		return left.Add(right)
 	} else {
-		return nil, fmt.Errorf("Invalid type: cannot perform addition operation with %s and %s", i.Type(), other.Type())
+		return nil, newTypeError("Invalid type: cannot perform addition operation with %s and %s", i.Type(), other.Type())
 	}
 }
 
 func (i *Integer) Sub(other Object) (Object, error) {
 	if otherInt, ok := other.(*Integer); ok {
-		return &Integer{i.value - otherInt.value}, nil
+		diff := i.value - otherInt.value
+		if (diff < i.value) != (otherInt.value > 0) {
+			return i.toBigInt().Sub(otherInt)
+		}
+		return &Integer{diff}, nil
+	} else if left, right, ok := coerceNumeric(i, other); ok {
+		return left.Sub(right)
 	} else {
-		return nil, fmt.Errorf("Invalid type: cannot perform subtraction operation with %s and %s", i.Type(), other.Type())
+		return nil, newTypeError("Invalid type: cannot perform subtraction operation with %s and %s", i.Type(), other.Type())
 	}
 }
 
 func (i *Integer) Multiply(other Object) (Object, error) {
 	if otherInt, ok := other.(*Integer); ok {
-		return &Integer{i.value * otherInt.value}, nil
+		product := i.value * otherInt.value
+		if i.value != 0 && (product/i.value != otherInt.value || (i.value == -1 && otherInt.value == math.MinInt64)) {
+			return i.toBigInt().Multiply(otherInt)
+		}
+		return &Integer{product}, nil
+	} else if left, right, ok := coerceNumeric(i, other); ok {
+		return left.Multiply(right)
+	} else if duration, ok := other.(*Duration); ok {
+		return duration.Multiply(i)
 	} else {
-		return nil, fmt.Errorf("Invalid type: cannot perform multiplication operation with %s and %s", i.Type(), other.Type())
+		return nil, newTypeError("Invalid type: cannot perform multiplication operation with %s and %s", i.Type(), other.Type())
 	}
 }
 
 func (i *Integer) Divide(other Object) (Object, error) {
 	if otherInt, ok := other.(*Integer); ok {
 		if otherInt.value == 0 {
-			return nil, fmt.Errorf("Division by zero")
+			return nil, newZeroDivisionError()
+		}
+		if i.value == math.MinInt64 && otherInt.value == -1 {
+			return i.toBigInt().Divide(otherInt)
 		}
 		return &Integer{i.value / otherInt.value}, nil
+	} else if left, right, ok := coerceNumeric(i, other); ok {
+		return left.Divide(right)
 	} else {
-		return nil, fmt.Errorf("Invalid type: cannot perform division operation with %s and %s", i.Type(), other.Type())
+		return nil, newTypeError("Invalid type: cannot perform division operation with %s and %s", i.Type(), other.Type())
 	}
 }
 
 func (i *Integer) Modulo(other Object) (Object, error) {
 	if otherInt, ok := other.(*Integer); ok {
 		if otherInt.value == 0 {
-			return nil, fmt.Errorf("Division by zero")
+			return nil, newZeroDivisionError()
 		}
 		return &Integer{i.value % otherInt.value}, nil
+	} else if left, right, ok := coerceNumeric(i, other); ok {
+		return left.Modulo(right)
 	} else {
-		return nil, fmt.Errorf("Invalid type: cannot perform modulo operation with %s and %s", i.Type(), other.Type())
+		return nil, newTypeError("Invalid type: cannot perform modulo operation with %s and %s", i.Type(), other.Type())
 	}
 }
 
 func (i *Integer) Equal(other Object) (Object, error) {
 	if otherInt, ok := other.(*Integer); ok {
 		return &Boolean{value: i.value == otherInt.value}, nil
+	} else if left, right, ok := coerceNumeric(i, other); ok {
+		return left.Equal(right)
 	}
-	return nil, fmt.Errorf("Invalid type: cannot compare %s with %s using equal operator", i.Type(), other.Type())
+	return nil, newTypeError("Invalid type: cannot compare %s with %s using equal operator", i.Type(), other.Type())
 }
 
 func (i *Integer) NotEqual(other Object) (Object, error) {
 	if otherInt, ok := other.(*Integer); ok {
 		return &Boolean{value: i.value != otherInt.value}, nil
+	} else if left, right, ok := coerceNumeric(i, other); ok {
+		return left.NotEqual(right)
 	}
-	return nil, fmt.Errorf("Invalid type: cannot compare %s with %s using not equal operator", i.Type(), other.Type())
+	return nil, newTypeError("Invalid type: cannot compare %s with %s using not equal operator", i.Type(), other.Type())
 }
 
 func (i *Integer) GreaterThan(other Object) (Object, error) {
 	if otherInt, ok := other.(*Integer); ok {
 		return &Boolean{value: i.value > otherInt.value}, nil
+	} else if left, right, ok := coerceNumeric(i, other); ok {
+		return left.GreaterThan(right)
 	}
-	return nil, fmt.Errorf("Invalid type: cannot compare %s with %s using greater than operator", i.Type(), other.Type())
+	return nil, newTypeError("Invalid type: cannot compare %s with %s using greater than operator", i.Type(), other.Type())
 }
 
 func (i *Integer) LessThan(other Object) (Object, error) {
 	if otherInt, ok := other.(*Integer); ok {
 		return &Boolean{value: i.value < otherInt.value}, nil
+	} else if left, right, ok := coerceNumeric(i, other); ok {
+		return left.LessThan(right)
 	}
-	return nil, fmt.Errorf("Invalid type: cannot compare %s with %s using less than operator", i.Type(), other.Type())
+	return nil, newTypeError("Invalid type: cannot compare %s with %s using less than operator", i.Type(), other.Type())
 }
 
 func (i *Integer) GreaterThanOrEqual(other Object) (Object, error) {
 	if otherInt, ok := other.(*Integer); ok {
 		return &Boolean{value: i.value >= otherInt.value}, nil
+	} else if left, right, ok := coerceNumeric(i, other); ok {
+		return left.GreaterThanOrEqual(right)
 	}
-	return nil, fmt.Errorf("Invalid type: cannot compare %s with %s using greater than or equal operator", i.Type(), other.Type())
+	return nil, newTypeError("Invalid type: cannot compare %s with %s using greater than or equal operator", i.Type(), other.Type())
 }
 
 func (i *Integer) LessThanOrEqual(other Object) (Object, error) {
 	if otherInt, ok := other.(*Integer); ok {
 		return &Boolean{value: i.value <= otherInt.value}, nil
+	} else if left, right, ok := coerceNumeric(i, other); ok {
+		return left.LessThanOrEqual(right)
 	}
-	return nil, fmt.Errorf("Invalid type: cannot compare %s with %s using less than or equal operator", i.Type(), other.Type())
+	return nil, newTypeError("Invalid type: cannot compare %s with %s using less than or equal operator", i.Type(), other.Type())
 }
 
 func (i *Integer) GetColumn() int {
@@ -147,6 +203,10 @@ func (i *Integer) GetLine() int {
 	return 0
 }
 
+func (i *Integer) toBigInt() *BigInt {
+	return &BigInt{value: big.NewInt(i.value)}
+}
+
 type Float struct {
 	value float64
 }
@@ -166,83 +226,111 @@ func (f *Float) String() *String {
 func (f *Float) Add(other Object) (Object, error) {
 	if otherFloat, ok := other.(*Float); ok {
 		return &Float{value: f.value + otherFloat.value}, nil
+	} else if left, right, ok := coerceNumeric(f, other); ok {
+		return left.Add(right)
 	}
-	return nil, fmt.Errorf("Invalid type: cannot add %s with %s", f.Type(), other.Type())
+	return nil, newTypeError("Invalid type: cannot add %s with %s", f.Type(), other.Type())
 }
 
 func (f *Float) Sub(other Object) (Object, error) {
 	if otherFloat, ok := other.(*Float); ok {
 		return &Float{value: f.value - otherFloat.value}, nil
+	} else if left, right, ok := coerceNumeric(f, other); ok {
+		return left.Sub(right)
 	}
-	return nil, fmt.Errorf("Invalid type: cannot subtract %s from %s", other.Type(), f.Type())
+	return nil, newTypeError("Invalid type: cannot subtract %s from %s", other.Type(), f.Type())
 }
 
 func (f *Float) Multiply(other Object) (Object, error) {
 	if otherFloat, ok := other.(*Float); ok {
 		return &Float{value: f.value * otherFloat.value}, nil
+	} else if left, right, ok := coerceNumeric(f, other); ok {
+		return left.Multiply(right)
 	}
-	return nil, fmt.Errorf("Invalid type: cannot multiply %s with %s", f.Type(), other.Type())
+	return nil, newTypeError("Invalid type: cannot multiply %s with %s", f.Type(), other.Type())
 }
 
 func (f *Float) Divide(other Object) (Object, error) {
 	if otherFloat, ok := other.(*Float); ok {
 		if otherFloat.value == 0 {
-			return nil, fmt.Errorf("Division by zero")
+			return nil, newZeroDivisionError()
 		}
 		return &Float{value: f.value / otherFloat.value}, nil
+	} else if left, right, ok := coerceNumeric(f, other); ok {
+		return left.Divide(right)
 	}
-	return nil, fmt.Errorf("Invalid type: cannot divide %s by %s", f.Type(), other.Type())
+	return nil, newTypeError("Invalid type: cannot divide %s by %s", f.Type(), other.Type())
 }
 
 func (f *Float) Modulo(other Object) (Object, error) {
-	return nil, fmt.Errorf("Modulo operation not supported for %s", f.Type())
+	if otherFloat, ok := other.(*Float); ok {
+		if otherFloat.value == 0 {
+			return nil, newZeroDivisionError()
+		}
+		return &Float{value: math.Mod(f.value, otherFloat.value)}, nil
+	} else if left, right, ok := coerceNumeric(f, other); ok {
+		return left.Modulo(right)
+	}
+	return nil, newTypeError("Invalid type: cannot perform modulo operation with %s and %s", f.Type(), other.Type())
 }
 
 func (f *Float) Equal(other Object) (Object, error) {
 	if otherFloat, ok := other.(*Float); ok {
 		return &Boolean{value: f.value == otherFloat.value}, nil
+	} else if left, right, ok := coerceNumeric(f, other); ok {
+		return left.Equal(right)
 	} else {
-		return nil, fmt.Errorf("Invalid type: cannot compare %s with %s", f.Type(), other.Type())
+		return nil, newTypeError("Invalid type: cannot compare %s with %s", f.Type(), other.Type())
 	}
 }
 
 func (f *Float) NotEqual(other Object) (Object, error) {
 	if otherFloat, ok := other.(*Float); ok {
 		return &Boolean{value: f.value != otherFloat.value}, nil
+	} else if left, right, ok := coerceNumeric(f, other); ok {
+		return left.NotEqual(right)
 	} else {
-		return nil, fmt.Errorf("Invalid type: cannot compare %s with %s", f.Type(), other.Type())
+		return nil, newTypeError("Invalid type: cannot compare %s with %s", f.Type(), other.Type())
 	}
 }
 
 func (f *Float) GreaterThan(other Object) (Object, error) {
 	if otherFloat, ok := other.(*Float); ok {
 		return &Boolean{value: f.value > otherFloat.value}, nil
+	} else if left, right, ok := coerceNumeric(f, other); ok {
+		return left.GreaterThan(right)
 	} else {
-		return nil, fmt.Errorf("Invalid type: cannot compare %s with %s", f.Type(), other.Type())
+		return nil, newTypeError("Invalid type: cannot compare %s with %s", f.Type(), other.Type())
 	}
 }
 
 func (f *Float) LessThan(other Object) (Object, error) {
 	if otherFloat, ok := other.(*Float); ok {
 		return &Boolean{value: f.value < otherFloat.value}, nil
+	} else if left, right, ok := coerceNumeric(f, other); ok {
+		return left.LessThan(right)
 	} else {
-		return nil, fmt.Errorf("Invalid type: cannot compare %s with %s", f.Type(), other.Type())
+		return nil, newTypeError("Invalid type: cannot compare %s with %s", f.Type(), other.Type())
 	}
 }
 
 func (f *Float) GreaterThanOrEqual(other Object) (Object, error) {
 	if otherFloat, ok := other.(*Float); ok {
 		return &Boolean{value: f.value >= otherFloat.value}, nil
+	} else if left, right, ok := coerceNumeric(f, other); ok {
+		return left.GreaterThanOrEqual(right)
 	} else {
-		return nil, fmt.Errorf("Invalid type: cannot compare %s with %s", f.Type(), other.Type())
+		return nil, newTypeError("Invalid type: cannot compare %s with %s", f.Type(), other.Type())
 	}
 }
 
 func (f *Float) LessThanOrEqual(other Object) (Object, error) {
 	if otherFloat, ok := other.(*Float); ok {
 		return &Boolean{value: f.value <= otherFloat.value}, nil
+	} else if left, right, ok := coerceNumeric(f, other); ok {
+		return left.LessThanOrEqual(right)
 	} else {
-		return nil, fmt.Errorf("Invalid type: cannot compare %s with %s", f.Type(), other.Type())
+		return nil, newTypeError("Invalid type: cannot compare %s with %s", f.Type(), other.Type())
 	}
 }
 
@@ -270,30 +358,30 @@ func (b *Boolean) String() *String {
 }
 
 func (b *Boolean) Add(other Object) (Object, error) {
-	return nil, fmt.Errorf("Addition operation not supported for boolean")
+	return nil, newTypeError("Addition operation not supported for boolean")
 }
 
 func (b *Boolean) Sub(other Object) (Object, error) {
-	return nil, fmt.Errorf("Subtraction operation not supported for boolean")
+	return nil, newTypeError("Subtraction operation not supported for boolean")
 }
 
 func (b *Boolean) Multiply(other Object) (Object, error) {
-	return nil, fmt.Errorf("Multiplication operation not supported for boolean")
+	return nil, newTypeError("Multiplication operation not supported for boolean")
 }
 
 func (b *Boolean) Divide(other Object) (Object, error) {
-	return nil, fmt.Errorf("Division operation not supported for boolean")
+	return nil, newTypeError("Division operation not supported for boolean")
 }
 
 func (b *Boolean) Modulo(other Object) (Object, error) {
-	return nil, fmt.Errorf("Modulo operation not supported for boolean")
+	return nil, newTypeError("Modulo operation not supported for boolean")
 }
 
 func (b *Boolean) Equal(other Object) (Object, error) {
 	if otherBool, ok := other.(*Boolean); ok {
 		return &Boolean{value: b.value == otherBool.value}, nil
 	} else {
-		return nil, fmt.Errorf("Invalid type: cannot compare %s with %s", b.Type(), other.Type())
+		return nil, newTypeError("Invalid type: cannot compare %s with %s", b.Type(), other.Type())
 	}
 }
 
@@ -301,24 +389,24 @@ func (b *Boolean) NotEqual(other Object) (Object, error) {
 	if otherBool, ok := other.(*Boolean); ok {
 		return &Boolean{value: b.value != otherBool.value}, nil
 	} else {
-		return nil, fmt.Errorf("Invalid type: cannot compare %s with %s", b.Type(), other.Type())
+		return nil, newTypeError("Invalid type: cannot compare %s with %s", b.Type(), other.Type())
 	}
 }
 
 func (b *Boolean) GreaterThan(other Object) (Object, error) {
-	return nil, fmt.Errorf("Comparison operation not supported for boolean")
+	return nil, newTypeError("Comparison operation not supported for boolean")
 }
 
 func (b *Boolean) LessThan(other Object) (Object, error) {
-	return nil, fmt.Errorf("Comparison operation not supported for boolean")
+	return nil, newTypeError("Comparison operation not supported for boolean")
 }
 
 func (b *Boolean) GreaterThanOrEqual(other Object) (Object, error) {
-	return nil, fmt.Errorf("Comparison operation not supported for boolean")
+	return nil, newTypeError("Comparison operation not supported for boolean")
 }
 
 func (b *Boolean) LessThanOrEqual(other Object) (Object, error) {
-	return nil, fmt.Errorf("Comparison operation not supported for boolean")
+	return nil, newTypeError("Comparison operation not supported for boolean")
 }
 
 func (b *Boolean) GetColumn() int {
@@ -348,31 +436,31 @@ func (s *String) Add(other Object) (Object, error) {
 	if otherString, ok := other.(*String); ok {
 		return &String{value: s.value + otherString.value}, nil
 	} else {
-		return nil, fmt.Errorf("Invalid type: cannot concatenate %s with %s", s.Type(), other.Type())
+		return nil, newTypeError("Invalid type: cannot concatenate %s with %s", s.Type(), other.Type())
 	}
 }
 
 func (s *String) Sub(other Object) (Object, error) {
-	return nil, fmt.Errorf("Subtraction operation not supported for string")
+	return nil, newTypeError("Subtraction operation not supported for string")
 }
 
 func (s *String) Multiply(other Object) (Object, error) {
-	return nil, fmt.Errorf("Multiplication operation not supported for string")
+	return nil, newTypeError("Multiplication operation not supported for string")
 }
 
 func (s *String) Divide(other Object) (Object, error) {
-	return nil, fmt.Errorf("Division operation not supported for string")
+	return nil, newTypeError("Division operation not supported for string")
 }
 
 func (s *String) Modulo(other Object) (Object, error) {
-	return nil, fmt.Errorf("Modulo operation not supported for string")
+	return nil, newTypeError("Modulo operation not supported for string")
 }
 
 func (s *String) Equal(other Object) (Object, error) {
 	if otherString, ok := other.(*String); ok {
 		return &Boolean{value: s.value == otherString.value}, nil
 	} else {
-		return nil, fmt.Errorf("Invalid type: cannot compare %s with %s", s.Type(), other.Type())
+		return nil, newTypeError("Invalid type: cannot compare %s with %s", s.Type(), other.Type())
 	}
 }
 
@@ -380,24 +468,24 @@ func (s *String) NotEqual(other Object) (Object, error) {
 	if otherString, ok := other.(*String); ok {
 		return &Boolean{value: s.value != otherString.value}, nil
 	} else {
-		return nil, fmt.Errorf("Invalid type: cannot compare %s with %s", s.Type(), other.Type())
+		return nil, newTypeError("Invalid type: cannot compare %s with %s", s.Type(), other.Type())
 	}
 }
 
 func (s *String) GreaterThan(other Object) (Object, error) {
-	return nil, fmt.Errorf("Comparison operation not supported for string")
+	return nil, newTypeError("Comparison operation not supported for string")
 }
 
 func (s *String) LessThan(other Object) (Object, error) {
-	return nil, fmt.Errorf("Comparison operation not supported for string")
+	return nil, newTypeError("Comparison operation not supported for string")
 }
 
 func (s *String) GreaterThanOrEqual(other Object) (Object, error) {
-	return nil, fmt.Errorf("Comparison operation not supported for string")
+	return nil, newTypeError("Comparison operation not supported for string")
 }
 
 func (s *String) LessThanOrEqual(other Object) (Object, error) {
-	return nil, fmt.Errorf("Comparison operation not supported for string")
+	return nil, newTypeError("Comparison operation not supported for string")
 }
 
 func (s *String) GetColumn() int {
@@ -407,119 +495,121 @@ func (s *String) GetLine() int {
 	return 0
 }
 
-// type Array struct {
-// 	Elements []Node
-// }
-
-// func (a *Array) Type() string {
-// 	return "array"
-// }
-
-// func (a *Array) Value() interface{} {
-// 	values := make([]interface{}, len(a.Elements))
-// 	for i, element := range a.Elements {
-// 		values[i] = element.String().value
-// 	}
-// 	return values
-// }
-
-// func (a *Array) String() *String {
-// 	strValues := make([]string, len(a.Elements))
-// 	for i, element := range a.Elements {
-// 		x := element.String()
-
-// 		strValues[i] = x.value
-
-// 	}
-// 	return &String{value: fmt.Sprintf("[%s]", strings.Join(strValues, ", "))}
-// }
-
-// func (a *Array) Add(other Object) (Object, error) {
-// 	if otherArray, ok := other.(*Array); ok {
-// 		newElements := make([]Node, len(a.Elements)+len(otherArray.Elements))
-// 		copy(newElements, a.Elements)
-// 		copy(newElements[len(a.Elements):], otherArray.Elements)
-// 		return &Array{Elements: newElements}, nil
-// 	} else {
-// 		return nil, fmt.Errorf("Invalid type: cannot concatenate %s with %s", a.Type(), other.Type())
-// 	}
-// }
-
-// func (a *Array) Sub(other Object) (Object, error) {
-// 	return nil, fmt.Errorf("Subtraction operation not supported for array")
-// }
-
-// func (a *Array) Multiply(other Object) (Object, error) {
-// 	return nil, fmt.Errorf("Multiplication operation not supported for array")
-// }
-
-// func (a *Array) Divide(other Object) (Object, error) {
-// 	return nil, fmt.Errorf("Division operation not supported for array")
-// }
-
-// func (a *Array) Modulo(other Object) (Object, error) {
-// 	return nil, fmt.Errorf("Modulo operation not supported for array")
-// }
-
-// func (a *Array) Equal(other Object) (Object, error) {
-// 	if otherArray, ok := other.(*Array); ok {
-// 		if len(a.Elements) != len(otherArray.Elements) {
-// 			return &Boolean{value: false}, nil
-// 		}
-// 		for i := range a.Elements {
-// 			equal, err := a.Elements[i].(Object).Equal(otherArray.Elements[i])
-// 			if err != nil {
-// 				return nil, err
-// 			}
-// 			if boolean, ok := equal.(*Boolean); ok && !boolean.value {
-// 				return &Boolean{value: false}, nil
-// 			}
-// 		}
-// 		return &Boolean{value: true}, nil
-// 	} else {
-// 		return nil, fmt.Errorf("Invalid type: cannot compare %s with %s", a.Type(), other.Type())
-// 	}
-// }
-
-// func (a *Array) NotEqual(other Object) (Object, error) {
-// 	equal, err := a.Equal(other)
-// 	if err != nil {
-// 		return nil, err
-// 	}
-// 	if boolean, ok := equal.(*Boolean); ok {
-// 		return &Boolean{value: !boolean.value}, nil
-// 	} else {
-// 		return nil, fmt.Errorf("Invalid type: cannot compare %s with %s", a.Type(), other.Type())
-// 	}
-// }
-
-// func (a *Array) GreaterThan(other Object) (Object, error) {
-// 	return nil, fmt.Errorf("Comparison operation not supported for array")
-// }
-
-// func (a *Array) LessThan(other Object) (Object, error) {
-// 	return nil, fmt.Errorf("Comparison operation not supported for array")
-// }
-
-// func (a *Array) GreaterThanOrEqual(other Object) (Object, error) {
-// 	return nil, fmt.Errorf("Comparison operation not supported for array")
-// }
-
-// func (a *Array) LessThanOrEqual(other Object) (Object, error) {
-// 	return nil, fmt.Errorf("Comparison operation not supported for array")
-// }
-
-// func (a *Array) GetColumn() int {
-// 	return 0
-// }
-// func (a *Array) GetLine() int {
-// 	return 0
-// }
+// Array is an ordered list of values, created by an array literal [a, b].
+type Array struct {
+	Elements []Object
+}
+
+func (a *Array) Type() string {
+	return "array"
+}
+
+func (a *Array) Value() interface{} {
+	values := make([]interface{}, len(a.Elements))
+	for i, element := range a.Elements {
+		values[i] = element.Value()
+	}
+	return values
+}
+
+func (a *Array) String() *String {
+	strValues := make([]string, len(a.Elements))
+	for i, element := range a.Elements {
+		strValues[i] = element.String().value
+	}
+	return &String{value: fmt.Sprintf("[%s]", strings.Join(strValues, ", "))}
+}
+
+func (a *Array) Add(other Object) (Object, error) {
+	if otherArray, ok := other.(*Array); ok {
+		newElements := make([]Object, len(a.Elements)+len(otherArray.Elements))
+		copy(newElements, a.Elements)
+		copy(newElements[len(a.Elements):], otherArray.Elements)
+		return &Array{Elements: newElements}, nil
+	} else {
+		return nil, newTypeError("Invalid type: cannot concatenate %s with %s", a.Type(), other.Type())
+	}
+}
+
+func (a *Array) Sub(other Object) (Object, error) {
+	return nil, newTypeError("Subtraction operation not supported for array")
+}
+
+func (a *Array) Multiply(other Object) (Object, error) {
+	return nil, newTypeError("Multiplication operation not supported for array")
+}
+
+func (a *Array) Divide(other Object) (Object, error) {
+	return nil, newTypeError("Division operation not supported for array")
+}
+
+func (a *Array) Modulo(other Object) (Object, error) {
+	return nil, newTypeError("Modulo operation not supported for array")
+}
+
+func (a *Array) Equal(other Object) (Object, error) {
+	if otherArray, ok := other.(*Array); ok {
+		if len(a.Elements) != len(otherArray.Elements) {
+			return &Boolean{value: false}, nil
+		}
+		for i := range a.Elements {
+			equal, err := a.Elements[i].Equal(otherArray.Elements[i])
+			if err != nil {
+				return nil, err
+			}
+			if !isTruthy(equal) {
+				return &Boolean{value: false}, nil
+			}
+		}
+		return &Boolean{value: true}, nil
+	} else {
+		return nil, newTypeError("Invalid type: cannot compare %s with %s", a.Type(), other.Type())
+	}
+}
+
+func (a *Array) NotEqual(other Object) (Object, error) {
+	equal, err := a.Equal(other)
+	if err != nil {
+		return nil, err
+	}
+	return &Boolean{value: !isTruthy(equal)}, nil
+}
+
+func (a *Array) GreaterThan(other Object) (Object, error) {
+	return nil, newTypeError("Comparison operation not supported for array")
+}
+
+func (a *Array) LessThan(other Object) (Object, error) {
+	return nil, newTypeError("Comparison operation not supported for array")
+}
+
+func (a *Array) GreaterThanOrEqual(other Object) (Object, error) {
+	return nil, newTypeError("Comparison operation not supported for array")
+}
+
+func (a *Array) LessThanOrEqual(other Object) (Object, error) {
+	return nil, newTypeError("Comparison operation not supported for array")
+}
+
+func (a *Array) GetColumn() int {
+	return 0
+}
+func (a *Array) GetLine() int {
+	return 0
+}
 
 type Function struct {
 	Name      string
 	Arguments []*IdentifierLiteral
 	Body      *BlockStatement
+	// Async functions return a Task when called, see async.go
+	Async bool
+	// Generator functions, those that yield, return a Generator when
+	// called, see generator.go
+	Generator bool
+	// Env holds the local scopes the function closed over when it was
+	// defined, innermost first
+	Env []map[string]Object
 }
 
 func (f *Function) Type() string {
@@ -539,47 +629,47 @@ func (f *Function) String() *String {
 }
 
 func (f *Function) Add(other Object) (Object, error) {
-	return nil, fmt.Errorf("Addition operation not supported for function")
+	return nil, newTypeError("Addition operation not supported for function")
 }
 
 func (f *Function) Sub(other Object) (Object, error) {
-	return nil, fmt.Errorf("Subtraction operation not supported for function")
+	return nil, newTypeError("Subtraction operation not supported for function")
 }
 
 func (f *Function) Multiply(other Object) (Object, error) {
-	return nil, fmt.Errorf("Multiplication operation not supported for function")
+	return nil, newTypeError("Multiplication operation not supported for function")
 }
 
 func (f *Function) Divide(other Object) (Object, error) {
-	return nil, fmt.Errorf("Division operation not supported for function")
+	return nil, newTypeError("Division operation not supported for function")
 }
 
 func (f *Function) Modulo(other Object) (Object, error) {
-	return nil, fmt.Errorf("Modulo operation not supported for function")
+	return nil, newTypeError("Modulo operation not supported for function")
 }
 
 func (f *Function) Equal(other Object) (Object, error) {
-	return nil, fmt.Errorf("Comparison operation not supported for function")
+	return nil, newTypeError("Comparison operation not supported for function")
 }
 
 func (f *Function) NotEqual(other Object) (Object, error) {
-	return nil, fmt.Errorf("Comparison operation not supported for function")
+	return nil, newTypeError("Comparison operation not supported for function")
 }
 
 func (f *Function) GreaterThan(other Object) (Object, error) {
-	return nil, fmt.Errorf("Comparison operation not supported for function")
+	return nil, newTypeError("Comparison operation not supported for function")
 }
 
 func (f *Function) LessThan(other Object) (Object, error) {
-	return nil, fmt.Errorf("Comparison operation not supported for function")
+	return nil, newTypeError("Comparison operation not supported for function")
 }
 
 func (f *Function) GreaterThanOrEqual(other Object) (Object, error) {
-	return nil, fmt.Errorf("Comparison operation not supported for function")
+	return nil, newTypeError("Comparison operation not supported for function")
 }
 
 func (f *Function) LessThanOrEqual(other Object) (Object, error) {
-	return nil, fmt.Errorf("Comparison operation not supported for function")
+	return nil, newTypeError("Comparison operation not supported for function")
 }
 
 func (f *Function) Call(args []Object) (Object, error) {
@@ -615,23 +705,23 @@ func (n *Nil) String() *String {
 }
 
 func (n *Nil) Add(other Object) (Object, error) {
-	return nil, fmt.Errorf("Addition operation not supported for nil")
+	return nil, newTypeError("Addition operation not supported for nil")
 }
 
 func (n *Nil) Sub(other Object) (Object, error) {
-	return nil, fmt.Errorf("Subtraction operation not supported for nil")
+	return nil, newTypeError("Subtraction operation not supported for nil")
 }
 
 func (n *Nil) Multiply(other Object) (Object, error) {
-	return nil, fmt.Errorf("Multiplication operation not supported for nil")
+	return nil, newTypeError("Multiplication operation not supported for nil")
 }
 
 func (n *Nil) Divide(other Object) (Object, error) {
-	return nil, fmt.Errorf("Division operation not supported for nil")
+	return nil, newTypeError("Division operation not supported for nil")
 }
 
 func (n *Nil) Modulo(other Object) (Object, error) {
-	return nil, fmt.Errorf("Modulo operation not supported for nil")
+	return nil, newTypeError("Modulo operation not supported for nil")
 }
 
 func (n *Nil) Equal(other Object) (Object, error) {
@@ -643,19 +733,19 @@ func (n *Nil) NotEqual(other Object) (Object, error) {
 }
 
 func (n *Nil) GreaterThan(other Object) (Object, error) {
-	return nil, fmt.Errorf("Comparison operation not supported for nil")
+	return nil, newTypeError("Comparison operation not supported for nil")
 }
 
 func (n *Nil) LessThan(other Object) (Object, error) {
-	return nil, fmt.Errorf("Comparison operation not supported for nil")
+	return nil, newTypeError("Comparison operation not supported for nil")
 }
 
 func (n *Nil) GreaterThanOrEqual(other Object) (Object, error) {
-	return nil, fmt.Errorf("Comparison operation not supported for nil")
+	return nil, newTypeError("Comparison operation not supported for nil")
 }
 
 func (n *Nil) LessThanOrEqual(other Object) (Object, error) {
-	return nil, fmt.Errorf("Comparison operation not supported for nil")
+	return nil, newTypeError("Comparison operation not supported for nil")
 }
 
 func (n *Nil) GetColumn() int {
@@ -668,6 +758,10 @@ func (n *Nil) GetLine() int {
 type GoFunction struct {
 	Name string
 	Func func([]Object) (Object, error) // The actual Go function
+	// EvalFunc is called instead of Func by builtins that need the
+	// evaluator making the call, to call back into GoScript on its stack
+	// or to release the interpreter lock while they block
+	EvalFunc func(e *Evaluator, args []Object) (Object, error)
 }
 
 func (f *GoFunction) Type() string {
@@ -687,47 +781,47 @@ func (f *GoFunction) String() *String {
 }
 
 func (f *GoFunction) Add(other Object) (Object, error) {
-	return nil, fmt.Errorf("Addition operation not supported for function")
+	return nil, newTypeError("Addition operation not supported for function")
 }
 
 func (f *GoFunction) Sub(other Object) (Object, error) {
-	return nil, fmt.Errorf("Subtraction operation not supported for function")
+	return nil, newTypeError("Subtraction operation not supported for function")
 }
 
 func (f *GoFunction) Multiply(other Object) (Object, error) {
-	return nil, fmt.Errorf("Multiplication operation not supported for function")
+	return nil, newTypeError("Multiplication operation not supported for function")
 }
 
 func (f *GoFunction) Divide(other Object) (Object, error) {
-	return nil, fmt.Errorf("Division operation not supported for function")
+	return nil, newTypeError("Division operation not supported for function")
 }
 
 func (f *GoFunction) Modulo(other Object) (Object, error) {
-	return nil, fmt.Errorf("Modulo operation not supported for function")
+	return nil, newTypeError("Modulo operation not supported for function")
 }
 
 func (f *GoFunction) Equal(other Object) (Object, error) {
-	return nil, fmt.Errorf("Comparison operation not supported for function")
+	return nil, newTypeError("Comparison operation not supported for function")
 }
 
 func (f *GoFunction) NotEqual(other Object) (Object, error) {
-	return nil, fmt.Errorf("Comparison operation not supported for function")
+	return nil, newTypeError("Comparison operation not supported for function")
 }
 
 func (f *GoFunction) GreaterThan(other Object) (Object, error) {
-	return nil, fmt.Errorf("Comparison operation not supported for function")
+	return nil, newTypeError("Comparison operation not supported for function")
 }
 
 func (f *GoFunction) LessThan(other Object) (Object, error) {
-	return nil, fmt.Errorf("Comparison operation not supported for function")
+	return nil, newTypeError("Comparison operation not supported for function")
 }
 
 func (f *GoFunction) GreaterThanOrEqual(other Object) (Object, error) {
-	return nil, fmt.Errorf("Comparison operation not supported for function")
+	return nil, newTypeError("Comparison operation not supported for function")
 }
 
 func (f *GoFunction) LessThanOrEqual(other Object) (Object, error) {
-	return nil, fmt.Errorf("Comparison operation not supported for function")
+	return nil, newTypeError("Comparison operation not supported for function")
 }
 
 func (f *GoFunction) Call(args []Object) (Object, error) {
@@ -749,13 +843,20 @@ type Expression interface {
 	GetColumn() int
 }
 
+// ReturnStatement returns from a function. Tail is set by the parser when
+// its value is a call made in tail position, which the evaluator makes in
+// place of the function returning, see markTailCalls.
 type ReturnStatement struct {
 	ReturnValue Node
+	Tail        bool
 	Line        int
 	Column      int
 }
 
 func (rs *ReturnStatement) String() *String {
+	if rs.ReturnValue == nil {
+		return &String{"return"}
+	}
 	return &String{fmt.Sprintf("return %s", rs.ReturnValue.String())}
 }
 
@@ -771,6 +872,349 @@ func (rs *ReturnStatement) GetColumn() int {
 	return rs.Column
 }
 
+type ThrowStatement struct {
+	Thrown Node
+	Line   int
+	Column int
+}
+
+func (ts *ThrowStatement) String() *String {
+	return &String{fmt.Sprintf("throw %s", ts.Thrown.String())}
+}
+
+func (ts *ThrowStatement) Value() interface{} {
+	return ts
+}
+
+func (ts *ThrowStatement) GetLine() int {
+	return ts.Line
+}
+
+func (ts *ThrowStatement) GetColumn() int {
+	return ts.Column
+}
+
+// DeferStatement schedules Call to run when the surrounding function returns.
+type DeferStatement struct {
+	Call   *FunctionCall
+	Line   int
+	Column int
+}
+
+func (ds *DeferStatement) String() *String {
+	return &String{fmt.Sprintf("defer %s", ds.Call.String())}
+}
+
+func (ds *DeferStatement) Value() interface{} {
+	return ds
+}
+
+func (ds *DeferStatement) GetLine() int {
+	return ds.Line
+}
+
+func (ds *DeferStatement) GetColumn() int {
+	return ds.Column
+}
+
+// AwaitExpression waits for the task Task evaluates to and gives its
+// result.
+type AwaitExpression struct {
+	Task   Node
+	Line   int
+	Column int
+}
+
+func (ae *AwaitExpression) String() *String {
+	return &String{fmt.Sprintf("await %s", ae.Task.String())}
+}
+
+func (ae *AwaitExpression) Value() interface{} {
+	return ae
+}
+
+func (ae *AwaitExpression) GetLine() int {
+	return ae.Line
+}
+
+func (ae *AwaitExpression) GetColumn() int {
+	return ae.Column
+}
+
+// YieldExpression hands YieldValue to whoever is iterating over the
+// generator it runs in, and gives the value sent back, nil unless the
+// generator is resumed by send.
+type YieldExpression struct {
+	YieldValue Node
+	Line       int
+	Column     int
+}
+
+func (ye *YieldExpression) String() *String {
+	return &String{"yield"}
+}
+
+func (ye *YieldExpression) Value() interface{} {
+	return ye
+}
+
+func (ye *YieldExpression) GetLine() int {
+	return ye.Line
+}
+
+func (ye *YieldExpression) GetColumn() int {
+	return ye.Column
+}
+
+// GoStatement is go f(x), running the call on a goroutine of its own.
+type GoStatement struct {
+	Call   *FunctionCall
+	Line   int
+	Column int
+}
+
+func (gs *GoStatement) String() *String {
+	return &String{fmt.Sprintf("go %s", gs.Call.String())}
+}
+
+func (gs *GoStatement) Value() interface{} {
+	return gs
+}
+
+func (gs *GoStatement) GetLine() int {
+	return gs.Line
+}
+
+func (gs *GoStatement) GetColumn() int {
+	return gs.Column
+}
+
+// SelectStatement waits until one of its cases can go ahead and runs it, or
+// runs Default straight away if none can and there is one.
+type SelectStatement struct {
+	Cases   []*SelectCase
+	Default *BlockStatement
+	Line    int
+	Column  int
+}
+
+// SelectCase is a receive from Channel, assigned to Name with Assign when
+// there is a name, or a send of Send to it.
+type SelectCase struct {
+	Channel Node
+	Send    Node
+	Name    *IdentifierLiteral
+	Assign  string
+	Body    *BlockStatement
+	Line    int
+}
+
+func (ss *SelectStatement) String() *String {
+	return &String{"select"}
+}
+
+func (ss *SelectStatement) Value() interface{} {
+	return ss
+}
+
+func (ss *SelectStatement) GetLine() int {
+	return ss.Line
+}
+
+func (ss *SelectStatement) GetColumn() int {
+	return ss.Column
+}
+
+// ImportStatement loads the module at Path and binds it to Name, which
+// defaults to the file name without its extension.
+type ImportStatement struct {
+	Path   string
+	Name   string
+	Line   int
+	Column int
+}
+
+func (is *ImportStatement) String() *String {
+	if is.Name == "" {
+		return &String{fmt.Sprintf("import %q", is.Path)}
+	}
+	return &String{fmt.Sprintf("import %s from %q", is.Name, is.Path)}
+}
+
+func (is *ImportStatement) Value() interface{} {
+	return is
+}
+
+func (is *ImportStatement) GetLine() int {
+	return is.Line
+}
+
+func (is *ImportStatement) GetColumn() int {
+	return is.Column
+}
+
+// ArrayLiteral is [a, b, c], its elements are evaluated into an Array.
+type ArrayLiteral struct {
+	Elements []Node
+	Line     int
+	Column   int
+}
+
+func (al *ArrayLiteral) String() *String {
+	elements := make([]string, len(al.Elements))
+	for i, element := range al.Elements {
+		elements[i] = element.String().value
+	}
+	return &String{fmt.Sprintf("[%s]", strings.Join(elements, ", "))}
+}
+
+func (al *ArrayLiteral) Value() interface{} {
+	return al
+}
+
+func (al *ArrayLiteral) GetLine() int {
+	return al.Line
+}
+
+func (al *ArrayLiteral) GetColumn() int {
+	return al.Column
+}
+
+// MapLiteral is {key: value, ...}, Keys and Values are parallel.
+type MapLiteral struct {
+	Keys   []Node
+	Values []Node
+	Line   int
+	Column int
+}
+
+func (ml *MapLiteral) String() *String {
+	entries := make([]string, len(ml.Keys))
+	for i := range ml.Keys {
+		entries[i] = fmt.Sprintf("%s: %s", ml.Keys[i].String().value, ml.Values[i].String().value)
+	}
+	return &String{fmt.Sprintf("{%s}", strings.Join(entries, ", "))}
+}
+
+func (ml *MapLiteral) Value() interface{} {
+	return ml
+}
+
+func (ml *MapLiteral) GetLine() int {
+	return ml.Line
+}
+
+func (ml *MapLiteral) GetColumn() int {
+	return ml.Column
+}
+
+// IndexNode is object[index].
+type IndexNode struct {
+	Object Node
+	Index  Node
+	Line   int
+	Column int
+}
+
+func (in *IndexNode) String() *String {
+	return &String{fmt.Sprintf("%s[%s]", in.Object.String().value, in.Index.String().value)}
+}
+
+func (in *IndexNode) Value() interface{} {
+	return in
+}
+
+func (in *IndexNode) GetLine() int {
+	return in.Line
+}
+
+func (in *IndexNode) GetColumn() int {
+	return in.Column
+}
+
+// SliceNode is object[start:end], either bound may be left out.
+type SliceNode struct {
+	Object Node
+	Start  Node
+	End    Node
+	Line   int
+	Column int
+}
+
+func (sn *SliceNode) String() *String {
+	var start, end string
+	if sn.Start != nil {
+		start = sn.Start.String().value
+	}
+	if sn.End != nil {
+		end = sn.End.String().value
+	}
+	return &String{fmt.Sprintf("%s[%s:%s]", sn.Object.String().value, start, end)}
+}
+
+func (sn *SliceNode) Value() interface{} {
+	return sn
+}
+
+func (sn *SliceNode) GetLine() int {
+	return sn.Line
+}
+
+func (sn *SliceNode) GetColumn() int {
+	return sn.Column
+}
+
+// TryStatement is try { } catch name { } finally { }, either the catch or
+// the finally block may be left out and the catch name is optional.
+type TryStatement struct {
+	Body      *BlockStatement
+	CatchName *IdentifierLiteral
+	Catch     *BlockStatement
+	Finally   *BlockStatement
+	Line      int
+	Column    int
+}
+
+func (ts *TryStatement) String() *String {
+	return &String{"try"}
+}
+
+func (ts *TryStatement) Value() interface{} {
+	return ts
+}
+
+func (ts *TryStatement) GetLine() int {
+	return ts.Line
+}
+
+func (ts *TryStatement) GetColumn() int {
+	return ts.Column
+}
+
+// AttributeNode is an attribute lookup such as err.message
+type AttributeNode struct {
+	Object    Node
+	Attribute string
+	Line      int
+	Column    int
+}
+
+func (an *AttributeNode) String() *String {
+	return &String{fmt.Sprintf("%s.%s", an.Object.String(), an.Attribute)}
+}
+
+func (an *AttributeNode) Value() interface{} {
+	return an
+}
+
+func (an *AttributeNode) GetLine() int {
+	return an.Line
+}
+
+func (an *AttributeNode) GetColumn() int {
+	return an.Column
+}
+
 type FunctionCall struct {
 	Name      string
 	Function  Node
@@ -799,6 +1243,8 @@ type FunctionLiteral struct {
 	Name      string
 	Arguments []*IdentifierLiteral
 	Body      *BlockStatement
+	Async     bool
+	Generator bool
 	Line      int
 	Column    int
 }
@@ -961,6 +1407,32 @@ func (fe *ForNode) GetColumn() int {
 	return fe.Column
 }
 
+// RangeNode is for key := range x { } or for key, value := range x { }.
+type RangeNode struct {
+	Key      *IdentifierLiteral
+	Element  *IdentifierLiteral
+	Iterable Node
+	Body     Node
+	Line     int
+	Column   int
+}
+
+func (rn *RangeNode) String() *String {
+	return &String{fmt.Sprintf("range %s", rn.Iterable.String().value)}
+}
+
+func (rn *RangeNode) Value() interface{} {
+	return rn
+}
+
+func (rn *RangeNode) GetLine() int {
+	return rn.Line
+}
+
+func (rn *RangeNode) GetColumn() int {
+	return rn.Column
+}
+
 type BlockStatement struct {
 	Statements []Node
 	Line       int
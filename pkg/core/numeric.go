@@ -0,0 +1,458 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DECIMAL_DIVISION_SCALE is the number of fractional digits kept when a
+// decimal division does not terminate. The last digit is rounded half to even.
+const DECIMAL_DIVISION_SCALE = 28
+
+// numeric ranks used to pick the common type of a mixed arithmetic
+// operation, the result always takes the type of the higher ranked operand
+const (
+	INTEGER_RANK int = iota
+	BIGINT_RANK
+	DECIMAL_RANK
+	FLOAT_RANK
+)
+
+func numericRank(obj Object) (int, bool) {
+	switch obj.(type) {
+	case *Integer:
+		return INTEGER_RANK, true
+	case *BigInt:
+		return BIGINT_RANK, true
+	case *Decimal:
+		return DECIMAL_RANK, true
+	case *Float:
+		return FLOAT_RANK, true
+	}
+	return 0, false
+}
+
+// coerceNumeric converts two numbers of different types to the type of the
+// higher ranked one. ok is false if either operand is not a number or both
+// already share a type, in which case the caller has nothing to coerce.
+func coerceNumeric(left, right Object) (Object, Object, bool) {
+	leftRank, ok := numericRank(left)
+	if !ok {
+		return nil, nil, false
+	}
+	rightRank, ok := numericRank(right)
+	if !ok || leftRank == rightRank {
+		return nil, nil, false
+	}
+	if leftRank > rightRank {
+		return left, promoteNumber(right, leftRank), true
+	}
+	return promoteNumber(left, rightRank), right, true
+}
+
+func promoteNumber(obj Object, rank int) Object {
+	switch rank {
+	case BIGINT_RANK:
+		return obj.(*Integer).toBigInt()
+	case DECIMAL_RANK:
+		switch n := obj.(type) {
+		case *Integer:
+			return &Decimal{coefficient: big.NewInt(n.value)}
+		case *BigInt:
+			return &Decimal{coefficient: new(big.Int).Set(n.value)}
+		}
+	case FLOAT_RANK:
+		switch n := obj.(type) {
+		case *Integer:
+			return &Float{value: float64(n.value)}
+		case *BigInt:
+			value, _ := new(big.Float).SetInt(n.value).Float64()
+			return &Float{value: value}
+		case *Decimal:
+			value, _ := n.rat().Float64()
+			return &Float{value: value}
+		}
+	}
+	return obj
+}
+
+// BigInt is an arbitrary precision integer. It is created by a literal with
+// an n suffix (123n), by the bigint builtin or when Integer arithmetic
+// overflows.
+type BigInt struct {
+	value *big.Int
+}
+
+func (b *BigInt) Type() string {
+	return "bigint"
+}
+
+func (b *BigInt) Value() interface{} {
+	return b.value
+}
+
+func (b *BigInt) String() *String {
+	return &String{value: b.value.String()}
+}
+
+func (b *BigInt) Add(other Object) (Object, error) {
+	if otherInt, ok := other.(*BigInt); ok {
+		return &BigInt{new(big.Int).Add(b.value, otherInt.value)}, nil
+	} else if left, right, ok := coerceNumeric(b, other); ok {
+		return left.Add(right)
+	}
+	return nil, newTypeError("Invalid type: cannot perform addition operation with %s and %s", b.Type(), other.Type())
+}
+
+func (b *BigInt) Sub(other Object) (Object, error) {
+	if otherInt, ok := other.(*BigInt); ok {
+		return &BigInt{new(big.Int).Sub(b.value, otherInt.value)}, nil
+	} else if left, right, ok := coerceNumeric(b, other); ok {
+		return left.Sub(right)
+	}
+	return nil, newTypeError("Invalid type: cannot perform subtraction operation with %s and %s", b.Type(), other.Type())
+}
+
+func (b *BigInt) Multiply(other Object) (Object, error) {
+	if otherInt, ok := other.(*BigInt); ok {
+		return &BigInt{new(big.Int).Mul(b.value, otherInt.value)}, nil
+	} else if left, right, ok := coerceNumeric(b, other); ok {
+		return left.Multiply(right)
+	}
+	return nil, newTypeError("Invalid type: cannot perform multiplication operation with %s and %s", b.Type(), other.Type())
+}
+
+// Divide truncates towards zero like Integer division does.
+func (b *BigInt) Divide(other Object) (Object, error) {
+	if otherInt, ok := other.(*BigInt); ok {
+		if otherInt.value.Sign() == 0 {
+			return nil, newZeroDivisionError()
+		}
+		return &BigInt{new(big.Int).Quo(b.value, otherInt.value)}, nil
+	} else if left, right, ok := coerceNumeric(b, other); ok {
+		return left.Divide(right)
+	}
+	return nil, newTypeError("Invalid type: cannot perform division operation with %s and %s", b.Type(), other.Type())
+}
+
+func (b *BigInt) Modulo(other Object) (Object, error) {
+	if otherInt, ok := other.(*BigInt); ok {
+		if otherInt.value.Sign() == 0 {
+			return nil, newZeroDivisionError()
+		}
+		return &BigInt{new(big.Int).Rem(b.value, otherInt.value)}, nil
+	} else if left, right, ok := coerceNumeric(b, other); ok {
+		return left.Modulo(right)
+	}
+	return nil, newTypeError("Invalid type: cannot perform modulo operation with %s and %s", b.Type(), other.Type())
+}
+
+func (b *BigInt) Equal(other Object) (Object, error) {
+	if otherInt, ok := other.(*BigInt); ok {
+		return &Boolean{value: b.value.Cmp(otherInt.value) == 0}, nil
+	} else if left, right, ok := coerceNumeric(b, other); ok {
+		return left.Equal(right)
+	}
+	return nil, newTypeError("Invalid type: cannot compare %s with %s", b.Type(), other.Type())
+}
+
+func (b *BigInt) NotEqual(other Object) (Object, error) {
+	if otherInt, ok := other.(*BigInt); ok {
+		return &Boolean{value: b.value.Cmp(otherInt.value) != 0}, nil
+	} else if left, right, ok := coerceNumeric(b, other); ok {
+		return left.NotEqual(right)
+	}
+	return nil, newTypeError("Invalid type: cannot compare %s with %s", b.Type(), other.Type())
+}
+
+func (b *BigInt) GreaterThan(other Object) (Object, error) {
+	if otherInt, ok := other.(*BigInt); ok {
+		return &Boolean{value: b.value.Cmp(otherInt.value) > 0}, nil
+	} else if left, right, ok := coerceNumeric(b, other); ok {
+		return left.GreaterThan(right)
+	}
+	return nil, newTypeError("Invalid type: cannot compare %s with %s", b.Type(), other.Type())
+}
+
+func (b *BigInt) LessThan(other Object) (Object, error) {
+	if otherInt, ok := other.(*BigInt); ok {
+		return &Boolean{value: b.value.Cmp(otherInt.value) < 0}, nil
+	} else if left, right, ok := coerceNumeric(b, other); ok {
+		return left.LessThan(right)
+	}
+	return nil, newTypeError("Invalid type: cannot compare %s with %s", b.Type(), other.Type())
+}
+
+func (b *BigInt) GreaterThanOrEqual(other Object) (Object, error) {
+	if otherInt, ok := other.(*BigInt); ok {
+		return &Boolean{value: b.value.Cmp(otherInt.value) >= 0}, nil
+	} else if left, right, ok := coerceNumeric(b, other); ok {
+		return left.GreaterThanOrEqual(right)
+	}
+	return nil, newTypeError("Invalid type: cannot compare %s with %s", b.Type(), other.Type())
+}
+
+func (b *BigInt) LessThanOrEqual(other Object) (Object, error) {
+	if otherInt, ok := other.(*BigInt); ok {
+		return &Boolean{value: b.value.Cmp(otherInt.value) <= 0}, nil
+	} else if left, right, ok := coerceNumeric(b, other); ok {
+		return left.LessThanOrEqual(right)
+	}
+	return nil, newTypeError("Invalid type: cannot compare %s with %s", b.Type(), other.Type())
+}
+
+func (b *BigInt) GetColumn() int {
+	return 0
+}
+func (b *BigInt) GetLine() int {
+	return 0
+}
+
+// Decimal is an exact base 10 number stored as coefficient * 10^-scale.
+// Addition, subtraction and multiplication are exact; division is exact
+// when the result terminates and is otherwise rounded to
+// DECIMAL_DIVISION_SCALE fractional digits.
+type Decimal struct {
+	coefficient *big.Int
+	scale       int
+}
+
+// ParseDecimal reads a decimal such as "12.50", "-3" or "1.5e-3".
+func ParseDecimal(input string) (*Decimal, error) {
+	text := strings.ReplaceAll(strings.TrimSpace(input), "_", "")
+	exponent := 0
+	if i := strings.IndexAny(text, "eE"); i >= 0 {
+		exp, err := strconv.Atoi(text[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid decimal %q", input)
+		}
+		exponent = exp
+		text = text[:i]
+	}
+	sign := ""
+	if strings.HasPrefix(text, "-") || strings.HasPrefix(text, "+") {
+		sign, text = text[:1], text[1:]
+	}
+	whole, fraction, _ := strings.Cut(text, ".")
+	digits := whole + fraction
+	if digits == "" || strings.TrimLeft(digits, "0123456789") != "" {
+		return nil, fmt.Errorf("invalid decimal %q", input)
+	}
+	coefficient, _ := new(big.Int).SetString(sign+digits, 10)
+	d := &Decimal{coefficient: coefficient, scale: len(fraction) - exponent}
+	if d.scale < 0 {
+		d.coefficient.Mul(d.coefficient, pow10(-d.scale))
+		d.scale = 0
+	}
+	return d, nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// rescale returns the coefficient of d expressed with the given scale, which
+// must not be smaller than d.scale.
+func (d *Decimal) rescale(scale int) *big.Int {
+	if scale == d.scale {
+		return d.coefficient
+	}
+	return new(big.Int).Mul(d.coefficient, pow10(scale-d.scale))
+}
+
+// align returns the coefficients of d and other at their common scale.
+func (d *Decimal) align(other *Decimal) (*big.Int, *big.Int, int) {
+	scale := max(d.scale, other.scale)
+	return d.rescale(scale), other.rescale(scale), scale
+}
+
+func (d *Decimal) cmp(other *Decimal) int {
+	left, right, _ := d.align(other)
+	return left.Cmp(right)
+}
+
+func (d *Decimal) rat() *big.Rat {
+	return new(big.Rat).SetFrac(d.coefficient, pow10(d.scale))
+}
+
+// trimmed drops trailing fractional zeros, 2.500 becomes 2.5.
+func (d *Decimal) trimmed() *Decimal {
+	coefficient := new(big.Int).Set(d.coefficient)
+	scale := d.scale
+	ten := big.NewInt(10)
+	remainder := new(big.Int)
+	for scale > 0 {
+		quotient, r := new(big.Int).QuoRem(coefficient, ten, remainder)
+		if r.Sign() != 0 {
+			break
+		}
+		coefficient = quotient
+		scale--
+	}
+	return &Decimal{coefficient: coefficient, scale: scale}
+}
+
+func (d *Decimal) Type() string {
+	return "decimal"
+}
+
+func (d *Decimal) Value() interface{} {
+	return d.rat()
+}
+
+func (d *Decimal) String() *String {
+	digits := new(big.Int).Abs(d.coefficient).String()
+	if d.scale > 0 {
+		if len(digits) <= d.scale {
+			digits = strings.Repeat("0", d.scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-d.scale] + "." + digits[len(digits)-d.scale:]
+	}
+	if d.coefficient.Sign() < 0 {
+		digits = "-" + digits
+	}
+	return &String{value: digits}
+}
+
+func (d *Decimal) Add(other Object) (Object, error) {
+	if otherDec, ok := other.(*Decimal); ok {
+		left, right, scale := d.align(otherDec)
+		return &Decimal{coefficient: new(big.Int).Add(left, right), scale: scale}, nil
+	} else if left, right, ok := coerceNumeric(d, other); ok {
+		return left.Add(right)
+	}
+	return nil, newTypeError("Invalid type: cannot perform addition operation with %s and %s", d.Type(), other.Type())
+}
+
+func (d *Decimal) Sub(other Object) (Object, error) {
+	if otherDec, ok := other.(*Decimal); ok {
+		left, right, scale := d.align(otherDec)
+		return &Decimal{coefficient: new(big.Int).Sub(left, right), scale: scale}, nil
+	} else if left, right, ok := coerceNumeric(d, other); ok {
+		return left.Sub(right)
+	}
+	return nil, newTypeError("Invalid type: cannot perform subtraction operation with %s and %s", d.Type(), other.Type())
+}
+
+func (d *Decimal) Multiply(other Object) (Object, error) {
+	if otherDec, ok := other.(*Decimal); ok {
+		return &Decimal{coefficient: new(big.Int).Mul(d.coefficient, otherDec.coefficient), scale: d.scale + otherDec.scale}, nil
+	} else if left, right, ok := coerceNumeric(d, other); ok {
+		return left.Multiply(right)
+	}
+	return nil, newTypeError("Invalid type: cannot perform multiplication operation with %s and %s", d.Type(), other.Type())
+}
+
+func (d *Decimal) Divide(other Object) (Object, error) {
+	if otherDec, ok := other.(*Decimal); ok {
+		if otherDec.coefficient.Sign() == 0 {
+			return nil, newZeroDivisionError()
+		}
+		// (a / 10^sa) / (b / 10^sb) at scale s is a * 10^(s - sa + sb) / b
+		numerator := new(big.Int).Mul(d.coefficient, pow10(DECIMAL_DIVISION_SCALE+otherDec.scale))
+		denominator := new(big.Int).Mul(otherDec.coefficient, pow10(d.scale))
+		quotient, remainder := new(big.Int).QuoRem(numerator, denominator, new(big.Int))
+
+		// round half to even
+		half := new(big.Int).Abs(remainder)
+		half.Lsh(half, 1)
+		if c := half.Cmp(new(big.Int).Abs(denominator)); c > 0 || (c == 0 && quotient.Bit(0) == 1) {
+			if numerator.Sign()*denominator.Sign() < 0 {
+				quotient.Sub(quotient, big.NewInt(1))
+			} else {
+				quotient.Add(quotient, big.NewInt(1))
+			}
+		}
+		return (&Decimal{coefficient: quotient, scale: DECIMAL_DIVISION_SCALE}).trimmed(), nil
+	} else if left, right, ok := coerceNumeric(d, other); ok {
+		return left.Divide(right)
+	}
+	return nil, newTypeError("Invalid type: cannot perform division operation with %s and %s", d.Type(), other.Type())
+}
+
+// Modulo keeps the sign of the dividend, matching Integer.
+func (d *Decimal) Modulo(other Object) (Object, error) {
+	if otherDec, ok := other.(*Decimal); ok {
+		if otherDec.coefficient.Sign() == 0 {
+			return nil, newZeroDivisionError()
+		}
+		left, right, scale := d.align(otherDec)
+		return &Decimal{coefficient: new(big.Int).Rem(left, right), scale: scale}, nil
+	} else if left, right, ok := coerceNumeric(d, other); ok {
+		return left.Modulo(right)
+	}
+	return nil, newTypeError("Invalid type: cannot perform modulo operation with %s and %s", d.Type(), other.Type())
+}
+
+func (d *Decimal) Equal(other Object) (Object, error) {
+	if otherDec, ok := other.(*Decimal); ok {
+		return &Boolean{value: d.cmp(otherDec) == 0}, nil
+	} else if left, right, ok := coerceNumeric(d, other); ok {
+		return left.Equal(right)
+	}
+	return nil, newTypeError("Invalid type: cannot compare %s with %s", d.Type(), other.Type())
+}
+
+func (d *Decimal) NotEqual(other Object) (Object, error) {
+	if otherDec, ok := other.(*Decimal); ok {
+		return &Boolean{value: d.cmp(otherDec) != 0}, nil
+	} else if left, right, ok := coerceNumeric(d, other); ok {
+		return left.NotEqual(right)
+	}
+	return nil, newTypeError("Invalid type: cannot compare %s with %s", d.Type(), other.Type())
+}
+
+func (d *Decimal) GreaterThan(other Object) (Object, error) {
+	if otherDec, ok := other.(*Decimal); ok {
+		return &Boolean{value: d.cmp(otherDec) > 0}, nil
+	} else if left, right, ok := coerceNumeric(d, other); ok {
+		return left.GreaterThan(right)
+	}
+	return nil, newTypeError("Invalid type: cannot compare %s with %s", d.Type(), other.Type())
+}
+
+func (d *Decimal) LessThan(other Object) (Object, error) {
+	if otherDec, ok := other.(*Decimal); ok {
+		return &Boolean{value: d.cmp(otherDec) < 0}, nil
+	} else if left, right, ok := coerceNumeric(d, other); ok {
+		return left.LessThan(right)
+	}
+	return nil, newTypeError("Invalid type: cannot compare %s with %s", d.Type(), other.Type())
+}
+
+func (d *Decimal) GreaterThanOrEqual(other Object) (Object, error) {
+	if otherDec, ok := other.(*Decimal); ok {
+		return &Boolean{value: d.cmp(otherDec) >= 0}, nil
+	} else if left, right, ok := coerceNumeric(d, other); ok {
+		return left.GreaterThanOrEqual(right)
+	}
+	return nil, newTypeError("Invalid type: cannot compare %s with %s", d.Type(), other.Type())
+}
+
+func (d *Decimal) LessThanOrEqual(other Object) (Object, error) {
+	if otherDec, ok := other.(*Decimal); ok {
+		return &Boolean{value: d.cmp(otherDec) <= 0}, nil
+	} else if left, right, ok := coerceNumeric(d, other); ok {
+		return left.LessThanOrEqual(right)
+	}
+	return nil, newTypeError("Invalid type: cannot compare %s with %s", d.Type(), other.Type())
+}
+
+func (d *Decimal) GetColumn() int {
+	return 0
+}
+func (d *Decimal) GetLine() int {
+	return 0
+}
+
+func floatToDecimal(value float64) (*Decimal, error) {
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return nil, fmt.Errorf("cannot convert %v to decimal", value)
+	}
+	// the shortest representation that round trips is what the user most
+	// likely meant, 0.1 becomes 0.1 rather than 0.1000000000000000055...
+	return ParseDecimal(strconv.FormatFloat(value, 'g', -1, 64))
+}
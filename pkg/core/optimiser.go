@@ -0,0 +1,209 @@
+package core
+
+import (
+	"fmt"
+)
+
+// The optimiser rewrites a program between parsing and evaluation into one
+// doing the same with less work. It folds arithmetic and comparisons on
+// literals, replaces an if statement whose condition is a literal with the
+// branch taken, drops the statements following a return or throw and turns
+// the ++ or -- updating the variable a for loop declared into an increment
+// of the loop's frame. An operation on literals that fails is left alone
+// so the error is still raised when it runs.
+
+// Optimise returns node optimised at level, 0 leaving it as it is and 1
+// applying every optimisation. The tree is rewritten in place.
+func Optimise(node Node, level int) Node {
+	if level <= 0 {
+		return node
+	}
+	return optimise(node)
+}
+
+// SetOptimisation sets the level the modules a program imports are
+// optimised at, see Optimise.
+func (e *Evaluator) SetOptimisation(level int) {
+	e.optimisation = level
+}
+
+func optimise(node Node) Node {
+	switch n := node.(type) {
+	case *BlockStatement:
+		return optimiseBlock(n)
+	case *ArrayLiteral:
+		optimiseAll(n.Elements)
+	case *MapLiteral:
+		optimiseAll(n.Keys)
+		optimiseAll(n.Values)
+	case *IndexNode:
+		n.Object, n.Index = optimise(n.Object), optimise(n.Index)
+	case *SliceNode:
+		n.Object, n.Start, n.End = optimise(n.Object), optimise(n.Start), optimise(n.End)
+	case *AttributeNode:
+		n.Object = optimise(n.Object)
+	case *ReturnStatement:
+		n.ReturnValue = optimise(n.ReturnValue)
+	case *ThrowStatement:
+		n.Thrown = optimise(n.Thrown)
+	case *TryStatement:
+		optimiseBlock(n.Body)
+		optimiseBlock(n.Catch)
+		optimiseBlock(n.Finally)
+	case *RangeNode:
+		n.Iterable, n.Body = optimise(n.Iterable), optimise(n.Body)
+	case *ForNode:
+		n.Initialisation, n.Condition = optimise(n.Initialisation), optimise(n.Condition)
+		n.Body, n.Updater = optimise(n.Body), optimise(n.Updater)
+		n.Updater = loopIncrement(n)
+	case *FunctionLiteral:
+		optimiseBlock(n.Body)
+	case *FunctionCall:
+		n.Function = optimise(n.Function)
+		optimiseAll(n.Arguments)
+	case *DeferStatement:
+		optimise(n.Call)
+	case *GoStatement:
+		optimise(n.Call)
+	case *SelectStatement:
+		for _, sc := range n.Cases {
+			sc.Channel, sc.Send = optimise(sc.Channel), optimise(sc.Send)
+			optimiseBlock(sc.Body)
+		}
+		optimiseBlock(n.Default)
+	case *YieldExpression:
+		n.YieldValue = optimise(n.YieldValue)
+	case *AwaitExpression:
+		n.Task = optimise(n.Task)
+	case *IfNode:
+		return optimiseIf(n)
+	case *InfixNode:
+		return optimiseInfix(n)
+	}
+	return node
+}
+
+func optimiseAll(nodes []Node) {
+	for i, node := range nodes {
+		nodes[i] = optimise(node)
+	}
+}
+
+// optimiseBlock optimises the statements of a block. Blocks have no scope
+// of their own so the statements of those it is left holding, such as the
+// branch of an if statement, are spliced into it.
+func optimiseBlock(b *BlockStatement) *BlockStatement {
+	if b == nil {
+		return nil
+	}
+	statements := make([]Node, 0, len(b.Statements))
+	for _, stmt := range b.Statements {
+		stmt = optimise(stmt)
+		if block, ok := stmt.(*BlockStatement); ok {
+			statements = append(statements, block.Statements...)
+		} else {
+			statements = append(statements, stmt)
+		}
+		// nothing after a return or throw can run
+		if len(statements) > 0 {
+			switch statements[len(statements)-1].(type) {
+			case *ReturnStatement, *ThrowStatement:
+				b.Statements = statements
+				return b
+			}
+		}
+	}
+	b.Statements = statements
+	return b
+}
+
+func optimiseIf(n *IfNode) Node {
+	n.Condition = optimise(n.Condition)
+	n.Consequence, n.Alternative = optimise(n.Consequence), optimise(n.Alternative)
+	condition, ok := constant(n.Condition)
+	if !ok {
+		return n
+	}
+	branch := n.Alternative
+	if isTruthy(condition) {
+		branch = n.Consequence
+	}
+	if branch == nil {
+		return &BlockStatement{Statements: []Node{}, Line: n.Line, Column: n.Column}
+	}
+	return branch
+}
+
+func optimiseInfix(n *InfixNode) Node {
+	if n.Operator == "=" || n.Operator == ":=" {
+		if index, ok := n.Left.(*IndexNode); ok {
+			optimise(index)
+		}
+		n.Right = optimise(n.Right)
+		return n
+	}
+	n.Left, n.Right = optimise(n.Left), optimise(n.Right)
+	operation, ok := infixOperators[n.Operator]
+	left, isLeft := constant(n.Left)
+	right, isRight := constant(n.Right)
+	if !ok || !isLeft || !isRight {
+		return n
+	}
+	result, err := operation(left, right)
+	if err != nil {
+		return n
+	}
+	return result
+}
+
+// constant returns the value of a literal.
+func constant(node Node) (Object, bool) {
+	switch n := node.(type) {
+	case *Integer, *Float, *BigInt, *Decimal, *String, *Boolean, *Nil:
+		return n.(Object), true
+	}
+	return nil, false
+}
+
+// loopIncrement returns the updater of a for loop, turned into an
+// incrementStatement when it increments the variable the loop's
+// initialisation declares.
+func loopIncrement(n *ForNode) Node {
+	assign, ok := declaration(n.Initialisation)
+	sufix, isSufix := n.Updater.(*SufixNode)
+	if !ok || !isSufix || (sufix.Operator != "++" && sufix.Operator != "--") {
+		return n.Updater
+	}
+	ident, ok := sufix.Left.(*IdentifierLiteral)
+	if !ok || ident.value != assign.Left.String().value {
+		return n.Updater
+	}
+	return &incrementStatement{Left: ident, Operator: sufix.Operator, Line: sufix.Line, Column: sufix.Column}
+}
+
+// incrementStatement is a ++ or -- of a variable in the current frame whose
+// value is not used, like the updater of a for loop counting over the
+// variable it declared. Unlike a SufixNode the variable is not looked up
+// through the frames.
+type incrementStatement struct {
+	Left     *IdentifierLiteral
+	Operator string
+	Line     int
+	Column   int
+}
+
+func (is *incrementStatement) String() *String {
+	return &String{fmt.Sprintf("%s%s", is.Left.value, is.Operator)}
+}
+
+func (is *incrementStatement) Value() interface{} {
+	return is.Operator
+}
+
+func (is *incrementStatement) GetLine() int {
+	return is.Line
+}
+
+func (is *incrementStatement) GetColumn() int {
+	return is.Column
+}
@@ -0,0 +1,162 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOptimise(t *testing.T) {
+	parse := func(t *testing.T, input string) *BlockStatement {
+		program, err := NewV1Parser(NewV1Lexer(input), false).ParseProgram()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return Optimise(program, 1).(*BlockStatement)
+	}
+
+	t.Run("test constant arithmetic is folded", func(t *testing.T) {
+		program := parse(t, "x = 2 * 3 + y")
+		sum := program.Statements[0].(*InfixNode).Right.(*InfixNode)
+		if six, ok := sum.Left.(*Integer); !ok || six.value != 6 {
+			t.Fatalf("expected 2 * 3 to be folded into 6, got %v", sum.Left)
+		}
+	})
+
+	t.Run("test failing operation is left alone", func(t *testing.T) {
+		program := parse(t, "x = 1 / 0")
+		if _, ok := program.Statements[0].(*InfixNode).Right.(*InfixNode); !ok {
+			t.Fatal("expected 1 / 0 to be left for the error to be raised when it runs")
+		}
+	})
+
+	t.Run("test if with literal condition becomes the branch taken", func(t *testing.T) {
+		program := parse(t, "if 1 > 2 {\n a = 1\n} else {\n a = 2\n b = 3\n}\nif false {\n c = 4\n}")
+		if len(program.Statements) != 2 {
+			t.Fatalf("expected the else branch spliced in, got %d statements", len(program.Statements))
+		}
+		if two := program.Statements[0].(*InfixNode).Right.(*Integer); two.value != 2 {
+			t.Fatalf("expected a = 2, got a = %d", two.value)
+		}
+	})
+
+	t.Run("test code after return is removed", func(t *testing.T) {
+		program := parse(t, "func f() {\n if true {\n  return 1\n }\n print(2)\n}")
+		body := program.Statements[0].(*FunctionLiteral).Body
+		if len(body.Statements) != 1 {
+			t.Fatalf("expected only the return to be left, got %d statements", len(body.Statements))
+		}
+		if _, ok := body.Statements[0].(*ReturnStatement); !ok {
+			t.Fatalf("expected a return statement, got %T", body.Statements[0])
+		}
+	})
+
+	t.Run("test loop updater increments the loop frame", func(t *testing.T) {
+		program := parse(t, "for i := 0; i < 3; i++ {\n}\nj = 0\nfor k := 0; j < 3; j++ {\n}")
+		if _, ok := program.Statements[0].(*ForNode).Updater.(*incrementStatement); !ok {
+			t.Fatalf("expected an increment statement, got %T", program.Statements[0].(*ForNode).Updater)
+		}
+		if _, ok := program.Statements[2].(*ForNode).Updater.(*SufixNode); !ok {
+			t.Fatal("expected an updater of another variable to be left alone")
+		}
+	})
+
+	t.Run("test level 0 leaves the program alone", func(t *testing.T) {
+		program, err := NewV1Parser(NewV1Lexer("x = 2 * 3"), false).ParseProgram()
+		if err != nil {
+			t.Fatal(err)
+		}
+		Optimise(program, 0)
+		if _, ok := program.(*BlockStatement).Statements[0].(*InfixNode).Right.(*InfixNode); !ok {
+			t.Fatal("expected 2 * 3 to be left alone")
+		}
+	})
+}
+
+func TestOptimiseBehaviour(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+		err      string
+	}{
+		{
+			name:     "test folded arithmetic",
+			input:    "x = 3\nprint(2 * 3 + x, 9223372036854775807 + 1, 1.5 * 2, \"a\" + \"b\", 1 < 2)",
+			expected: "9 9223372036854775808 3.000000 ab true\n",
+		},
+		{
+			name:     "test division by zero is still raised",
+			input:    "try {\n print(1 / 0)\n} catch e {\n print(e.message)\n}",
+			expected: "Division by zero\n",
+		},
+		{
+			name:     "test unreachable branches",
+			input:    "if false {\n print(\"never\")\n} else {\n print(\"else\")\n}\nif 1 {\n x := 5\n}\nprint(x)",
+			expected: "else\n5\n",
+		},
+		{
+			name:     "test code after return",
+			input:    "func f(n) {\n if true {\n  return n\n }\n print(\"unreachable\")\n}\nfunc g() {\n print(1)\n return 2\n print(3)\n}\nprint(f(4), g())",
+			expected: "1\n4 2\n",
+		},
+		{
+			name:     "test loop updaters",
+			input:    "total = 0\nfor i := 0; i < 5; i++ {\n total = total + i\n}\nfor j := 3; j > 0; j-- {\n total = total * j\n}\nprint(total)",
+			expected: "60\n",
+		},
+		{
+			name:     "test loop variable assigned in the body",
+			input:    "for i := 0; i < 10; i++ {\n i = i + 2\n print(i)\n}",
+			expected: "2\n5\n8\n11\n",
+		},
+		{
+			name:     "test closures over the loop variable",
+			input:    "fs = []\nfor i := 0; i < 3; i++ {\n fs = fs + [func() {\n  return i\n }]\n}\nf = fs[0]\nprint(f())",
+			expected: "3\n",
+		},
+		{
+			name:     "test loop in generator",
+			input:    "func count(n) {\n for i := 0; i < n; i++ {\n  yield i\n }\n}\nfor v := range count(3) {\n print(v)\n}",
+			expected: "0\n1\n2\n",
+		},
+		{
+			name:     "test increment error in updater",
+			input:    "for i := 0.5; i < 2; i++ {\n print(i)\n}\nfor s := \"a\"; s != \"\"; s++ {\n}",
+			expected: "0.500000\n1.500000\n",
+			err:      "operator '++' not supported for type *core.String",
+		},
+	}
+
+	run := func(input string, vm bool, level int) (string, error) {
+		program, err := NewV1Parser(NewV1Lexer(input), false).ParseProgram()
+		if err != nil {
+			return "", err
+		}
+		var out bytes.Buffer
+		evaluator := NewEvaluator(false)
+		evaluator.SetVM(vm)
+		evaluator.SetOutput(&out)
+		_, err = evaluator.Evaluate(Optimise(program, level))
+		return out.String(), err
+	}
+
+	for _, backend := range backends {
+		for _, test := range cases {
+			t.Run(backend.name+"/"+test.name, func(t *testing.T) {
+				for level := 0; level <= 1; level++ {
+					out, err := run(test.input, backend.vm, level)
+					if test.err != "" {
+						if err == nil || err.Error() != test.err {
+							t.Fatalf("-O%d: expected error %q, got %v", level, test.err, err)
+						}
+					} else if err != nil {
+						t.Fatalf("-O%d: unexpected error: %v", level, err)
+					}
+					if out != test.expected {
+						t.Fatalf("-O%d: expected output %q, got %q", level, test.expected, out)
+					}
+				}
+			})
+		}
+	}
+}
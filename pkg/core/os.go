@@ -0,0 +1,402 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hyperioxx/goscript/pkg/utils"
+)
+
+// osModule builds the native os module.
+func osModule(e *Evaluator) map[string]Object {
+	functions := map[string]func([]Object) (Object, error){
+		"env":      osEnv,
+		"setenv":   osSetenv,
+		"unsetenv": osUnsetenv,
+		"cwd":      osCwd,
+		"exit":     osExit,
+	}
+	module := map[string]Object{}
+	for name, fn := range functions {
+		module[name] = &GoFunction{Name: name, Func: fn}
+	}
+	module["exec"] = &GoFunction{Name: "exec", EvalFunc: (*Evaluator).osExec}
+	module["execStream"] = &GoFunction{Name: "execStream", EvalFunc: (*Evaluator).osExecStream}
+	module["args"] = newStringArray(e.args)
+	return module
+}
+
+// SetArgs sets the arguments the script was started with, they are read
+// through os.args.
+func (e *Evaluator) SetArgs(args []string) {
+	e.args = args
+}
+
+// commandArgs reads the command name, its arguments and the options of exec
+// and execStream. options is a map that may set "dir", "env", a map of
+// variables added to the environment, and "stdin".
+func commandArgs(name string, args []Object, max int) (*exec.Cmd, error) {
+	if err := checkArgs(name, args, 1, max); err != nil {
+		return nil, err
+	}
+	program, err := stringArg(name, args, 0)
+	if err != nil {
+		return nil, err
+	}
+	var programArgs []string
+	if len(args) > 1 {
+		array, ok := args[1].(*Array)
+		if !ok {
+			return nil, newTypeError("%s() expects an array of arguments, got %s", name, args[1].Type())
+		}
+		for i := range array.Elements {
+			arg, err := stringArg(name, array.Elements, i)
+			if err != nil {
+				return nil, err
+			}
+			programArgs = append(programArgs, arg)
+		}
+	}
+	return exec.Command(program, programArgs...), nil
+}
+
+func setCommandOptions(name string, cmd *exec.Cmd, arg Object) error {
+	options, ok := arg.(*Map)
+	if !ok {
+		return newTypeError("%s() expects a map of options, got %s", name, arg.Type())
+	}
+	for _, key := range options.Keys() {
+		value, _ := options.Get(key)
+		switch key {
+		case "dir":
+			dir, err := stringArg(name, []Object{value}, 0)
+			if err != nil {
+				return err
+			}
+			cmd.Dir = dir
+		case "stdin":
+			stdin, err := stringArg(name, []Object{value}, 0)
+			if err != nil {
+				return err
+			}
+			cmd.Stdin = strings.NewReader(stdin)
+		case "env":
+			env, ok := value.(*Map)
+			if !ok {
+				return newTypeError("%s() expects env to be a map, got %s", name, value.Type())
+			}
+			cmd.Env = os.Environ()
+			for _, variable := range env.Keys() {
+				value, _ := env.Get(variable)
+				str, err := stringArg(name, []Object{value}, 0)
+				if err != nil {
+					return err
+				}
+				cmd.Env = append(cmd.Env, variable+"="+str)
+			}
+		default:
+			return newRuntimeError(VALUE_ERROR, "%s() got an unknown option %q", name, key)
+		}
+	}
+	return nil
+}
+
+// exitCode returns the exit code of a command that ran, a command that
+// could not be started is an OSError.
+func exitCode(err error) (int, error) {
+	if err == nil {
+		return 0, nil
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	return 0, newOSError(err)
+}
+
+// osExec is exec(name, args, options) running a command to completion. It
+// returns a map of its stdout, stderr and exit code, a non zero exit code is
+// not an error.
+func (e *Evaluator) osExec(args []Object) (Object, error) {
+	cmd, err := commandArgs("exec", args, 3)
+	if err != nil {
+		return &Nil{}, err
+	}
+	if len(args) == 3 {
+		if err := setCommandOptions("exec", cmd, args[2]); err != nil {
+			return &Nil{}, err
+		}
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	waitErr := e.wait(func(stop <-chan struct{}) {
+		err = runCommand(cmd, stop)
+	})
+	if waitErr != nil {
+		return &Nil{}, waitErr
+	}
+	code, err := exitCode(err)
+	if err != nil {
+		return &Nil{}, err
+	}
+	result := NewMap()
+	result.Set("stdout", &String{value: stdout.String()})
+	result.Set("stderr", &String{value: stderr.String()})
+	result.Set("code", &Integer{value: int64(code)})
+	return result, nil
+}
+
+// runCommand runs cmd to completion, killing it and the processes it
+// started if stop is closed first.
+func runCommand(cmd *exec.Cmd, stop <-chan struct{}) error {
+	if err := startCommand(cmd); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+			killCommand(cmd)
+		case <-done:
+		}
+	}()
+	return waitCommand(cmd)
+}
+
+// commands holds the commands started by exec and execStream that are
+// still running. They run in process groups of their own, which an
+// interrupt sent to the terminal's does not reach, so KillCommands kills
+// them instead.
+var commands = struct {
+	sync.Mutex
+	running map[*exec.Cmd]bool
+}{running: map[*exec.Cmd]bool{}}
+
+// startCommand starts cmd in a process group of its own and records it as
+// running until waitCommand.
+func startCommand(cmd *exec.Cmd) error {
+	ownProcessGroup(cmd)
+	commands.Lock()
+	defer commands.Unlock()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	commands.running[cmd] = true
+	return nil
+}
+
+// waitCommand waits for cmd to finish.
+func waitCommand(cmd *exec.Cmd) error {
+	err := cmd.Wait()
+	commands.Lock()
+	delete(commands.running, cmd)
+	commands.Unlock()
+	return err
+}
+
+// KillCommands kills the commands scripts started that are still running,
+// along with the processes they started. Whoever runs scripts calls it
+// before exiting, on an interrupt in particular.
+func KillCommands() {
+	commands.Lock()
+	defer commands.Unlock()
+	for cmd := range commands.running {
+		killCommand(cmd)
+	}
+}
+
+// commandLine is a line written by a running command, stderr tells which
+// stream it came from and eof that the stream is finished.
+type commandLine struct {
+	text   string
+	stderr bool
+	eof    bool
+}
+
+// osExecStream is execStream(name, args, onStdout, onStderr, options)
+// calling onStdout and onStderr with each line the command writes, as it
+// writes it, and returning the exit code. Without onStderr the command's
+// stderr goes to ours. An error raised by a callback, or the program being
+// stopped, kills the command.
+func (e *Evaluator) osExecStream(args []Object) (Object, error) {
+	if err := checkArgs("execStream", args, 3, 5); err != nil {
+		return &Nil{}, err
+	}
+	cmd, err := commandArgs("execStream", args[:2], 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	var onStderr Object
+	if len(args) > 3 {
+		if _, isNil := args[3].(*Nil); !isNil {
+			onStderr = args[3]
+		}
+	}
+	if len(args) == 5 {
+		if err := setCommandOptions("execStream", cmd, args[4]); err != nil {
+			return &Nil{}, err
+		}
+	}
+
+	lines := make(chan commandLine)
+	var readers []io.Reader
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return &Nil{}, newOSError(err)
+	}
+	readers = append(readers, stdout)
+	if onStderr != nil {
+		stderr, err := cmd.StderrPipe()
+		if err != nil {
+			return &Nil{}, newOSError(err)
+		}
+		readers = append(readers, stderr)
+	} else {
+		cmd.Stderr = os.Stderr
+	}
+	if err := startCommand(cmd); err != nil {
+		return &Nil{}, newOSError(err)
+	}
+
+	// the pipes are read in the background but the callbacks run here, on
+	// the evaluator that called execStream
+	done := make(chan struct{})
+	for i, reader := range readers {
+		go func(reader io.Reader, stderr bool) {
+			scanner := bufio.NewScanner(reader)
+			for scanner.Scan() {
+				select {
+				case lines <- commandLine{text: scanner.Text(), stderr: stderr}:
+				case <-done:
+					return
+				}
+			}
+			select {
+			case lines <- commandLine{stderr: stderr, eof: true}:
+			case <-done:
+			}
+		}(reader, i == 1)
+	}
+
+	var callErr error
+	for open := len(readers); open > 0; {
+		var line commandLine
+		err := e.wait(func(stop <-chan struct{}) {
+			select {
+			case line = <-lines:
+			case <-stop:
+			}
+		})
+		if err != nil {
+			callErr = err
+			break
+		}
+		if line.eof {
+			open--
+			continue
+		}
+		callback := args[2]
+		if line.stderr {
+			callback = onStderr
+		}
+		if _, err := e.callObject(callback, []Object{&String{value: line.text}}, 0); err != nil {
+			callErr = err
+			break
+		}
+	}
+	close(done)
+	if callErr != nil {
+		killCommand(cmd)
+		waitCommand(cmd)
+		return &Nil{}, callErr
+	}
+
+	code, err := exitCode(waitCommand(cmd))
+	if err != nil {
+		return &Nil{}, err
+	}
+	return &Integer{value: int64(code)}, nil
+}
+
+// osEnv is env(name) returning the variable or nil when it is not set, or
+// env() returning every variable as a map sorted by name.
+func osEnv(args []Object) (Object, error) {
+	values, err := stringArgs("env", args, 0, 1, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	if len(values) == 1 {
+		value, ok := os.LookupEnv(values[0])
+		if !ok {
+			return &Nil{}, nil
+		}
+		return &String{value: value}, nil
+	}
+	environ := os.Environ()
+	sort.Strings(environ)
+	env := NewMap()
+	for _, variable := range environ {
+		name, value, _ := strings.Cut(variable, "=")
+		env.Set(name, &String{value: value})
+	}
+	return env, nil
+}
+
+func osSetenv(args []Object) (Object, error) {
+	values, err := stringArgs("setenv", args, 2, 2, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	if err := os.Setenv(values[0], values[1]); err != nil {
+		return &Nil{}, newOSError(err)
+	}
+	return &Nil{}, nil
+}
+
+func osUnsetenv(args []Object) (Object, error) {
+	values, err := stringArgs("unsetenv", args, 1, 1, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	if err := os.Unsetenv(values[0]); err != nil {
+		return &Nil{}, newOSError(err)
+	}
+	return &Nil{}, nil
+}
+
+func osCwd(args []Object) (Object, error) {
+	if err := checkArgs("cwd", args, 0, 0); err != nil {
+		return &Nil{}, err
+	}
+	dir, err := utils.GetWorkingDirectory()
+	if err != nil {
+		return &Nil{}, newOSError(err)
+	}
+	return &String{value: dir}, nil
+}
+
+// osExit is exit() or exit(code). It stops the script by returning an
+// ExitError, which whoever runs the evaluator turns into the exit of the
+// process.
+func osExit(args []Object) (Object, error) {
+	if err := checkArgs("exit", args, 0, 1); err != nil {
+		return &Nil{}, err
+	}
+	code := 0
+	if len(args) == 1 {
+		var err error
+		if code, err = intArg("exit", args, 0); err != nil {
+			return &Nil{}, err
+		}
+	}
+	return &Nil{}, &ExitError{Code: code}
+}
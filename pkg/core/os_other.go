@@ -0,0 +1,14 @@
+//go:build !unix
+
+package core
+
+import (
+	"os/exec"
+)
+
+func ownProcessGroup(cmd *exec.Cmd) {}
+
+// killCommand kills cmd, the processes it started are left running.
+func killCommand(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
@@ -0,0 +1,174 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOsModule(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOSCRIPT_TEST_VALUE", "set")
+
+	cases := []struct {
+		name     string
+		input    string
+		expected Object
+		err      string
+	}{
+		{
+			name:     "test exec",
+			input:    "r = os.exec(\"sh\", [\"-c\", \"echo out; echo err >&2; exit 3\"])\n[r[\"stdout\"], r[\"stderr\"], r[\"code\"]]",
+			expected: &Array{Elements: []Object{&String{value: "out\n"}, &String{value: "err\n"}, &Integer{value: 3}}},
+		},
+		{
+			name:     "test exec options",
+			input:    "r = os.exec(\"sh\", [\"-c\", \"pwd; cat; echo $EXTRA\"], {\"dir\": \"/\", \"stdin\": \"in \", \"env\": {\"EXTRA\": \"x\"}})\nr[\"stdout\"]",
+			expected: &String{value: "/\nin x\n"},
+		},
+		{
+			name:  "test exec missing program",
+			input: "os.exec(\"goscript-no-such-program\")",
+			err:   "exec: \"goscript-no-such-program\": executable file not found in $PATH",
+		},
+		{
+			name:     "test exec stream",
+			input:    "out = []\nerrs = []\ncode = os.execStream(\"sh\", [\"-c\", \"echo a; echo b; echo c >&2\"], func(line) {\n out = out + [line]\n}, func(line) {\n errs = errs + [line]\n})\n[out, errs, code]",
+			expected: &Array{Elements: []Object{newStringArray([]string{"a", "b"}), newStringArray([]string{"c"}), &Integer{value: 0}}},
+		},
+		{
+			name:  "test exec stream callback error",
+			input: "os.execStream(\"sh\", [\"-c\", \"echo a; sleep 5; echo b\"], func(line) {\n throw \"stop at \" + line\n})",
+			err:   "stop at a",
+		},
+		{
+			name:     "test env",
+			input:    "[os.env(\"GOSCRIPT_TEST_VALUE\"), os.env(\"GOSCRIPT_TEST_MISSING\"), os.env()[\"GOSCRIPT_TEST_VALUE\"]]",
+			expected: &Array{Elements: []Object{&String{value: "set"}, &Nil{}, &String{value: "set"}}},
+		},
+		{
+			name:     "test setenv is seen by commands",
+			input:    "os.setenv(\"GOSCRIPT_TEST_VALUE\", \"changed\")\nos.exec(\"sh\", [\"-c\", \"echo $GOSCRIPT_TEST_VALUE\"])[\"stdout\"]",
+			expected: &String{value: "changed\n"},
+		},
+		{
+			name:     "test cwd",
+			input:    "os.cwd()",
+			expected: &String{value: wd},
+		},
+		{
+			name:  "test exit is not caught",
+			input: "try {\n os.exit(4)\n} catch e {\n}\n1",
+			err:   "exit status 4",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := evalInput("import \"os\"\n" + test.input)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestOsArgsAndExit(t *testing.T) {
+	input := "import \"os\"\nfunc main() {\n defer os.setenv(\"GOSCRIPT_TEST_DEFERRED\", \"ran\")\n if length(os.args) == 2 {\n  os.exit(7)\n }\n}\nmain()"
+	t.Setenv("GOSCRIPT_TEST_DEFERRED", "")
+
+	program, err := NewV1Parser(NewV1Lexer(input), false).ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	evaluator := NewEvaluator(false)
+	evaluator.SetArgs([]string{"first", "second"})
+	_, err = evaluator.Evaluate(program)
+
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 7 {
+		t.Fatalf("expected exit status 7, got %v", err)
+	}
+	if os.Getenv("GOSCRIPT_TEST_DEFERRED") != "ran" {
+		t.Fatalf("deferred call did not run before exiting")
+	}
+}
+
+func TestExecStreamKillsChildren(t *testing.T) {
+	// the child of sh would create the marker if it were left running after
+	// the callback failed
+	marker := filepath.Join(t.TempDir(), "marker")
+	input := fmt.Sprintf("import \"os\"\nos.execStream(\"sh\", [\"-c\", \"echo a; (sleep 0.5; touch %s)\"], func(line) {\n throw \"stop\"\n})", marker)
+	start := time.Now()
+	if _, err := evalInput(input); err == nil || err.Error() != "stop" {
+		t.Fatalf("expected error %q, got %v", "stop", err)
+	}
+	if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+		t.Fatalf("execStream took %s to return after the callback failed", elapsed)
+	}
+	time.Sleep(time.Second)
+	if _, err := os.Stat(marker); err == nil {
+		t.Fatal("the command's children were left running")
+	}
+}
+
+func TestKillCommands(t *testing.T) {
+	// an interrupt kills the running commands, the child of sh would create
+	// the marker if it were left running
+	marker := filepath.Join(t.TempDir(), "marker")
+	input := fmt.Sprintf("import \"os\"\nos.exec(\"sh\", [\"-c\", \"(sleep 0.5; touch %s) & sleep 30\"])[\"code\"]", marker)
+	type outcome struct {
+		result Object
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := evalInput(input)
+		done <- outcome{result, err}
+	}()
+
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		commands.Lock()
+		running := len(commands.running)
+		commands.Unlock()
+		if running > 0 {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("the command did not start")
+		}
+	}
+	KillCommands()
+
+	select {
+	case out := <-done:
+		if out.err != nil {
+			t.Fatalf("unexpected error: %v", out.err)
+		}
+		if reflect.DeepEqual(out.result, &Integer{value: 0}) {
+			t.Fatal("expected the killed command to fail")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("exec was still running after the commands were killed")
+	}
+	time.Sleep(time.Second)
+	if _, err := os.Stat(marker); err == nil {
+		t.Fatal("the command's children were left running")
+	}
+}
@@ -1,11 +1,15 @@
 package core
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
 	"strconv"
+	"strings"
 )
 
 const SYNTAX_ERROR_MSG = "syntax error on line: %d"
+const LEXER_ERROR_MSG = "%s on line: %d, column: %d"
 
 const (
 	_ int = iota
@@ -27,11 +31,15 @@ var precedences = map[TokenType]int{
 	NOT_EQ:     EQUALS,
 	LT:         LESSGREATER,
 	GT:         LESSGREATER,
+	LT_EQ:      LESSGREATER,
+	GT_EQ:      LESSGREATER,
 	ADD:        SUM,
 	SUB:        SUM,
 	MUL:        PRODUCT,
 	DIV:        PRODUCT,
+	REM:        PRODUCT,
 	LPAREN:     CALL_P,
+	DOT:        CALL_P,
 	ASSIGN:     ASSIGN_P,
 	ASSIGN_INF: ASSIGN_P,
 	IF:         IF_P,
@@ -48,12 +56,14 @@ type Parser interface {
 type V1Parser struct {
 	l Lexer
 
-	curToken        Token
-	peekToken       Token
-	errors          []string
-	Debug           bool
-	prefixParseFns  map[TokenType]prefixParseFn
-	infixParseFns   map[TokenType]infixParseFn
+	curToken       Token
+	peekToken      Token
+	errors         []string
+	Debug          bool
+	prefixParseFns map[TokenType]prefixParseFn
+	infixParseFns  map[TokenType]infixParseFn
+	// functions are the function literals being parsed, innermost last
+	functions []*FunctionLiteral
 }
 
 type (
@@ -75,6 +85,7 @@ func NewV1Parser(l Lexer, debug bool) Parser {
 	p.registerInfix(SUB, p.parseInfixNode)
 	p.registerInfix(MUL, p.parseInfixNode)
 	p.registerInfix(DIV, p.parseInfixNode)
+	p.registerInfix(REM, p.parseInfixNode)
 	p.registerInfix(EQ, p.parseInfixNode)
 	p.registerInfix(NOT_EQ, p.parseInfixNode)
 	p.registerInfix(GT, p.parseInfixNode)
@@ -85,8 +96,12 @@ func NewV1Parser(l Lexer, debug bool) Parser {
 	p.registerInfix(ASSIGN_INF, p.parseInfixNode)
 	p.registerInfix(INC, p.parseSuffixNode)
 	p.registerInfix(DEC, p.parseSuffixNode)
+	p.registerInfix(DOT, p.parseAttributeNode)
+	p.registerInfix(LPAREN, p.parseFunctionCall)
+	p.registerInfix(LBRACKET, p.parseIndexNode)
 	// prefix expressions
 	p.registerPrefix(INT, p.parseIntegerLiteral)
+	p.registerPrefix(BIGINT, p.parseBigIntLiteral)
 	p.registerPrefix(IDENT, p.parseIdentifier)
 	p.registerPrefix(FUNC, p.parseFunctionLiteral)
 	p.registerPrefix(LPAREN, p.parseLeftParen)
@@ -98,6 +113,19 @@ func NewV1Parser(l Lexer, debug bool) Parser {
 	p.registerPrefix(BOOL, p.parseBooleanLiteral)
 	p.registerPrefix(TRUE, p.parseBooleanLiteral)
 	p.registerPrefix(FALSE, p.parseBooleanLiteral)
+	p.registerPrefix(ERROR, p.parseLexerError)
+	p.registerPrefix(THROW, p.parseThrowStatement)
+	p.registerPrefix(TRY, p.parseTryStatement)
+	p.registerPrefix(DEFER, p.parseDeferStatement)
+	p.registerPrefix(ASYNC, p.parseAsyncFunction)
+	p.registerPrefix(AWAIT, p.parseAwaitExpression)
+	p.registerPrefix(GO, p.parseGoStatement)
+	p.registerPrefix(SELECT, p.parseSelectStatement)
+	p.registerPrefix(YIELD, p.parseYieldExpression)
+	p.registerPrefix(IMPORT, p.parseImportStatement)
+	p.registerPrefix(LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(LBRACE, p.parseMapLiteral)
+	p.registerPrefix(RANGE, p.parseRangeExpression)
 
 	p.nextToken()
 	p.nextToken()
@@ -157,16 +185,18 @@ func (p *V1Parser) ParseNode(precedence int) (Node, error) {
 		p.nextToken()
 
 		leftExp, err = infix(leftExp)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return leftExp, err
+	return leftExp, nil
 }
 
 func (p *V1Parser) parseBooleanLiteral() (Node, error) {
 	literal := &Boolean{
 		value: p.curToken.Value == "true",
 	}
-	p.nextToken()
 	return literal, nil
 }
 
@@ -177,7 +207,7 @@ func (p *V1Parser) parseLeftParen() (Node, error) {
 
 	p.nextToken()
 
-	if p.peekTokenIs(IDENT) {
+	if p.curTokenIs(IDENT) && p.peekTokenIs(IDENT) {
 		if p.Debug {
 			fmt.Println("Detected IDENT token")
 		}
@@ -257,9 +287,19 @@ func (p *V1Parser) parseStringLiteral() (Node, error) {
 	return &String{value: p.curToken.Value}, nil
 }
 
+func (p *V1Parser) parseLexerError() (Node, error) {
+	return nil, fmt.Errorf(LEXER_ERROR_MSG, p.curToken.Error, p.curToken.Line, p.curToken.Column)
+}
+
 func (p *V1Parser) parseIntegerLiteral() (Node, error) {
 	lit := &Integer{}
-	value, err := strconv.Atoi(p.curToken.Value)
+	// the lexer has already validated the literal so base 0 only has to
+	// pick up the 0x, 0o and 0b prefixes
+	value, err := strconv.ParseInt(strings.ReplaceAll(p.curToken.Value, "_", ""), 0, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		// too big for an int64 so it becomes a BigInt instead
+		return p.parseBigIntLiteral()
+	}
 	if err != nil {
 		return nil, fmt.Errorf(SYNTAX_ERROR_MSG, p.curToken.Line)
 	}
@@ -269,8 +309,20 @@ func (p *V1Parser) parseIntegerLiteral() (Node, error) {
 	return lit, nil
 }
 
+func (p *V1Parser) parseBigIntLiteral() (Node, error) {
+	value, ok := new(big.Int).SetString(strings.ReplaceAll(p.curToken.Value, "_", ""), 0)
+	if !ok {
+		return nil, fmt.Errorf(SYNTAX_ERROR_MSG, p.curToken.Line)
+	}
+
+	return &BigInt{value: value}, nil
+}
+
 func (p *V1Parser) parseFloatLiteral() (Node, error) {
-	value, err := strconv.ParseFloat(p.curToken.Value, 64)
+	value, err := strconv.ParseFloat(strings.ReplaceAll(p.curToken.Value, "_", ""), 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return nil, fmt.Errorf(LEXER_ERROR_MSG, fmt.Sprintf("float literal %s is out of range", p.curToken.Value), p.curToken.Line, p.curToken.Column)
+	}
 	if err != nil {
 		return nil, fmt.Errorf(SYNTAX_ERROR_MSG, p.curToken.Line)
 	}
@@ -283,7 +335,7 @@ func (p *V1Parser) parseIdentifier() (Node, error) {
 		fmt.Println("Entering parseIdentifier")
 	}
 
-	ident := &IdentifierLiteral{value: p.curToken.Value}
+	ident := NewIdentifierLiteral(p.curToken.Value, p.curToken.Line, p.curToken.Column)
 
 	if p.Debug {
 		fmt.Printf("Parsed IDENT: %v\n", ident.value)
@@ -318,16 +370,18 @@ func (p *V1Parser) parseFunctionLiteral() (Node, error) {
 		fmt.Println("Entering function literal ")
 	}
 	fl := &FunctionLiteral{}
-	p.nextToken()
-
-	fl.Name = p.curToken.Value
+	// anonymous functions go straight to their parameters
+	if !p.peekTokenIs(LPAREN) {
+		p.nextToken()
+		fl.Name = p.curToken.Value
+	}
 
 	p.nextToken()
 
 	if !p.peekTokenIs(RPAREN) && !p.curTokenIs(LBRACE) {
 		for !p.curTokenIs(RPAREN) {
 			p.nextToken()
-			param := &IdentifierLiteral{value: p.curToken.Value}
+			param := NewIdentifierLiteral(p.curToken.Value, p.curToken.Line, p.curToken.Column)
 			p.nextToken()
 			fl.Arguments = append(fl.Arguments, param)
 		}
@@ -338,12 +392,15 @@ func (p *V1Parser) parseFunctionLiteral() (Node, error) {
 		p.nextToken()
 	}
 
+	p.functions = append(p.functions, fl)
 	block, err := p.parseBlockStatement()
+	p.functions = p.functions[:len(p.functions)-1]
 	if err != nil {
 		return nil, err
 	}
 
 	fl.Body = block
+	markTailCalls(block)
 
 	return fl, nil
 }
@@ -357,33 +414,34 @@ func (p *V1Parser) parseFunctionCall(function Node) (Node, error) {
 	fc := &FunctionCall{
 		Name:     function.String().value,
 		Function: function,
+		Line:     p.curToken.Line,
+		Column:   p.curToken.Column,
 	}
 
-	if !p.curTokenIs(RPAREN) {
-		for !p.curTokenIs(RPAREN) {
-			p.nextToken()
-			param, err := p.ParseNode(0)
-			if err != nil {
-				return nil, err
-			}
-			p.nextToken()
-			fc.Arguments = append(fc.Arguments, param)
-		}
+	// curToken is the opening parenthesis, on return it is the closing one
+	if p.expectPeek(RPAREN) {
+		return fc, nil
 	}
 
-	if !p.curTokenIs(RPAREN) {
-		if p.Debug {
-			fmt.Println("Current token is not RPAREN, returning nil")
+	for {
+		p.nextToken()
+		param, err := p.ParseNode(LOWEST)
+		if err != nil {
+			return nil, err
+		}
+		fc.Arguments = append(fc.Arguments, param)
+		if !p.expectPeek(COMMA) {
+			break
 		}
-		return nil, fmt.Errorf(SYNTAX_ERROR_MSG, p.curToken.Line)
 	}
 
-	if p.Debug {
-		fmt.Println("Current token is RPAREN, moving to next token")
+	if !p.expectPeek(RPAREN) {
+		if p.Debug {
+			fmt.Println("Peek token is not RPAREN, returning nil")
+		}
+		return nil, fmt.Errorf(SYNTAX_ERROR_MSG, p.peekToken.Line)
 	}
 
-	p.nextToken()
-
 	if p.Debug {
 		fmt.Println("Exiting parseFunctionCall")
 	}
@@ -418,7 +476,7 @@ func (p *V1Parser) parseInfixNode(left Node) (Node, error) {
 	right, err := p.ParseNode(precedence)
 
 	if err != nil {
-		return nil, fmt.Errorf(SYNTAX_ERROR_MSG, p.curToken.Line)
+		return nil, err
 	}
 
 	Node.Right = right
@@ -433,9 +491,14 @@ func (p *V1Parser) parseInfixNode(left Node) (Node, error) {
 
 func (p *V1Parser) parseReturnStatement() (Node, error) {
 
-	p.nextToken()
+	rs := &ReturnStatement{Line: p.curToken.Line, Column: p.curToken.Column}
+
+	// a bare return must not swallow the brace closing its block
+	if p.peekTokenIs(RBRACE) || p.peekTokenIs(NEWLINE) || p.peekTokenIs(SEMICOLON) || p.peekTokenIs(EOF) {
+		return rs, nil
+	}
 
-	rs := &ReturnStatement{}
+	p.nextToken()
 
 	node, err := p.ParseNode(LOWEST)
 	if err != nil {
@@ -451,6 +514,428 @@ func (p *V1Parser) parseReturnStatement() (Node, error) {
 	return rs, nil
 }
 
+// markTailCalls marks the return statements of a function body whose value
+// is a call in tail position. A return inside a try statement is not in tail
+// position as the call has to finish before the catch or finally block can
+// run, nor is one in a function defined in the body, which is marked when
+// that function is parsed.
+func markTailCalls(body *BlockStatement) {
+	inspect(body, func(n Node) bool {
+		switch n := n.(type) {
+		case *FunctionLiteral, *TryStatement:
+			return false
+		case *ReturnStatement:
+			_, n.Tail = n.ReturnValue.(*FunctionCall)
+		}
+		return true
+	})
+}
+
+func (p *V1Parser) parseThrowStatement() (Node, error) {
+	ts := &ThrowStatement{Line: p.curToken.Line, Column: p.curToken.Column}
+
+	p.nextToken()
+
+	node, err := p.ParseNode(LOWEST)
+	if err != nil {
+		return nil, err
+	}
+	if node == nil {
+		return nil, fmt.Errorf(SYNTAX_ERROR_MSG, ts.Line)
+	}
+	ts.Thrown = node
+
+	return ts, nil
+}
+
+func (p *V1Parser) parseDeferStatement() (Node, error) {
+	ds := &DeferStatement{Line: p.curToken.Line, Column: p.curToken.Column}
+
+	p.nextToken()
+
+	node, err := p.ParseNode(LOWEST)
+	if err != nil {
+		return nil, err
+	}
+	call, ok := node.(*FunctionCall)
+	if !ok {
+		return nil, fmt.Errorf("expression in defer must be function call on line: %d", ds.Line)
+	}
+	ds.Call = call
+
+	return ds, nil
+}
+
+// parseAsyncFunction parses async func, a function literal whose calls run
+// as tasks.
+func (p *V1Parser) parseAsyncFunction() (Node, error) {
+	line := p.curToken.Line
+	if !p.expectPeek(FUNC) {
+		return nil, fmt.Errorf("expected func after async on line: %d", line)
+	}
+	node, err := p.parseFunctionLiteral()
+	if err != nil {
+		return nil, err
+	}
+	node.(*FunctionLiteral).Async = true
+	return node, nil
+}
+
+func (p *V1Parser) parseAwaitExpression() (Node, error) {
+	ae := &AwaitExpression{Line: p.curToken.Line, Column: p.curToken.Column}
+
+	p.nextToken()
+
+	task, err := p.ParseNode(PREFIX)
+	if err != nil {
+		return nil, err
+	}
+	ae.Task = task
+
+	return ae, nil
+}
+
+// parseYieldExpression parses yield, which makes the function it is in a
+// generator. A bare yield gives nil.
+func (p *V1Parser) parseYieldExpression() (Node, error) {
+	ye := &YieldExpression{Line: p.curToken.Line, Column: p.curToken.Column}
+	if len(p.functions) == 0 {
+		return nil, fmt.Errorf("'yield' outside function on line: %d", ye.Line)
+	}
+	p.functions[len(p.functions)-1].Generator = true
+
+	if p.peekTokenIs(RBRACE) || p.peekTokenIs(NEWLINE) || p.peekTokenIs(SEMICOLON) || p.peekTokenIs(EOF) {
+		return ye, nil
+	}
+
+	p.nextToken()
+
+	value, err := p.ParseNode(LOWEST)
+	if err != nil {
+		return nil, err
+	}
+	ye.YieldValue = value
+
+	return ye, nil
+}
+
+func (p *V1Parser) parseGoStatement() (Node, error) {
+	gs := &GoStatement{Line: p.curToken.Line, Column: p.curToken.Column}
+
+	p.nextToken()
+
+	node, err := p.ParseNode(LOWEST)
+	if err != nil {
+		return nil, err
+	}
+	call, ok := node.(*FunctionCall)
+	if !ok {
+		return nil, fmt.Errorf("expression in go must be function call on line: %d", gs.Line)
+	}
+	gs.Call = call
+
+	return gs, nil
+}
+
+// parseSelectStatement parses select. Like Go every case is a channel
+// operation followed by a colon and the statements run when it is chosen:
+//
+//	select {
+//	case msg := ch.recv():
+//		print(msg)
+//	case done.send(true):
+//	case time.after(1).recv():
+//		print("timed out")
+//	default:
+//		print("nothing ready")
+//	}
+func (p *V1Parser) parseSelectStatement() (Node, error) {
+	ss := &SelectStatement{Line: p.curToken.Line, Column: p.curToken.Column}
+
+	if !p.expectPeek(LBRACE) {
+		return nil, fmt.Errorf(SYNTAX_ERROR_MSG, p.curToken.Line)
+	}
+	p.nextToken()
+
+	for !p.curTokenIs(RBRACE) {
+		switch p.curToken.Type {
+		case NEWLINE, SEMICOLON:
+			p.nextToken()
+		case CASE:
+			line := p.curToken.Line
+			p.nextToken()
+			operation, err := p.ParseNode(LOWEST)
+			if err != nil {
+				return nil, err
+			}
+			sc, err := newSelectCase(operation, line)
+			if err != nil {
+				return nil, err
+			}
+			if sc.Body, err = p.parseCaseBody(); err != nil {
+				return nil, err
+			}
+			ss.Cases = append(ss.Cases, sc)
+		case DEFAULT:
+			if ss.Default != nil {
+				return nil, fmt.Errorf("multiple defaults in select on line: %d", p.curToken.Line)
+			}
+			body, err := p.parseCaseBody()
+			if err != nil {
+				return nil, err
+			}
+			ss.Default = body
+		case EOF:
+			return nil, fmt.Errorf("select without closing brace on line: %d", ss.Line)
+		default:
+			return nil, fmt.Errorf("expected case or default in select on line: %d", p.curToken.Line)
+		}
+	}
+
+	return ss, nil
+}
+
+// newSelectCase checks a case is a receive, ch.recv(), optionally assigned
+// to a variable with = or :=, or a send, ch.send(value).
+func newSelectCase(operation Node, line int) (*SelectCase, error) {
+	sc := &SelectCase{Line: line}
+	if assign, ok := operation.(*InfixNode); ok && (assign.Operator == "=" || assign.Operator == ":=") {
+		name, ok := assign.Left.(*IdentifierLiteral)
+		if !ok {
+			return nil, fmt.Errorf("select case can only assign to a variable on line: %d", line)
+		}
+		sc.Name, sc.Assign = name, assign.Operator
+		operation = assign.Right
+	}
+	call, ok := operation.(*FunctionCall)
+	if ok {
+		if method, ok := call.Function.(*AttributeNode); ok {
+			sc.Channel = method.Object
+			switch {
+			case method.Attribute == "recv" && len(call.Arguments) == 0:
+				return sc, nil
+			case method.Attribute == "send" && len(call.Arguments) == 1 && sc.Name == nil:
+				sc.Send = call.Arguments[0]
+				return sc, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("select case must be a channel recv() or send() on line: %d", line)
+}
+
+// parseCaseBody parses the colon after a case or default and the statements
+// that follow up to the next case, default or the end of the select.
+func (p *V1Parser) parseCaseBody() (*BlockStatement, error) {
+	if !p.expectPeek(COLON) {
+		return nil, fmt.Errorf("expected : after %s on line: %d", p.curToken.Value, p.curToken.Line)
+	}
+	block := &BlockStatement{Statements: []Node{}}
+
+	p.nextToken()
+
+	for !p.curTokenIs(CASE) && !p.curTokenIs(DEFAULT) && !p.curTokenIs(RBRACE) && !p.curTokenIs(EOF) {
+		stmt, err := p.ParseNode(LOWEST)
+		if err != nil {
+			return nil, err
+		}
+		if stmt != nil {
+			block.Statements = append(block.Statements, stmt)
+		}
+		p.nextToken()
+	}
+
+	return block, nil
+}
+
+func (p *V1Parser) parseArrayLiteral() (Node, error) {
+	al := &ArrayLiteral{Line: p.curToken.Line, Column: p.curToken.Column}
+
+	// elements may be spread over several lines
+	p.skipNewlines()
+	if p.expectPeek(RBRACKET) {
+		return al, nil
+	}
+	for {
+		p.nextToken()
+		element, err := p.ParseNode(LOWEST)
+		if err != nil {
+			return nil, err
+		}
+		if element == nil {
+			return nil, fmt.Errorf(SYNTAX_ERROR_MSG, p.curToken.Line)
+		}
+		al.Elements = append(al.Elements, element)
+		p.skipNewlines()
+		if !p.expectPeek(COMMA) {
+			break
+		}
+		p.skipNewlines()
+		// allow a trailing comma
+		if p.peekTokenIs(RBRACKET) {
+			break
+		}
+	}
+	if !p.expectPeek(RBRACKET) {
+		return nil, fmt.Errorf(SYNTAX_ERROR_MSG, p.peekToken.Line)
+	}
+
+	return al, nil
+}
+
+func (p *V1Parser) parseMapLiteral() (Node, error) {
+	ml := &MapLiteral{Line: p.curToken.Line, Column: p.curToken.Column}
+
+	p.skipNewlines()
+	if p.expectPeek(RBRACE) {
+		return ml, nil
+	}
+	for {
+		p.nextToken()
+		key, err := p.ParseNode(LOWEST)
+		if err != nil {
+			return nil, err
+		}
+		if key == nil || !p.expectPeek(COLON) {
+			return nil, fmt.Errorf(SYNTAX_ERROR_MSG, p.curToken.Line)
+		}
+		p.nextToken()
+		value, err := p.ParseNode(LOWEST)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			return nil, fmt.Errorf(SYNTAX_ERROR_MSG, p.curToken.Line)
+		}
+		ml.Keys = append(ml.Keys, key)
+		ml.Values = append(ml.Values, value)
+		p.skipNewlines()
+		if !p.expectPeek(COMMA) {
+			break
+		}
+		p.skipNewlines()
+		if p.peekTokenIs(RBRACE) {
+			break
+		}
+	}
+	if !p.expectPeek(RBRACE) {
+		return nil, fmt.Errorf(SYNTAX_ERROR_MSG, p.peekToken.Line)
+	}
+
+	return ml, nil
+}
+
+// parseIndexNode parses object[index] and the slice object[start:end].
+func (p *V1Parser) parseIndexNode(object Node) (Node, error) {
+	line, column := p.curToken.Line, p.curToken.Column
+
+	var start Node
+	if !p.peekTokenIs(COLON) {
+		p.nextToken()
+		index, err := p.ParseNode(LOWEST)
+		if err != nil {
+			return nil, err
+		}
+		start = index
+		if p.expectPeek(RBRACKET) {
+			return &IndexNode{Object: object, Index: index, Line: line, Column: column}, nil
+		}
+	}
+	if !p.expectPeek(COLON) {
+		return nil, fmt.Errorf(SYNTAX_ERROR_MSG, p.peekToken.Line)
+	}
+
+	sn := &SliceNode{Object: object, Start: start, Line: line, Column: column}
+	if !p.peekTokenIs(RBRACKET) {
+		p.nextToken()
+		end, err := p.ParseNode(LOWEST)
+		if err != nil {
+			return nil, err
+		}
+		sn.End = end
+	}
+	if !p.expectPeek(RBRACKET) {
+		return nil, fmt.Errorf(SYNTAX_ERROR_MSG, p.peekToken.Line)
+	}
+
+	return sn, nil
+}
+
+// parseImportStatement parses import "path" and import name from "path".
+func (p *V1Parser) parseImportStatement() (Node, error) {
+	is := &ImportStatement{Line: p.curToken.Line, Column: p.curToken.Column}
+
+	if p.expectPeek(IDENT) {
+		is.Name = p.curToken.Value
+		if !p.expectPeek(IDENT) || p.curToken.Value != "from" {
+			return nil, fmt.Errorf("expected 'from' after import name on line: %d", is.Line)
+		}
+	}
+	if !p.expectPeek(STRING) {
+		return nil, fmt.Errorf("import path must be a string on line: %d", is.Line)
+	}
+	is.Path = p.curToken.Value
+
+	return is, nil
+}
+
+func (p *V1Parser) parseTryStatement() (Node, error) {
+	ts := &TryStatement{Line: p.curToken.Line, Column: p.curToken.Column}
+
+	if !p.expectPeek(LBRACE) {
+		return nil, fmt.Errorf(SYNTAX_ERROR_MSG, p.curToken.Line)
+	}
+	body, err := p.parseBlockStatement()
+	if err != nil {
+		return nil, err
+	}
+	ts.Body = body
+
+	if p.peekTokenIs(CATCH) {
+		p.nextToken()
+		if p.peekTokenIs(IDENT) {
+			p.nextToken()
+			ts.CatchName = NewIdentifierLiteral(p.curToken.Value, p.curToken.Line, p.curToken.Column)
+		}
+		if !p.expectPeek(LBRACE) {
+			return nil, fmt.Errorf(SYNTAX_ERROR_MSG, p.curToken.Line)
+		}
+		block, err := p.parseBlockStatement()
+		if err != nil {
+			return nil, err
+		}
+		ts.Catch = block
+	}
+
+	if p.peekTokenIs(FINALLY) {
+		p.nextToken()
+		if !p.expectPeek(LBRACE) {
+			return nil, fmt.Errorf(SYNTAX_ERROR_MSG, p.curToken.Line)
+		}
+		block, err := p.parseBlockStatement()
+		if err != nil {
+			return nil, err
+		}
+		ts.Finally = block
+	}
+
+	if ts.Catch == nil && ts.Finally == nil {
+		return nil, fmt.Errorf("try without catch or finally on line: %d", ts.Line)
+	}
+
+	return ts, nil
+}
+
+func (p *V1Parser) parseAttributeNode(left Node) (Node, error) {
+	an := &AttributeNode{Object: left, Line: p.curToken.Line, Column: p.curToken.Column}
+
+	if !p.expectPeek(IDENT) {
+		return nil, fmt.Errorf(SYNTAX_ERROR_MSG, p.curToken.Line)
+	}
+	an.Attribute = p.curToken.Value
+
+	return an, nil
+}
+
 func (p *V1Parser) parseIfStatement() (Node, error) {
 
 	p.nextToken()
@@ -508,7 +993,90 @@ func (p *V1Parser) parseForStatement() (Node, error) {
 
 	forExp := &ForNode{}
 
-	components := []Node{}
+	if p.peekTokenIs(IDENT) {
+		p.nextToken()
+		first, err := p.ParseNode(LOWEST)
+		if err != nil {
+			return nil, err
+		}
+		if rn, ok, err := p.parseRangeClause(first); ok || err != nil {
+			return rn, err
+		}
+		// not a range loop, carry on with the first component
+		p.nextToken()
+		return p.parseForComponents(forExp, []Node{first})
+	}
+
+	return p.parseForComponents(forExp, []Node{})
+}
+
+// parseRangeClause finishes parsing for key := range x { } and
+// for key, value := range x { } once the first expression after for has been
+// parsed, ok is false if it turns out to be an ordinary for loop.
+func (p *V1Parser) parseRangeClause(first Node) (Node, bool, error) {
+	var rn *RangeNode
+	switch first := first.(type) {
+	case *InfixNode:
+		iterable, isRange := first.Right.(*RangeNode)
+		key, isIdent := first.Left.(*IdentifierLiteral)
+		if !isRange || !isIdent || first.Operator != ":=" {
+			return nil, false, nil
+		}
+		rn = iterable
+		rn.Key = key
+	case *IdentifierLiteral:
+		if !p.peekTokenIs(COMMA) {
+			return nil, false, nil
+		}
+		p.nextToken()
+		if !p.expectPeek(IDENT) {
+			return nil, true, fmt.Errorf(SYNTAX_ERROR_MSG, p.curToken.Line)
+		}
+		value := NewIdentifierLiteral(p.curToken.Value, p.curToken.Line, p.curToken.Column)
+		if !p.expectPeek(ASSIGN_INF) || !p.expectPeek(RANGE) {
+			return nil, true, fmt.Errorf("expected := range on line: %d", p.curToken.Line)
+		}
+		node, err := p.parseRangeExpression()
+		if err != nil {
+			return nil, true, err
+		}
+		rn = node.(*RangeNode)
+		rn.Key = first
+		rn.Element = value
+	default:
+		return nil, false, nil
+	}
+
+	if !p.expectPeek(LBRACE) {
+		return nil, true, fmt.Errorf(SYNTAX_ERROR_MSG, p.curToken.Line)
+	}
+	block, err := p.parseBlockStatement()
+	if err != nil {
+		return nil, true, err
+	}
+	rn.Body = block
+
+	return rn, true, nil
+}
+
+// parseRangeExpression parses range x, which is only valid in a for loop.
+func (p *V1Parser) parseRangeExpression() (Node, error) {
+	rn := &RangeNode{Line: p.curToken.Line, Column: p.curToken.Column}
+
+	p.nextToken()
+	iterable, err := p.ParseNode(LOWEST)
+	if err != nil {
+		return nil, err
+	}
+	if iterable == nil {
+		return nil, fmt.Errorf(SYNTAX_ERROR_MSG, rn.Line)
+	}
+	rn.Iterable = iterable
+
+	return rn, nil
+}
+
+func (p *V1Parser) parseForComponents(forExp *ForNode, components []Node) (Node, error) {
 
 	for !p.curTokenIs(LBRACE) && len(components) <= 3 {
 		p.nextToken()
@@ -546,7 +1114,7 @@ func (p *V1Parser) parseForStatement() (Node, error) {
 }
 
 func (p *V1Parser) parseSuffixNode(left Node) (Node, error) {
-	
+
 	Node := &SufixNode{
 		Left:     left,
 		Operator: p.curToken.Value,
@@ -599,6 +1167,12 @@ func (p *V1Parser) curTokenIs(t TokenType) bool {
 	return p.curToken.Type == t
 }
 
+func (p *V1Parser) skipNewlines() {
+	for p.peekTokenIs(NEWLINE) {
+		p.nextToken()
+	}
+}
+
 func (p *V1Parser) expectPeek(t TokenType) bool {
 	if p.peekTokenIs(t) {
 		p.nextToken()
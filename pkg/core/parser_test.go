@@ -2,6 +2,8 @@ package core
 
 import (
 	"fmt"
+	"math"
+	"math/big"
 	"reflect"
 	"testing"
 )
@@ -17,7 +19,7 @@ func TestParser(t *testing.T) {
 			input: "i++",
 			expected: []Node{
 				&SufixNode{
-					Left:     &IdentifierLiteral{value: "i"},
+					Left:     &IdentifierLiteral{value: "i", Line: 1, Column: 1},
 					Operator: "++",
 				},
 			},
@@ -27,7 +29,7 @@ func TestParser(t *testing.T) {
 			input: "i--",
 			expected: []Node{
 				&SufixNode{
-					Left:     &IdentifierLiteral{value: "i"},
+					Left:     &IdentifierLiteral{value: "i", Line: 1, Column: 1},
 					Operator: "--",
 				},
 			},
@@ -37,57 +39,57 @@ func TestParser(t *testing.T) {
 			input: "i > 10",
 			expected: []Node{
 				&InfixNode{
-					Left:     &IdentifierLiteral{value: "i"},
+					Left:     &IdentifierLiteral{value: "i", Line: 1, Column: 1},
 					Operator: ">",
 					Right:    &Integer{value: 10},
 				},
 			},
 		},
 		{
-			name: "test for loop increment",
+			name:  "test for loop increment",
 			input: "for i = 0 ; i < 10; i++ {}",
 			expected: []Node{
 				&ForNode{
 					Initialisation: &InfixNode{
-						Left:     &IdentifierLiteral{value: "i"},
+						Left:     &IdentifierLiteral{value: "i", Line: 1, Column: 5},
 						Operator: "=",
 						Right:    &Integer{value: 0},
 					},
 					Condition: &InfixNode{
-						Left:     &IdentifierLiteral{value: "i"},
+						Left:     &IdentifierLiteral{value: "i", Line: 1, Column: 13},
 						Operator: "<",
 						Right:    &Integer{value: 10},
 					},
 					Updater: &SufixNode{
-						Left:     &IdentifierLiteral{value: "i"},
+						Left:     &IdentifierLiteral{value: "i", Line: 1, Column: 21},
 						Operator: "++",
 					},
 					Body: &BlockStatement{Statements: []Node{}},
 				},
-			},	
+			},
 		},
 		{
-			name: "test for loop decrement",
+			name:  "test for loop decrement",
 			input: "for i = 10 ; i > 10; i-- {}",
 			expected: []Node{
 				&ForNode{
 					Initialisation: &InfixNode{
-						Left:     &IdentifierLiteral{value: "i"},
+						Left:     &IdentifierLiteral{value: "i", Line: 1, Column: 5},
 						Operator: "=",
 						Right:    &Integer{value: 10},
 					},
 					Condition: &InfixNode{
-						Left:     &IdentifierLiteral{value: "i"},
+						Left:     &IdentifierLiteral{value: "i", Line: 1, Column: 14},
 						Operator: ">",
 						Right:    &Integer{value: 10},
 					},
 					Updater: &SufixNode{
-						Left:     &IdentifierLiteral{value: "i"},
+						Left:     &IdentifierLiteral{value: "i", Line: 1, Column: 22},
 						Operator: "--",
 					},
 					Body: &BlockStatement{Statements: []Node{}},
 				},
-			},	
+			},
 		},
 	}
 
@@ -105,3 +107,59 @@ func TestParser(t *testing.T) {
 		}
 	}
 }
+
+func TestParserNumericLiterals(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected Node
+		err      string
+	}{
+		{name: "hexadecimal", input: "0xFF", expected: &Integer{value: 255}},
+		{name: "octal", input: "0o755", expected: &Integer{value: 493}},
+		{name: "binary", input: "0b1010", expected: &Integer{value: 10}},
+		{name: "underscores", input: "1_000_000", expected: &Integer{value: 1000000}},
+		{name: "negative", input: "-0x10", expected: &Integer{value: -16}},
+		{name: "min int64", input: "-9223372036854775808", expected: &Integer{value: math.MinInt64}},
+		{name: "exponent", input: "1e9", expected: &Float{value: 1e9}},
+		{name: "leading dot", input: ".5", expected: &Float{value: 0.5}},
+		{name: "float underscores", input: "1_000.000_5", expected: &Float{value: 1000.0005}},
+		{
+			name:  "malformed literal",
+			input: "x = 1.2.3",
+			err:   `Malformed number literal "1.2.3": unexpected '.' after number on line: 1, column: 5`,
+		},
+		{name: "bigint suffix", input: "0xFFn", expected: &BigInt{value: big.NewInt(255)}},
+		{name: "integer overflow promotes", input: "9223372036854775808", expected: &BigInt{value: new(big.Int).Lsh(big.NewInt(1), 63)}},
+		{
+			name:  "float with bigint suffix",
+			input: "1.5n",
+			err:   `Malformed number literal "1.5n": a float literal cannot have the bigint suffix on line: 1, column: 1`,
+		},
+		{
+			name:  "float out of range",
+			input: "1e400",
+			err:   "float literal 1e400 is out of range on line: 1, column: 1",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			parser := NewV1Parser(NewV1Lexer(test.input), false)
+			if test.err != "" {
+				_, err := parser.ParseProgram()
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			node, err := parser.ParseNode(LOWEST)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(node, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, node)
+			}
+		})
+	}
+}
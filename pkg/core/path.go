@@ -0,0 +1,56 @@
+package core
+
+import (
+	"path/filepath"
+)
+
+// pathModule builds the native path module, a thin layer over path/filepath.
+func pathModule(e *Evaluator) map[string]Object {
+	functions := map[string]func([]Object) (Object, error){
+		"join":  pathJoin,
+		"base":  pathUnary("base", filepath.Base),
+		"dir":   pathUnary("dir", filepath.Dir),
+		"ext":   pathUnary("ext", filepath.Ext),
+		"clean": pathUnary("clean", filepath.Clean),
+		"abs":   pathAbs,
+	}
+	module := map[string]Object{}
+	for name, fn := range functions {
+		module[name] = &GoFunction{Name: name, Func: fn}
+	}
+	return module
+}
+
+func pathJoin(args []Object) (Object, error) {
+	elements := make([]string, len(args))
+	for i := range args {
+		element, err := stringArg("join", args, i)
+		if err != nil {
+			return &Nil{}, err
+		}
+		elements[i] = element
+	}
+	return &String{value: filepath.Join(elements...)}, nil
+}
+
+func pathUnary(name string, fn func(string) string) func([]Object) (Object, error) {
+	return func(args []Object) (Object, error) {
+		values, err := stringArgs(name, args, 1, 1, 1)
+		if err != nil {
+			return &Nil{}, err
+		}
+		return &String{value: fn(values[0])}, nil
+	}
+}
+
+func pathAbs(args []Object) (Object, error) {
+	values, err := stringArgs("abs", args, 1, 1, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	path, err := filepath.Abs(values[0])
+	if err != nil {
+		return &Nil{}, newOSError(err)
+	}
+	return &String{value: path}, nil
+}
@@ -0,0 +1,255 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Policy decides what the standard library may touch on behalf of a
+// program, for embedding hosts that run code they do not trust. A function
+// the policy does not allow raises a PermissionError instead of running.
+// The zero Policy denies everything, evaluators start with AllowAll.
+// Imported .gs modules in the script's directory or the search path are read
+// whatever the policy, others only when it allows the fs module to read them.
+type Policy struct {
+	// FileSystem allows the fs module
+	FileSystem bool
+	// Paths, when not empty, limits the fs module to these files and
+	// directories and what is below them, after following symbolic links
+	Paths []string
+	// Environment allows reading and changing environment variables and
+	// the working directory
+	Environment bool
+	// Exec allows running other processes
+	Exec bool
+	// Network allows making HTTP requests and serving HTTP
+	Network bool
+	// Clock allows reading the clock, sleeping and waiting on timers
+	Clock bool
+}
+
+// AllowAll returns the policy that allows everything.
+func AllowAll() Policy {
+	return Policy{FileSystem: true, Environment: true, Exec: true, Network: true, Clock: true}
+}
+
+// SetPolicy sets what the standard library may touch from now on, including
+// the modules already imported.
+func (e *Evaluator) SetPolicy(policy Policy) {
+	e.policy = policy
+}
+
+// permission is what a standard library function needs the policy to allow.
+type permission int
+
+const (
+	fileSystemAccess permission = iota
+	environmentAccess
+	execAccess
+	networkAccess
+	clockAccess
+)
+
+var permissionNames = map[permission]string{
+	fileSystemAccess:  "file system",
+	environmentAccess: "environment",
+	execAccess:        "process execution",
+	networkAccess:     "network",
+	clockAccess:       "clock",
+}
+
+func (p Policy) allows(perm permission) bool {
+	switch perm {
+	case fileSystemAccess:
+		return p.FileSystem
+	case environmentAccess:
+		return p.Environment
+	case execAccess:
+		return p.Exec
+	case networkAccess:
+		return p.Network
+	case clockAccess:
+		return p.Clock
+	}
+	return false
+}
+
+// allowsPath reports whether path is one of Paths or below one of them.
+func (p Policy) allowsPath(path string) bool {
+	return len(p.Paths) == 0 || within(path, p.Paths)
+}
+
+// within reports whether path is one of dirs or below one of them.
+func within(path string, dirs []string) bool {
+	resolved, err := resolvePath(path)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range dirs {
+		base, err := resolvePath(allowed)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(base, resolved)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePath makes path absolute and follows the symbolic links of the
+// longest part of it that exists, so a link cannot lead out of a directory.
+func resolvePath(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	rest := ""
+	for {
+		if _, err := os.Lstat(path); err == nil {
+			resolved, err := filepath.EvalSymlinks(path)
+			if err != nil {
+				return "", err
+			}
+			return filepath.Join(resolved, rest), nil
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return filepath.Join(path, rest), nil
+		}
+		rest = filepath.Join(filepath.Base(path), rest)
+		path = parent
+	}
+}
+
+// permit returns the error raised when the policy does not allow function
+// the access it needs.
+func (e *Evaluator) permit(perm permission, function string) error {
+	if !e.policy.allows(perm) {
+		return newRuntimeError(PERMISSION_ERROR, "permission denied: %s needs %s access", function, permissionNames[perm])
+	}
+	return nil
+}
+
+// permitPath is permit for a function of the fs module reaching path.
+func (e *Evaluator) permitPath(function, path string) error {
+	if err := e.permit(fileSystemAccess, function); err != nil {
+		return err
+	}
+	if !e.policy.allowsPath(path) {
+		return newRuntimeError(PERMISSION_ERROR, "permission denied: %s cannot access %q", function, path)
+	}
+	return nil
+}
+
+// permitModule is permitPath for the file of a module about to be imported.
+// The script's directory and the search path are trusted, modules anywhere
+// else are only read when the policy allows reading them.
+func (e *Evaluator) permitModule(filename string) error {
+	err := e.permitPath("import", filename)
+	if err == nil || within(filename, append([]string{e.scriptDir}, e.searchPath...)) {
+		return nil
+	}
+	return err
+}
+
+// modulePermissions is what the functions of the native modules need the
+// policy to allow, by module and function. Functions not listed need
+// nothing.
+var modulePermissions = map[string]map[string]permission{
+	"os": {
+		"env":        environmentAccess,
+		"setenv":     environmentAccess,
+		"unsetenv":   environmentAccess,
+		"cwd":        environmentAccess,
+		"exec":       execAccess,
+		"execStream": execAccess,
+	},
+	"path": {
+		// abs joins relative paths to the working directory, revealing it
+		"abs": environmentAccess,
+	},
+	"http": {
+		"get":     networkAccess,
+		"post":    networkAccess,
+		"request": networkAccess,
+		"serve":   networkAccess,
+	},
+	"time": {
+		"now":       clockAccess,
+		"monotonic": clockAccess,
+		"since":     clockAccess,
+		"sleep":     clockAccess,
+		"after":     clockAccess,
+	},
+}
+
+// fsPathArgs is which arguments of the fs functions are paths. Every fs
+// function needs file system access.
+var fsPathArgs = map[string][]int{
+	"readFile":   {0},
+	"writeFile":  {0},
+	"appendFile": {0},
+	"exists":     {0},
+	"stat":       {0},
+	"listDir":    {0},
+	"mkdirAll":   {0},
+	"remove":     {0},
+	"rename":     {0, 1},
+	"glob":       {0},
+	"open":       {0},
+	"walk":       {0},
+}
+
+// guardModule makes the functions of the native module path check the
+// policy every time they are called, before they run.
+func guardModule(path string, module map[string]Object) map[string]Object {
+	for name, value := range module {
+		fn, ok := value.(*GoFunction)
+		if !ok {
+			continue
+		}
+		function := path + "." + name
+		if path == "fs" {
+			args := fsPathArgs[name]
+			module[name] = guarded(fn, func(e *Evaluator, values []Object) error {
+				if err := e.permit(fileSystemAccess, function); err != nil {
+					return err
+				}
+				for _, i := range args {
+					if i >= len(values) {
+						continue
+					}
+					// arguments of the wrong type are left for the function to report
+					if value, ok := values[i].(*String); ok {
+						if err := e.permitPath(function, value.value); err != nil {
+							return err
+						}
+					}
+				}
+				return nil
+			})
+		} else if perm, ok := modulePermissions[path][name]; ok {
+			module[name] = guarded(fn, func(e *Evaluator, values []Object) error {
+				return e.permit(perm, function)
+			})
+		}
+	}
+	return module
+}
+
+// guarded returns fn calling check first and failing with its error.
+func guarded(fn *GoFunction, check func(e *Evaluator, args []Object) error) *GoFunction {
+	call, evalCall := fn.Func, fn.EvalFunc
+	return &GoFunction{Name: fn.Name, EvalFunc: func(e *Evaluator, args []Object) (Object, error) {
+		if err := check(e, args); err != nil {
+			return &Nil{}, err
+		}
+		if evalCall != nil {
+			return evalCall(e, args)
+		}
+		return call(args)
+	}}
+}
@@ -0,0 +1,293 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPolicy(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		policy   Policy
+		expected Object
+		err      string
+	}{
+		{
+			name:   "test file system denied",
+			input:  "import \"fs\"\nfs.readFile(dir + \"/inside.txt\")",
+			policy: Policy{},
+			err:    "permission denied: fs.readFile needs file system access",
+		},
+		{
+			name:     "test path allowed",
+			input:    "import \"fs\"\nfs.readFile(dir + \"/inside.txt\")",
+			policy:   Policy{FileSystem: true, Paths: []string{"DIR"}},
+			expected: &String{value: "inside"},
+		},
+		{
+			name:   "test path outside allowed paths",
+			input:  "import \"fs\"\nfs.readFile(dir + \"/../outside.txt\")",
+			policy: Policy{FileSystem: true, Paths: []string{"DIR"}},
+			err:    "permission denied: fs.readFile cannot access \"DIR/../outside.txt\"",
+		},
+		{
+			name:   "test symbolic link out of allowed paths",
+			input:  "import \"fs\"\nfs.readFile(dir + \"/link.txt\")",
+			policy: Policy{FileSystem: true, Paths: []string{"DIR"}},
+			err:    "permission denied: fs.readFile cannot access \"DIR/link.txt\"",
+		},
+		{
+			name:   "test second path of rename checked",
+			input:  "import \"fs\"\nfs.rename(dir + \"/inside.txt\", dir + \"/../moved.txt\")",
+			policy: Policy{FileSystem: true, Paths: []string{"DIR"}},
+			err:    "permission denied: fs.rename cannot access \"DIR/../moved.txt\"",
+		},
+		{
+			name:   "test glob out of allowed paths",
+			input:  "import \"fs\"\nfs.glob(dir + \"/*/../../*\")",
+			policy: Policy{FileSystem: true, Paths: []string{"DIR"}},
+			err:    "permission denied: fs.glob cannot access \"DIR/*/../../*\"",
+		},
+		{
+			name:     "test glob leaves out matches through symbolic links",
+			input:    "import \"fs\"\n[length(fs.glob(dir + \"/linkdi?/*\")), length(fs.glob(dir + \"/*.txt\"))]",
+			policy:   Policy{FileSystem: true, Paths: []string{"DIR"}},
+			expected: &Array{Elements: []Object{&Integer{value: 0}, &Integer{value: 1}}},
+		},
+		{
+			name:   "test environment denied",
+			input:  "import \"os\"\nos.env(\"HOME\")",
+			policy: Policy{},
+			err:    "permission denied: os.env needs environment access",
+		},
+		{
+			name:   "test absolute path denied",
+			input:  "import \"path\"\npath.abs(\".\")",
+			policy: Policy{FileSystem: true},
+			err:    "permission denied: path.abs needs environment access",
+		},
+		{
+			name:     "test path functions not needing the environment",
+			input:    "import \"path\"\npath.join(\"a\", \"b\")",
+			policy:   Policy{},
+			expected: &String{value: "a/b"},
+		},
+		{
+			name:   "test process execution denied",
+			input:  "import \"os\"\nos.exec(\"echo\", [\"hi\"])",
+			policy: Policy{Environment: true},
+			err:    "permission denied: os.exec needs process execution access",
+		},
+		{
+			name:   "test network denied",
+			input:  "import \"http\"\nhttp.get(\"http://127.0.0.1:1\")",
+			policy: Policy{},
+			err:    "permission denied: http.get needs network access",
+		},
+		{
+			name:   "test clock denied",
+			input:  "import \"time\"\ntime.now()",
+			policy: Policy{},
+			err:    "permission denied: time.now needs clock access",
+		},
+		{
+			name:   "test sleep denied",
+			input:  "import \"time\"\ntime.sleep(0)",
+			policy: Policy{},
+			err:    "permission denied: time.sleep needs clock access",
+		},
+		{
+			name:     "test functions needing nothing allowed",
+			input:    "import \"time\"\nimport \"os\"\nimport \"path\"\n[time.fromUnix(0).utc().format(\"2006\"), length(os.args), path.base(\"a/b\")]",
+			policy:   Policy{},
+			expected: &Array{Elements: []Object{&String{value: "1970"}, &Integer{value: 0}, &String{value: "b"}}},
+		},
+		{
+			name:     "test permission error is catchable",
+			input:    "import \"os\"\nx = \"\"\ntry {\n os.setenv(\"A\", \"1\")\n} catch e {\n x = e.type\n}\nx",
+			policy:   Policy{},
+			expected: &String{value: PERMISSION_ERROR},
+		},
+	}
+
+	for _, backend := range backends {
+		for _, test := range cases {
+			t.Run(backend.name+"/"+test.name, func(t *testing.T) {
+				dir := t.TempDir()
+				if err := os.WriteFile(filepath.Join(dir, "inside.txt"), []byte("inside"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				outside := t.TempDir()
+				if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(dir, "link.txt")); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Symlink(outside, filepath.Join(dir, "linkdir")); err != nil {
+					t.Fatal(err)
+				}
+
+				policy := test.policy
+				policy.Paths = nil
+				for _, path := range test.policy.Paths {
+					policy.Paths = append(policy.Paths, strings.ReplaceAll(path, "DIR", dir))
+				}
+				evaluator := NewEvaluator(false)
+				evaluator.SetVM(backend.vm)
+				evaluator.SetPolicy(policy)
+				result, err := evalWith(evaluator, fmt.Sprintf("dir = %q\n%s", dir, test.input))
+				if test.err != "" {
+					// DIR stands for the test's directory in expected messages
+					expected := strings.ReplaceAll(test.err, "DIR", dir)
+					if err == nil || err.Error() != expected {
+						t.Fatalf("expected error %q, got %v", expected, err)
+					}
+					if toRuntimeError(err).ErrorType != PERMISSION_ERROR {
+						t.Fatalf("expected %s, got %s", PERMISSION_ERROR, toRuntimeError(err).ErrorType)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !reflect.DeepEqual(result, test.expected) {
+					t.Fatalf("expected %v, got %v", test.expected, result)
+				}
+			})
+		}
+	}
+}
+
+func TestSetPolicyAfterImport(t *testing.T) {
+	evaluator := NewEvaluator(false)
+	if _, err := evalWith(evaluator, "import \"time\"\nt = time.now()"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	evaluator.SetPolicy(Policy{})
+	if _, err := evalWith(evaluator, "time.now()"); err == nil || toRuntimeError(err).ErrorType != PERMISSION_ERROR {
+		t.Fatalf("expected %s, got %v", PERMISSION_ERROR, err)
+	}
+}
+
+// evalWith evaluates input a statement at a time with evaluator, giving the
+// value of the last statement.
+func evalWith(evaluator *Evaluator, input string) (Object, error) {
+	program, err := NewV1Parser(NewV1Lexer(input), false).ParseProgram()
+	if err != nil {
+		return nil, err
+	}
+	var result Object = &Nil{}
+	for _, stmt := range program.(*BlockStatement).Statements {
+		result, err = evaluator.Evaluate(stmt)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func TestPolicyImports(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		policy   Policy
+		expected Object
+		err      string
+	}{
+		{
+			name:     "test module in the script's directory",
+			input:    "import local from \"./local.gs\"\nlocal.Value",
+			policy:   Policy{},
+			expected: &String{value: "local"},
+		},
+		{
+			name:     "test module in the search path",
+			input:    "import \"shared\"\nshared.Value",
+			policy:   Policy{},
+			expected: &String{value: "shared"},
+		},
+		{
+			name:   "test absolute import denied",
+			input:  "import secret from \"ROOT/other/secret.gs\"\nsecret.Value",
+			policy: Policy{},
+			err:    "permission denied: import needs file system access",
+		},
+		{
+			name:   "test relative import out of the script's directory denied",
+			input:  "import secret from \"../other/secret.gs\"\nsecret.Value",
+			policy: Policy{},
+			err:    "permission denied: import needs file system access",
+		},
+		{
+			name:   "test absolute import of a missing module denied",
+			input:  "import missing from \"ROOT/other/missing.gs\"",
+			policy: Policy{},
+			err:    "permission denied: import needs file system access",
+		},
+		{
+			name:   "test import out of allowed paths",
+			input:  "import secret from \"ROOT/other/secret.gs\"\nsecret.Value",
+			policy: Policy{FileSystem: true, Paths: []string{"ROOT/app"}},
+			err:    "permission denied: import cannot access \"ROOT/other/secret.gs\"",
+		},
+		{
+			name:     "test import from allowed paths",
+			input:    "import secret from \"ROOT/other/secret.gs\"\nsecret.Value",
+			policy:   Policy{FileSystem: true, Paths: []string{"ROOT/other"}},
+			expected: &String{value: "secret"},
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			files := map[string]string{
+				"app/local.gs":    "Value = \"local\"",
+				"lib/shared.gs":   "Value = \"shared\"",
+				"other/secret.gs": "Value = \"secret\"",
+			}
+			for name, source := range files {
+				path := filepath.Join(root, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// ROOT stands for the test's directory
+			policy := test.policy
+			policy.Paths = nil
+			for _, path := range test.policy.Paths {
+				policy.Paths = append(policy.Paths, strings.ReplaceAll(path, "ROOT", root))
+			}
+			evaluator := NewEvaluator(false)
+			evaluator.SetSearchPath([]string{filepath.Join(root, "lib")})
+			if err := evaluator.SetFilename(filepath.Join(root, "app", "main.gs")); err != nil {
+				t.Fatal(err)
+			}
+			evaluator.SetPolicy(policy)
+			result, err := evalWith(evaluator, strings.ReplaceAll(test.input, "ROOT", root))
+			if test.err != "" {
+				expected := strings.ReplaceAll(test.err, "ROOT", root)
+				if err == nil || err.Error() != expected {
+					t.Fatalf("expected error %q, got %v", expected, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package core
+
+import (
+	"container/list"
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// reModule builds the native re module. Every function takes a compiled
+// regexp or a pattern string as its first argument, and the same functions
+// are the methods of Regexp.
+func reModule(e *Evaluator) map[string]Object {
+	module := map[string]Object{
+		"compile": &GoFunction{Name: "compile", Func: reCompile},
+	}
+	for name, method := range e.methods["regexp"] {
+		module[name] = method
+	}
+	return module
+}
+
+// regexpMethods is the method table of Regexp.
+func regexpMethods() map[string]*GoFunction {
+	return map[string]*GoFunction{
+		"match":       {Name: "match", Func: reMatch},
+		"find":        {Name: "find", Func: reFind},
+		"findAll":     {Name: "findAll", Func: reFindAll},
+		"submatch":    {Name: "submatch", Func: reSubmatch},
+		"submatchAll": {Name: "submatchAll", Func: reSubmatchAll},
+		"groups":      {Name: "groups", Func: reGroups},
+		"replace":     {Name: "replace", EvalFunc: (*Evaluator).reReplace},
+		"split":       {Name: "split", Func: reSplit},
+	}
+}
+
+// Regexp is a compiled regular expression. Compiling a pattern compiled
+// recently gives back the same Regexp.
+type Regexp struct {
+	nativeObject
+	regexp *regexp.Regexp
+}
+
+// REGEXP_CACHE_SIZE is the number of patterns regexpCache keeps compiled.
+const REGEXP_CACHE_SIZE = 128
+
+// regexpCache holds the Regexps compiled last keyed by their pattern, it is
+// shared by all evaluators. order lists the patterns most recently used
+// first, the least recently used is dropped once there are too many.
+var regexpCache = struct {
+	sync.Mutex
+	regexps map[string]*list.Element
+	order   *list.List
+}{regexps: map[string]*list.Element{}, order: list.New()}
+
+func compileRegexp(pattern string) (*Regexp, error) {
+	regexpCache.Lock()
+	defer regexpCache.Unlock()
+	if element, ok := regexpCache.regexps[pattern]; ok {
+		regexpCache.order.MoveToFront(element)
+		return element.Value.(*Regexp), nil
+	}
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, newRuntimeError(VALUE_ERROR, "%s", err.Error())
+	}
+	re := &Regexp{nativeObject: nativeObject{"regexp"}, regexp: compiled}
+	regexpCache.regexps[pattern] = regexpCache.order.PushFront(re)
+	if regexpCache.order.Len() > REGEXP_CACHE_SIZE {
+		oldest := regexpCache.order.Back()
+		regexpCache.order.Remove(oldest)
+		delete(regexpCache.regexps, oldest.Value.(*Regexp).regexp.String())
+	}
+	return re, nil
+}
+
+// regexpArgs checks the arguments of a re function, the first is a Regexp
+// or a pattern to compile and the following count are strings.
+func regexpArgs(name string, args []Object, min, max, count int) (*Regexp, []string, error) {
+	if err := checkArgs(name, args, min, max); err != nil {
+		return nil, nil, err
+	}
+	var re *Regexp
+	switch arg := args[0].(type) {
+	case *Regexp:
+		re = arg
+	case *String:
+		compiled, err := compileRegexp(arg.value)
+		if err != nil {
+			return nil, nil, err
+		}
+		re = compiled
+	default:
+		return nil, nil, newTypeError("%s() expects a regexp or string, got %s", name, arg.Type())
+	}
+	values := make([]string, count)
+	for i := range values {
+		value, err := stringArg(name, args, i+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		values[i] = value
+	}
+	return re, values, nil
+}
+
+// limitArg reads the optional limit on the number of results, -1 for all.
+func limitArg(name string, args []Object, i int) (int, error) {
+	if len(args) <= i {
+		return -1, nil
+	}
+	return intArg(name, args, i)
+}
+
+func reCompile(args []Object) (Object, error) {
+	values, err := stringArgs("compile", args, 1, 1, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	re, err := compileRegexp(values[0])
+	if err != nil {
+		return &Nil{}, err
+	}
+	return re, nil
+}
+
+func reMatch(args []Object) (Object, error) {
+	re, values, err := regexpArgs("match", args, 2, 2, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	return &Boolean{value: re.regexp.MatchString(values[0])}, nil
+}
+
+// reFind returns the leftmost match or nil.
+func reFind(args []Object) (Object, error) {
+	re, values, err := regexpArgs("find", args, 2, 2, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	location := re.regexp.FindStringIndex(values[0])
+	if location == nil {
+		return &Nil{}, nil
+	}
+	return &String{value: values[0][location[0]:location[1]]}, nil
+}
+
+// reFindAll is findAll(re, s) or findAll(re, s, n) returning at most n
+// matches.
+func reFindAll(args []Object) (Object, error) {
+	re, values, err := regexpArgs("findAll", args, 2, 3, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	n, err := limitArg("findAll", args, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	return newStringArray(re.regexp.FindAllString(values[0], n)), nil
+}
+
+// submatchArray holds the whole match followed by each group, groups that
+// did not take part in the match are nil.
+func submatchArray(s string, location []int) *Array {
+	elements := make([]Object, len(location)/2)
+	for i := range elements {
+		if location[2*i] < 0 {
+			elements[i] = &Nil{}
+			continue
+		}
+		elements[i] = &String{value: s[location[2*i]:location[2*i+1]]}
+	}
+	return &Array{Elements: elements}
+}
+
+// reSubmatch returns the leftmost match and its groups as an array, or nil.
+func reSubmatch(args []Object) (Object, error) {
+	re, values, err := regexpArgs("submatch", args, 2, 2, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	location := re.regexp.FindStringSubmatchIndex(values[0])
+	if location == nil {
+		return &Nil{}, nil
+	}
+	return submatchArray(values[0], location), nil
+}
+
+func reSubmatchAll(args []Object) (Object, error) {
+	re, values, err := regexpArgs("submatchAll", args, 2, 3, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	n, err := limitArg("submatchAll", args, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	matches := &Array{Elements: []Object{}}
+	for _, location := range re.regexp.FindAllStringSubmatchIndex(values[0], n) {
+		matches.Elements = append(matches.Elements, submatchArray(values[0], location))
+	}
+	return matches, nil
+}
+
+// reGroups returns the named groups of the leftmost match as a map, or nil
+// when there is no match.
+func reGroups(args []Object) (Object, error) {
+	re, values, err := regexpArgs("groups", args, 2, 2, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	location := re.regexp.FindStringSubmatchIndex(values[0])
+	if location == nil {
+		return &Nil{}, nil
+	}
+	groups := submatchArray(values[0], location)
+	m := NewMap()
+	for i, name := range re.regexp.SubexpNames() {
+		if name != "" {
+			m.Set(name, groups.Elements[i])
+		}
+	}
+	return m, nil
+}
+
+// reReplace is replace(re, s, replacement). A string replacement may refer
+// to groups with $1 or ${name}, a function is called with each match and
+// returns the string to put in its place.
+func (e *Evaluator) reReplace(args []Object) (Object, error) {
+	re, values, err := regexpArgs("replace", args, 3, 3, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	if replacement, ok := args[2].(*String); ok {
+		return &String{value: re.regexp.ReplaceAllString(values[0], replacement.value)}, nil
+	}
+
+	var callErr error
+	result := re.regexp.ReplaceAllStringFunc(values[0], func(match string) string {
+		if callErr != nil {
+			return match
+		}
+		value, err := e.callObject(args[2], []Object{&String{value: match}}, 0)
+		if err != nil {
+			callErr = err
+			return match
+		}
+		str, ok := value.(*String)
+		if !ok {
+			callErr = newTypeError("replace() function must return a string, got %s", value.Type())
+			return match
+		}
+		return str.value
+	})
+	if callErr != nil {
+		return &Nil{}, callErr
+	}
+	return &String{value: result}, nil
+}
+
+// reSplit is split(re, s) or split(re, s, n) returning at most n parts.
+func reSplit(args []Object) (Object, error) {
+	re, values, err := regexpArgs("split", args, 2, 3, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	n, err := limitArg("split", args, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	return newStringArray(re.regexp.Split(values[0], n)), nil
+}
+
+func (r *Regexp) GetAttribute(name string) (Object, bool) {
+	if name == "pattern" {
+		return &String{value: r.regexp.String()}, true
+	}
+	return nil, false
+}
+
+func (r *Regexp) Type() string {
+	return "regexp"
+}
+
+func (r *Regexp) Value() interface{} {
+	return r.regexp
+}
+
+func (r *Regexp) String() *String {
+	return &String{value: fmt.Sprintf("<regexp %s>", r.regexp.String())}
+}
+
+func (r *Regexp) Equal(other Object) (Object, error) {
+	return &Boolean{value: r == other}, nil
+}
+
+func (r *Regexp) NotEqual(other Object) (Object, error) {
+	return &Boolean{value: r != other}, nil
+}
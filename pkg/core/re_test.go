@@ -0,0 +1,165 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestReModule(t *testing.T) {
+	date := NewMap()
+	date.Set("year", &String{value: "2024"})
+	date.Set("month", &String{value: "05"})
+
+	cases := []struct {
+		name     string
+		input    string
+		expected Object
+		err      string
+	}{
+		{
+			name:     "test match",
+			input:    "re.match(\"^\\d+$\", \"12345\")",
+			expected: &Boolean{value: true},
+		},
+		{
+			name:     "test compiled match method",
+			input:    "r = re.compile(\"[a-z]+\")\nr.match(\"123\")",
+			expected: &Boolean{value: false},
+		},
+		{
+			name:     "test compile caches by pattern",
+			input:    "re.compile(\"a+\") == re.compile(\"a+\")",
+			expected: &Boolean{value: true},
+		},
+		{
+			name:     "test pattern attribute",
+			input:    "re.compile(\"a+b\").pattern",
+			expected: &String{value: "a+b"},
+		},
+		{
+			name:     "test find",
+			input:    "re.find(\"\\d+\", \"abc 42 def 7\")",
+			expected: &String{value: "42"},
+		},
+		{
+			name:     "test find without match",
+			input:    "re.find(\"\\d+\", \"abc\")",
+			expected: &Nil{},
+		},
+		{
+			name:     "test find all",
+			input:    "re.findAll(\"\\d+\", \"1 22 333\")",
+			expected: newStringArray([]string{"1", "22", "333"}),
+		},
+		{
+			name:     "test find all with limit",
+			input:    "re.compile(\"\\d+\").findAll(\"1 22 333\", 2)",
+			expected: newStringArray([]string{"1", "22"}),
+		},
+		{
+			name:     "test positional groups",
+			input:    "re.submatch(\"(\\w+)@(\\w+)?\", \"me@\")",
+			expected: &Array{Elements: []Object{&String{value: "me@"}, &String{value: "me"}, &Nil{}}},
+		},
+		{
+			name:  "test submatch all",
+			input: "re.submatchAll(\"(\\w)=(\\d)\", \"a=1 b=2\")",
+			expected: &Array{Elements: []Object{
+				newStringArray([]string{"a=1", "a", "1"}),
+				newStringArray([]string{"b=2", "b", "2"}),
+			}},
+		},
+		{
+			name:     "test named groups",
+			input:    "re.groups(\"(?P<year>\\d{4})-(?P<month>\\d{2})\", \"on 2024-05-01\")",
+			expected: date,
+		},
+		{
+			name:     "test replace with template",
+			input:    "re.replace(\"(\\w+)@(\\w+)\", \"me@host\", \"$2 at ${1}\")",
+			expected: &String{value: "host at me"},
+		},
+		{
+			name:     "test replace with function",
+			input:    "import \"strings\"\nre.replace(\"[aeiou]\", \"goscript\", func(m) {\n return strings.upper(m)\n})",
+			expected: &String{value: "gOscrIpt"},
+		},
+		{
+			name:  "test replace function must return string",
+			input: "re.replace(\"a\", \"abc\", func(m) {\n return 1\n})",
+			err:   "replace() function must return a string, got integer",
+		},
+		{
+			name:  "test replace function error stops",
+			input: "re.replace(\"a\", \"aaa\", func(m) {\n throw \"stop\"\n})",
+			err:   "stop",
+		},
+		{
+			name:     "test split",
+			input:    "re.split(\"\\s*,\\s*\", \"a , b,c\")",
+			expected: newStringArray([]string{"a", "b", "c"}),
+		},
+		{
+			name:  "test invalid pattern",
+			input: "re.compile(\"(a\")",
+			err:   "error parsing regexp: missing closing ): `(a`",
+		},
+		{
+			name:  "test wrong pattern type",
+			input: "re.match(1, \"a\")",
+			err:   "match() expects a regexp or string, got integer",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := evalInput("import \"re\"\n" + test.input)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestRegexpCacheEviction(t *testing.T) {
+	first, err := compileRegexp("first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, err := compileRegexp("kept")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < REGEXP_CACHE_SIZE; i++ {
+		// using kept keeps it among the most recently used
+		if again, _ := compileRegexp("kept"); again != kept {
+			t.Fatal("expected kept to stay cached")
+		}
+		if _, err := compileRegexp(fmt.Sprintf("pattern%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	regexpCache.Lock()
+	size := len(regexpCache.regexps)
+	regexpCache.Unlock()
+	if size > REGEXP_CACHE_SIZE {
+		t.Fatalf("expected at most %d cached patterns, got %d", REGEXP_CACHE_SIZE, size)
+	}
+	if again, _ := compileRegexp("first"); again == first {
+		t.Fatal("expected the least recently used pattern to be evicted")
+	}
+	if again, _ := compileRegexp("kept"); again != kept {
+		t.Fatal("expected a recently used pattern to stay cached")
+	}
+}
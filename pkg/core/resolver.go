@@ -0,0 +1,543 @@
+package core
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// The resolver works out where the variables used by a function or a
+// program live before the compiler turns them into bytecode or the tree
+// walker runs them. A local the resolver can pin down gets a slot in an
+// array of the function's own, found by the depth of the block it was
+// declared in and its slot within the function. A variable no enclosing
+// scope can bind is read straight from the global table, the scope of the
+// program or module the code belongs to. Anything else is looked up through
+// the frames.
+
+// variable is where the resolver found a variable.
+type variable struct {
+	// global is set for a variable read from the global table
+	global bool
+	// depth is the number of blocks between the use of a local and the
+	// block it was declared in, slot its index among the function's locals
+	depth int
+	slot  int
+}
+
+// resolution is what resolving a function body or a program found.
+type resolution struct {
+	// variables holds the variable used by each identifier, assignment,
+	// increment, call by name and range variable, those missing are looked
+	// up in the frames
+	variables map[Node]variable
+	// slots is the number of locals and params the slot of each parameter,
+	// -1 for a parameter kept in the function's frame
+	slots  int
+	params []int
+}
+
+// resolved is a resolution the tree walker runs code with, slots holding
+// the locals of the function call running it and globals its global table.
+type resolved struct {
+	*resolution
+	slots   []Object
+	globals map[string]Object
+}
+
+// newSlots returns the slots of a call whose parameters have the slots in
+// params, holding the arguments.
+func newSlots(count int, params []int, args []Object) []Object {
+	if count == 0 {
+		return nil
+	}
+	slots := make([]Object, count)
+	for i, slot := range params {
+		if slot >= 0 {
+			slots[slot] = args[i]
+		}
+	}
+	return slots
+}
+
+// resolver resolves the variables of a function body or a program, in the
+// order the compiler compiles them.
+type resolver struct {
+	res *resolution
+	// slotted is set when the locals of a function get slots, in which case
+	// nodes the compiler hands to the tree walker are an error
+	slotted bool
+	// scopes are the blocks being resolved, innermost last, with the slots
+	// of the locals declared in them
+	scopes []map[string]int
+	// dynamic holds the names declared somewhere a slot can't stand in for
+	dynamic map[string]bool
+	// placeable holds the declarations that may be given a slot
+	placeable map[*InfixNode]bool
+	// closed holds the names that may be bound in a scope between the code
+	// and the global table, nil when they are not known in which case
+	// nothing is read from the global table
+	closed map[string]bool
+	// blocks holds the names bound in each loop being resolved, loops
+	// running in frames of their own
+	blocks []map[string]bool
+}
+
+// resolveProgram resolves a program, or a statement of one. None of its
+// variables get slots.
+func resolveProgram(node Node) *resolution {
+	r := &resolver{res: &resolution{variables: map[Node]variable{}}, closed: map[string]bool{}}
+	r.resolve(node)
+	return r.res
+}
+
+// resolveFunction resolves the body of a function, closed being the names
+// bound in the scopes it closes over. When slotted is set its locals get
+// slots and a body the compiler can't compile entirely fails with
+// errUnsupported.
+func resolveFunction(body *BlockStatement, params []*IdentifierLiteral, closed map[string]bool, slotted bool) (*resolution, error) {
+	r := &resolver{res: &resolution{variables: map[Node]variable{}}, slotted: slotted, closed: closed}
+	r.findDeclarations(body)
+	r.scopes = []map[string]int{{}}
+	for _, param := range params {
+		slot := -1
+		if slotted && !r.dynamic[param.value] {
+			slot = r.declare(param.value)
+		}
+		r.res.params = append(r.res.params, slot)
+	}
+	if err := r.resolve(body); err != nil {
+		return nil, err
+	}
+	return r.res, nil
+}
+
+// resolveBody resolves the body of a function for the tree walker. Like
+// compileFunction its locals get slots unless it defines functions or has
+// nodes the compiler leaves to the tree walker, which look variables up in
+// the frames.
+func resolveBody(body *BlockStatement, params []*IdentifierLiteral, closed map[string]bool) *resolution {
+	res, err := resolveFunction(body, params, closed, true)
+	if err != nil {
+		// resolving without slots never fails
+		res, _ = resolveFunction(body, params, closed, false)
+	}
+	return res
+}
+
+// compilable reports whether the compiler has instructions for node, the
+// tree walker runs the nodes it has not.
+func compilable(node Node) bool {
+	switch n := node.(type) {
+	case Object, *IdentifierLiteral, *AttributeNode, *ArrayLiteral, *MapLiteral, *IndexNode, *SliceNode,
+		*ReturnStatement, *ThrowStatement, *FunctionLiteral, *BlockStatement, *FunctionCall,
+		*YieldExpression, *AwaitExpression, *incrementStatement:
+		return true
+	case *RangeNode:
+		return n.Body != nil
+	case *ForNode:
+		return n.Condition != nil && n.Body != nil
+	case *IfNode:
+		return n.Consequence != nil
+	case *InfixNode:
+		if n.Operator == "=" || n.Operator == ":=" {
+			_, isIdent := n.Left.(*IdentifierLiteral)
+			_, isIndex := n.Left.(*IndexNode)
+			return isIdent || (isIndex && n.Operator == "=")
+		}
+		_, ok := binaryOpcodes[n.Operator]
+		return ok
+	case *SufixNode:
+		_, isIdent := n.Left.(*IdentifierLiteral)
+		return isIdent && (n.Operator == "++" || n.Operator == "--")
+	}
+	return false
+}
+
+func (r *resolver) resolve(node Node) error {
+	if node == nil {
+		return nil
+	}
+	if !compilable(node) {
+		if r.slotted {
+			return errUnsupported
+		}
+		return nil
+	}
+	switch n := node.(type) {
+	case *IdentifierLiteral:
+		r.use(n, n.value)
+	case *FunctionLiteral:
+		if r.slotted {
+			// a closure would need the slots in a frame to close over
+			return errUnsupported
+		}
+	case *FunctionCall:
+		switch callee := n.Function.(type) {
+		case nil, *IdentifierLiteral:
+			r.use(n, n.Name)
+		default:
+			if err := r.resolve(callee); err != nil {
+				return err
+			}
+		}
+		return r.resolveAll(n.Arguments...)
+	case *InfixNode:
+		if n.Operator != "=" && n.Operator != ":=" {
+			return r.resolveAll(n.Left, n.Right)
+		}
+		if index, ok := n.Left.(*IndexNode); ok {
+			return r.resolveAll(n.Right, index.Object, index.Index)
+		}
+		if err := r.resolve(n.Right); err != nil {
+			return err
+		}
+		name := n.Left.String().value
+		if n.Operator == ":=" {
+			if r.slotted && !r.dynamic[name] && r.placeable[n] {
+				r.res.variables[n] = variable{slot: r.declare(name)}
+			}
+		} else if slot, depth, ok := r.local(name); ok {
+			r.res.variables[n] = variable{depth: depth, slot: slot}
+		}
+	case *SufixNode:
+		r.use(n, n.Left.String().value)
+	case *incrementStatement:
+		r.use(n, n.Left.value)
+	case *ForNode:
+		r.begin(n)
+		defer r.end()
+		return r.resolveAll(n.Initialisation, n.Condition, n.Body, n.Updater)
+	case *RangeNode:
+		if err := r.resolve(n.Iterable); err != nil {
+			return err
+		}
+		r.begin(n)
+		defer r.end()
+		for _, ident := range []*IdentifierLiteral{n.Key, n.Element} {
+			if ident != nil && r.slotted && !r.dynamic[ident.value] {
+				r.res.variables[ident] = variable{slot: r.declare(ident.value)}
+			}
+		}
+		return r.resolve(n.Body)
+	default:
+		return r.resolveAll(children(n)...)
+	}
+	return nil
+}
+
+func (r *resolver) resolveAll(nodes ...Node) error {
+	for _, node := range nodes {
+		if err := r.resolve(node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// use resolves the variable name that node reads.
+func (r *resolver) use(node Node, name string) {
+	if slot, depth, ok := r.local(name); ok {
+		r.res.variables[node] = variable{depth: depth, slot: slot}
+		return
+	}
+	if r.global(name) {
+		r.res.variables[node] = variable{global: true}
+	}
+}
+
+// local returns the slot of the local called name and how many blocks out
+// it was declared.
+func (r *resolver) local(name string) (int, int, bool) {
+	if r.dynamic[name] {
+		return 0, 0, false
+	}
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if slot, ok := r.scopes[i][name]; ok {
+			return slot, len(r.scopes) - 1 - i, true
+		}
+	}
+	return 0, 0, false
+}
+
+// global reports whether name can only refer to the global table.
+func (r *resolver) global(name string) bool {
+	if r.closed == nil || r.closed[name] {
+		return false
+	}
+	for _, block := range r.blocks {
+		if block[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// declare gives name a new slot in the innermost block, or the slot it
+// already has there.
+func (r *resolver) declare(name string) int {
+	scope := r.scopes[len(r.scopes)-1]
+	if slot, ok := scope[name]; ok {
+		return slot
+	}
+	scope[name] = r.res.slots
+	r.res.slots++
+	return scope[name]
+}
+
+// begin enters a loop, which runs in a frame of its own.
+func (r *resolver) begin(loop Node) {
+	if r.slotted {
+		r.scopes = append(r.scopes, map[string]int{})
+	}
+	r.blocks = append(r.blocks, bindings(loop))
+}
+
+func (r *resolver) end() {
+	if r.slotted {
+		r.scopes = r.scopes[:len(r.scopes)-1]
+	}
+	r.blocks = r.blocks[:len(r.blocks)-1]
+}
+
+// declaration reports whether node declares a variable with :=.
+func declaration(node Node) (*InfixNode, bool) {
+	assign, ok := node.(*InfixNode)
+	if !ok || assign.Operator != ":=" {
+		return nil, false
+	}
+	_, ok = assign.Left.(*IdentifierLiteral)
+	return assign, ok
+}
+
+// mentions reports whether name appears anywhere in node.
+func mentions(node Node, name string) bool {
+	found := false
+	inspect(node, func(n Node) bool {
+		switch n := n.(type) {
+		case *IdentifierLiteral:
+			found = found || n.value == name
+		case *FunctionCall:
+			found = found || n.Name == name
+		}
+		return !found
+	})
+	return found
+}
+
+// findDeclarations sorts the declarations in a function body into those
+// that may be given a slot and those that may not. A slot stands in for a
+// variable as long as every use of the name after the declaration, in the
+// order the code is resolved, refers to it. That holds for declarations
+// made directly in the function body or a loop's initialisation, and for
+// those made directly in a loop body as long as nothing in the loop uses
+// the name ahead of them, which on the next iteration would refer to the
+// variable of the last one. A name declared anywhere else never gets a
+// slot.
+func (r *resolver) findDeclarations(body *BlockStatement) {
+	r.dynamic = map[string]bool{}
+	r.placeable = map[*InfixNode]bool{}
+	loopBody := func(body Node, header ...Node) {
+		block, ok := body.(*BlockStatement)
+		if !ok {
+			return
+		}
+		for i, stmt := range block.Statements {
+			assign, ok := declaration(stmt)
+			if !ok {
+				continue
+			}
+			name := assign.Left.String().value
+			used := mentions(assign.Right, name)
+			for _, node := range append(header, block.Statements[:i]...) {
+				used = used || mentions(node, name)
+			}
+			r.placeable[assign] = !used
+		}
+	}
+	inspect(body, func(n Node) bool {
+		switch n := n.(type) {
+		case *ForNode:
+			if assign, ok := declaration(n.Initialisation); ok {
+				r.placeable[assign] = true
+			}
+			loopBody(n.Body, n.Condition, n.Updater)
+		case *RangeNode:
+			loopBody(n.Body)
+		}
+		return true
+	})
+	for _, stmt := range body.Statements {
+		if assign, ok := declaration(stmt); ok {
+			r.placeable[assign] = true
+		}
+	}
+	inspect(body, func(n Node) bool {
+		if assign, ok := declaration(n); ok && !r.placeable[assign] {
+			r.dynamic[assign.Left.String().value] = true
+		}
+		return true
+	})
+}
+
+// bindings returns the names node may bind in the scope it runs in, leaving
+// out those bound inside the functions it defines.
+func bindings(node Node) map[string]bool {
+	names := map[string]bool{}
+	inspect(node, func(n Node) bool {
+		switch n := n.(type) {
+		case *InfixNode:
+			if ident, ok := n.Left.(*IdentifierLiteral); ok && (n.Operator == "=" || n.Operator == ":=") {
+				names[ident.value] = true
+			}
+		case *FunctionLiteral:
+			if n.Name != "" {
+				names[n.Name] = true
+			}
+			return false
+		case *RangeNode:
+			for _, ident := range []*IdentifierLiteral{n.Key, n.Element} {
+				if ident != nil {
+					names[ident.value] = true
+				}
+			}
+		case *TryStatement:
+			if n.CatchName != nil {
+				names[n.CatchName.value] = true
+			}
+		case *SelectStatement:
+			for _, sc := range n.Cases {
+				if sc.Name != nil {
+					names[sc.Name.value] = true
+				}
+			}
+		case *ImportStatement:
+			names[importName(n)] = true
+		}
+		return true
+	})
+	return names
+}
+
+// importName is the name an import statement binds the module to.
+func importName(n *ImportStatement) string {
+	if n.Name != "" {
+		return n.Name
+	}
+	if _, ok := nativeModules[n.Path]; ok {
+		return n.Path
+	}
+	return strings.TrimSuffix(filepath.Base(n.Path), MODULE_EXTENSION)
+}
+
+// functionBindings returns the names a function binds in its own frame,
+// its parameters included.
+func functionBindings(fn *FunctionLiteral) map[string]bool {
+	names := bindings(fn.Body)
+	for _, param := range fn.Arguments {
+		names[param.value] = true
+	}
+	return names
+}
+
+// union returns a new set holding the names in both sets.
+func union(a, b map[string]bool) map[string]bool {
+	names := make(map[string]bool, len(a)+len(b))
+	for name := range a {
+		names[name] = true
+	}
+	for name := range b {
+		names[name] = true
+	}
+	return names
+}
+
+// recordClosures records for every function defined in node the names
+// that may be bound in the scopes it closes over other than the global
+// table, its own included, outer being those bound around node.
+func recordClosures(node Node, outer map[string]bool, closures map[*BlockStatement]map[string]bool) {
+	inspect(node, func(n Node) bool {
+		var inner map[string]bool
+		switch n := n.(type) {
+		case *FunctionLiteral:
+			inner = union(outer, functionBindings(n))
+			closures[n.Body] = inner
+		case *ForNode, *RangeNode, *SelectStatement:
+			inner = union(outer, bindings(n))
+		default:
+			return true
+		}
+		for _, child := range children(n) {
+			recordClosures(child, inner, closures)
+		}
+		return false
+	})
+}
+
+// Resolve checks every variable a program uses is bound somewhere it could
+// be visible from, reporting the first that is not as an undefined variable
+// with the line it is used on. Functions see the names bound in the
+// functions around them and at the top level, the top level sees its own.
+func (e *Evaluator) Resolve(program Node) error {
+	visible := bindings(program)
+	for name := range e.globals.scope {
+		visible[name] = true
+	}
+	return e.checkDefined(program, visible)
+}
+
+func (e *Evaluator) checkDefined(node Node, visible map[string]bool) error {
+	var err error
+	check := func(name string, line int) {
+		if _, builtin := e.builtins[name]; err == nil && !visible[name] && !builtin {
+			err = newRuntimeError(NAME_ERROR, "undefined variable '%s' on line: %d", name, line)
+		}
+	}
+	var walk func(Node) bool
+	walk = func(n Node) bool {
+		if err != nil {
+			return false
+		}
+		switch n := n.(type) {
+		case *FunctionLiteral:
+			err = e.checkDefined(n.Body, union(visible, functionBindings(n)))
+			return false
+		case *IdentifierLiteral:
+			check(n.value, n.Line)
+		case *FunctionCall:
+			if n.Function == nil {
+				check(n.Name, n.Line)
+			}
+		case *InfixNode:
+			if _, ok := n.Left.(*IdentifierLiteral); ok && (n.Operator == "=" || n.Operator == ":=") {
+				inspect(n.Right, walk)
+				return false
+			}
+		case *RangeNode:
+			inspect(n.Iterable, walk)
+			inspect(n.Body, walk)
+			return false
+		case *TryStatement:
+			inspect(n.Body, walk)
+			if n.Catch != nil {
+				inspect(n.Catch, walk)
+			}
+			if n.Finally != nil {
+				inspect(n.Finally, walk)
+			}
+			return false
+		case *SelectStatement:
+			for _, sc := range n.Cases {
+				inspect(sc.Channel, walk)
+				inspect(sc.Send, walk)
+				inspect(sc.Body, walk)
+			}
+			if n.Default != nil {
+				inspect(n.Default, walk)
+			}
+			return false
+		}
+		return true
+	}
+	inspect(node, walk)
+	return err
+}
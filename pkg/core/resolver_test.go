@@ -0,0 +1,239 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{
+			name:  "test undefined variable in function",
+			input: "print(1)\nfunc f() {\n return missing + 1\n}",
+			err:   "undefined variable 'missing' on line: 3",
+		},
+		{
+			name:  "test undefined function",
+			input: "x = 1\nnothing(x)",
+			err:   "undefined variable 'nothing' on line: 2",
+		},
+		{
+			name:  "test function locals are not visible outside",
+			input: "func f() {\n x := 1\n}\nprint(x)",
+			err:   "undefined variable 'x' on line: 4",
+		},
+		{
+			name:  "test function cannot see another function's locals",
+			input: "func a() {\n secret = 1\n}\nfunc b() {\n return secret\n}",
+			err:   "undefined variable 'secret' on line: 5",
+		},
+		{
+			name:  "test function cannot see its caller's locals",
+			input: "func g() {\n return y\n}\nfunc f() {\n y = 1\n return g()\n}\nf()",
+			err:   "undefined variable 'y' on line: 2",
+		},
+		{
+			name:  "test global defined after the function",
+			input: "func f() {\n return later\n}\nlater = 1\nf()",
+		},
+		{
+			name:  "test closure sees enclosing function",
+			input: "func outer(a) {\n n := 1\n return func() {\n  return n + a\n }\n}",
+		},
+		{
+			name:  "test recursion",
+			input: "func fact(n) {\n if n < 2 {\n  return 1\n }\n return n * fact(n - 1)\n}\nfact(5)",
+		},
+		{
+			name:  "test builtins, imports, range and catch names",
+			input: "import \"time\"\nfor i, v := range [1] {\n print(i, v, time)\n}\ntry {\n throw 1\n} catch e {\n print(e)\n}",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			program, err := NewV1Parser(NewV1Lexer(test.input), false).ParseProgram()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = NewEvaluator(false).Resolve(program)
+			if test.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || toRuntimeError(err).Message != test.err {
+				t.Fatalf("expected error %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+// TestScoping pins down the lexical scoping the resolver relies on, see
+// CHANGELOG.md. before is what the same program gave when callees read
+// their callers' locals and assignment always created a local.
+func TestScoping(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		before   string
+		expected Object
+		err      string
+	}{
+		{
+			name:     "test assignment in function updates global",
+			input:    "x = 1\nfunc f() {\n x = 2\n}\nf()\nx",
+			before:   "1",
+			expected: &Integer{value: 2},
+		},
+		{
+			name:     "test declaration in function shadows global",
+			input:    "x = 1\nfunc f() {\n x := 2\n return x\n}\n[f(), x]",
+			before:   "[2, 1], := was not supported",
+			expected: &Array{Elements: []Object{&Integer{value: 2}, &Integer{value: 1}}},
+		},
+		{
+			name:   "test callee does not see caller locals",
+			input:  "func g() {\n return y\n}\nfunc f() {\n y = 1\n return g()\n}\nf()",
+			before: "1",
+			err:    "variable 'y' is not defined",
+		},
+		{
+			name:     "test parameter shadows global",
+			input:    "n = 1\nfunc f(n) {\n n = n + 10\n return n\n}\n[f(5), n]",
+			before:   "[15, 1]",
+			expected: &Array{Elements: []Object{&Integer{value: 15}, &Integer{value: 1}}},
+		},
+		{
+			name:     "test loop body updates outer variable",
+			input:    "total = 0\nfor i := 0; i < 3; i++ {\n total = total + i\n}\ntotal",
+			before:   "0, the loop frame got its own total",
+			expected: &Integer{value: 3},
+		},
+		{
+			name:     "test closure assigns captured variable",
+			input:    "func counter() {\n n := 0\n return func() {\n  n = n + 1\n  return n\n }\n}\nc = counter()\nc()\nc()",
+			before:   "a parse error, there were no anonymous functions",
+			expected: &Integer{value: 2},
+		},
+	}
+
+	for _, backend := range backends {
+		for _, test := range cases {
+			t.Run(backend.name+"/"+test.name, func(t *testing.T) {
+				result, err := evalInputWith(test.input, backend.vm)
+				if test.err != "" {
+					if err == nil || err.Error() != test.err {
+						t.Fatalf("expected error %q (was %s), got %v", test.err, test.before, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !reflect.DeepEqual(result, test.expected) {
+					t.Fatalf("expected %v (was %s), got %v", test.expected, test.before, result)
+				}
+			})
+		}
+	}
+}
+
+func TestResolveFunction(t *testing.T) {
+	input := "func f(a) {\n for i := 0; i < a; i++ {\n  total = i\n }\n return helper(a)\n}"
+	program, err := NewV1Parser(NewV1Lexer(input), false).ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	closures := map[*BlockStatement]map[string]bool{}
+	recordClosures(program, map[string]bool{}, closures)
+	fn := program.(*BlockStatement).Statements[0].(*FunctionLiteral)
+	res, err := resolveFunction(fn.Body, fn.Arguments, closures[fn.Body], true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the variable each name resolved to where it is first used
+	found := map[string]variable{}
+	resolved := map[string]bool{}
+	inspect(fn.Body, func(n Node) bool {
+		var name string
+		switch n := n.(type) {
+		case *IdentifierLiteral:
+			name = n.value
+		case *FunctionCall:
+			name = n.Name
+		case *InfixNode:
+			if n.Operator == "=" {
+				name = n.Left.String().value + "="
+			}
+		}
+		if v, ok := res.variables[n]; ok && name != "" && !resolved[name] {
+			found[name], resolved[name] = v, true
+		}
+		return true
+	})
+
+	expected := map[string]variable{
+		"a":      {depth: 1, slot: 0},
+		"i":      {depth: 0, slot: 1},
+		"helper": {global: true},
+	}
+	for name, v := range expected {
+		if found[name] != v {
+			t.Errorf("expected %s to resolve to %+v, got %+v", name, v, found[name])
+		}
+	}
+	if resolved["total="] {
+		t.Errorf("expected total, which the loop may declare, to be looked up in the frames")
+	}
+	if res.slots != 2 {
+		t.Errorf("expected 2 slots, got %d", res.slots)
+	}
+}
+
+func TestTreeWalkerResolution(t *testing.T) {
+	input := "func sum(items) {\n total := 0\n for _, item := range items {\n  total = total + item\n }\n return scale(total)\n}\nfunc scale(n) {\n return n * factor\n}\nfactor = 10\nsum([1, 2, 3])"
+	program, err := NewV1Parser(NewV1Lexer(input), false).ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	evaluator := NewEvaluator(false)
+	evaluator.SetVM(false)
+	var result Object
+	for _, stmt := range program.(*BlockStatement).Statements {
+		if result, err = evaluator.Evaluate(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(result, &Integer{value: 60}) {
+		t.Fatalf("expected 60, got %v", result)
+	}
+
+	// the bodies the tree walker ran were resolved once, sum's locals and
+	// parameter into slots
+	sum := program.(*BlockStatement).Statements[0].(*FunctionLiteral)
+	res, ok := evaluator.resolutions[sum.Body]
+	if !ok {
+		t.Fatal("expected the body of sum to be resolved")
+	}
+	if res.slots != 4 {
+		t.Errorf("expected 4 slots, got %d", res.slots)
+	}
+	scale := program.(*BlockStatement).Statements[1].(*FunctionLiteral)
+	found := false
+	inspect(scale.Body, func(n Node) bool {
+		if ident, ok := n.(*IdentifierLiteral); ok && ident.value == "factor" {
+			found = evaluator.resolutions[scale.Body].variables[n] == variable{global: true}
+		}
+		return true
+	})
+	if !found {
+		t.Error("expected factor to be read from the global table")
+	}
+}
@@ -4,10 +4,27 @@ const CALL_STACK_SIZE = 10000
 
 type Frame struct {
 	scope map[string]Object
+	// function marks the frame pushed for a function call, block frames
+	// such as loops set up inside the call sit above it
+	function bool
+	closure  []map[string]Object
+	deferred []*deferredCall
+	// panic is the error the function is unwinding with while its deferred
+	// calls run, recover() clears it
+	panic     error
+	deferring bool
+}
+
+// deferredCall is a call scheduled by defer, the function and its arguments
+// are evaluated when the defer statement runs.
+type deferredCall struct {
+	function Object
+	args     []Object
+	line     int
 }
 
 func NewFrame() *Frame {
-	return &Frame{map[string]Object{}}
+	return &Frame{scope: map[string]Object{}}
 }
 
 func NewCallStack() *[]Frame {
@@ -0,0 +1,284 @@
+package core
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// stringFunctions make up the native strings module. They all take the
+// string they work on first so they double as the methods of String, see
+// stringMethods.
+var stringFunctions = map[string]func([]Object) (Object, error){
+	"split":      stringsSplit,
+	"join":       stringsJoin,
+	"replace":    stringsReplace,
+	"trim":       stringsTrim("trim", strings.TrimSpace, strings.Trim),
+	"trimLeft":   stringsTrim("trimLeft", trimLeftSpace, strings.TrimLeft),
+	"trimRight":  stringsTrim("trimRight", trimRightSpace, strings.TrimRight),
+	"trimPrefix": stringsAffix("trimPrefix", strings.TrimPrefix),
+	"trimSuffix": stringsAffix("trimSuffix", strings.TrimSuffix),
+	"contains":   stringsPredicate("contains", strings.Contains),
+	"hasPrefix":  stringsPredicate("hasPrefix", strings.HasPrefix),
+	"hasSuffix":  stringsPredicate("hasSuffix", strings.HasSuffix),
+	"index":      stringsIndex("index", strings.Index),
+	"lastIndex":  stringsIndex("lastIndex", strings.LastIndex),
+	"upper":      stringsMap("upper", strings.ToUpper),
+	"lower":      stringsMap("lower", strings.ToLower),
+	"repeat":     stringsRepeat,
+	"fields":     stringsFields,
+	"padLeft":    stringsPad("padLeft", true),
+	"padRight":   stringsPad("padRight", false),
+}
+
+// stringsModule builds the native strings module.
+func stringsModule(e *Evaluator) map[string]Object {
+	module := map[string]Object{}
+	for name, fn := range stringFunctions {
+		module[name] = &GoFunction{Name: name, Func: fn}
+	}
+	return module
+}
+
+// stringMethods is the method table of String, s.split(",") is
+// strings.split(s, ","). join is a method of Array instead.
+func stringMethods() map[string]*GoFunction {
+	methods := map[string]*GoFunction{}
+	for name, fn := range stringFunctions {
+		if name != "join" {
+			methods[name] = &GoFunction{Name: name, Func: fn}
+		}
+	}
+	return methods
+}
+
+// arrayMethods is the method table of Array.
+func arrayMethods() map[string]*GoFunction {
+	return map[string]*GoFunction{
+		"join": {Name: "join", Func: stringsJoin},
+	}
+}
+
+// stringArgs checks a strings function got between min and max arguments and
+// returns the first count of them, which must be strings.
+func stringArgs(name string, args []Object, min, max, count int) ([]string, error) {
+	if err := checkArgs(name, args, min, max); err != nil {
+		return nil, err
+	}
+	values := make([]string, 0, count)
+	for i := 0; i < count && i < len(args); i++ {
+		value, err := stringArg(name, args, i)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func newStringArray(values []string) *Array {
+	elements := make([]Object, len(values))
+	for i, value := range values {
+		elements[i] = &String{value: value}
+	}
+	return &Array{Elements: elements}
+}
+
+// stringsSplit is split(s, sep) or split(s, sep, n) with n limiting the
+// number of parts like strings.SplitN. An empty separator splits after each
+// rune.
+func stringsSplit(args []Object) (Object, error) {
+	values, err := stringArgs("split", args, 2, 3, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	n := -1
+	if len(args) == 3 {
+		if n, err = intArg("split", args, 2); err != nil {
+			return &Nil{}, err
+		}
+	}
+	return newStringArray(strings.SplitN(values[0], values[1], n)), nil
+}
+
+// stringsJoin is join(array, sep), elements that are not strings are joined
+// using their string form.
+func stringsJoin(args []Object) (Object, error) {
+	if err := checkArgs("join", args, 2, 2); err != nil {
+		return &Nil{}, err
+	}
+	array, ok := args[0].(*Array)
+	if !ok {
+		return &Nil{}, newTypeError("join() expects an array, got %s", args[0].Type())
+	}
+	sep, err := stringArg("join", args, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	parts := make([]string, len(array.Elements))
+	for i, element := range array.Elements {
+		parts[i] = element.String().value
+	}
+	return &String{value: strings.Join(parts, sep)}, nil
+}
+
+// stringsReplace is replace(s, old, new) replacing every occurrence or
+// replace(s, old, new, n) replacing the first n.
+func stringsReplace(args []Object) (Object, error) {
+	values, err := stringArgs("replace", args, 3, 4, 3)
+	if err != nil {
+		return &Nil{}, err
+	}
+	n := -1
+	if len(args) == 4 {
+		if n, err = intArg("replace", args, 3); err != nil {
+			return &Nil{}, err
+		}
+	}
+	return &String{value: strings.Replace(values[0], values[1], values[2], n)}, nil
+}
+
+func trimLeftSpace(s string) string {
+	return strings.TrimLeftFunc(s, unicode.IsSpace)
+}
+
+func trimRightSpace(s string) string {
+	return strings.TrimRightFunc(s, unicode.IsSpace)
+}
+
+// stringsTrim builds the trim functions, which strip white space or, given a
+// second argument, any of the runes in it.
+func stringsTrim(name string, space func(string) string, cutset func(string, string) string) func([]Object) (Object, error) {
+	return func(args []Object) (Object, error) {
+		values, err := stringArgs(name, args, 1, 2, 2)
+		if err != nil {
+			return &Nil{}, err
+		}
+		if len(values) == 2 {
+			return &String{value: cutset(values[0], values[1])}, nil
+		}
+		return &String{value: space(values[0])}, nil
+	}
+}
+
+func stringsAffix(name string, fn func(string, string) string) func([]Object) (Object, error) {
+	return func(args []Object) (Object, error) {
+		values, err := stringArgs(name, args, 2, 2, 2)
+		if err != nil {
+			return &Nil{}, err
+		}
+		return &String{value: fn(values[0], values[1])}, nil
+	}
+}
+
+func stringsPredicate(name string, fn func(string, string) bool) func([]Object) (Object, error) {
+	return func(args []Object) (Object, error) {
+		values, err := stringArgs(name, args, 2, 2, 2)
+		if err != nil {
+			return &Nil{}, err
+		}
+		return &Boolean{value: fn(values[0], values[1])}, nil
+	}
+}
+
+// stringsIndex builds index and lastIndex, which count in runes like
+// indexing and slicing do. They return -1 when the substring is missing.
+func stringsIndex(name string, fn func(string, string) int) func([]Object) (Object, error) {
+	return func(args []Object) (Object, error) {
+		values, err := stringArgs(name, args, 2, 2, 2)
+		if err != nil {
+			return &Nil{}, err
+		}
+		index := fn(values[0], values[1])
+		if index > 0 {
+			index = utf8.RuneCountInString(values[0][:index])
+		}
+		return &Integer{value: int64(index)}, nil
+	}
+}
+
+func stringsMap(name string, fn func(string) string) func([]Object) (Object, error) {
+	return func(args []Object) (Object, error) {
+		values, err := stringArgs(name, args, 1, 1, 1)
+		if err != nil {
+			return &Nil{}, err
+		}
+		return &String{value: fn(values[0])}, nil
+	}
+}
+
+// MAX_STRING_SIZE is the length in bytes of the longest string repeat and
+// the pad methods make.
+const MAX_STRING_SIZE = 1 << 28
+
+func newStringSizeError(name string) error {
+	return newRuntimeError(VALUE_ERROR, "%s() result would be longer than %d bytes", name, MAX_STRING_SIZE)
+}
+
+func stringsRepeat(args []Object) (Object, error) {
+	values, err := stringArgs("repeat", args, 2, 2, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	count, err := intArg("repeat", args, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	if count < 0 {
+		return &Nil{}, newRuntimeError(VALUE_ERROR, "repeat() count must not be negative")
+	}
+	if len(values[0]) > 0 && count > MAX_STRING_SIZE/len(values[0]) {
+		return &Nil{}, newStringSizeError("repeat")
+	}
+	return &String{value: strings.Repeat(values[0], count)}, nil
+}
+
+func stringsFields(args []Object) (Object, error) {
+	values, err := stringArgs("fields", args, 1, 1, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	return newStringArray(strings.Fields(values[0])), nil
+}
+
+// stringsPad builds padLeft and padRight, pad(s, width) or pad(s, width,
+// padding) fills s with spaces or repeats of padding until it is width
+// runes long.
+func stringsPad(name string, left bool) func([]Object) (Object, error) {
+	return func(args []Object) (Object, error) {
+		if err := checkArgs(name, args, 2, 3); err != nil {
+			return &Nil{}, err
+		}
+		s, err := stringArg(name, args, 0)
+		if err != nil {
+			return &Nil{}, err
+		}
+		width, err := intArg(name, args, 1)
+		if err != nil {
+			return &Nil{}, err
+		}
+		padding := " "
+		if len(args) == 3 {
+			if padding, err = stringArg(name, args, 2); err != nil {
+				return &Nil{}, err
+			}
+			if padding == "" {
+				return &Nil{}, newRuntimeError(VALUE_ERROR, "%s() padding must not be empty", name)
+			}
+		}
+
+		missing := width - utf8.RuneCountInString(s)
+		if missing <= 0 {
+			return &String{value: s}, nil
+		}
+		repeats := missing/utf8.RuneCountInString(padding) + 1
+		if repeats > (MAX_STRING_SIZE-len(s))/len(padding) {
+			return &Nil{}, newStringSizeError(name)
+		}
+		fill := []rune(strings.Repeat(padding, repeats))[:missing]
+		if left {
+			return &String{value: string(fill) + s}, nil
+		}
+		return &String{value: s + string(fill)}, nil
+	}
+}
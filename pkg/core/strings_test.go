@@ -0,0 +1,169 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStringsModule(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected Object
+		err      string
+	}{
+		{
+			name:     "test split",
+			input:    "strings.split(\"a,b,c\", \",\")",
+			expected: newStringArray([]string{"a", "b", "c"}),
+		},
+		{
+			name:     "test split method",
+			input:    "\"a,b,c\".split(\",\", 2)",
+			expected: newStringArray([]string{"a", "b,c"}),
+		},
+		{
+			name:     "test join",
+			input:    "strings.join([\"a\", \"b\"], \"-\")",
+			expected: &String{value: "a-b"},
+		},
+		{
+			name:     "test join method on array",
+			input:    "[1, 2, 3].join(\", \")",
+			expected: &String{value: "1, 2, 3"},
+		},
+		{
+			name:     "test replace",
+			input:    "\"aaa\".replace(\"a\", \"b\", 2)",
+			expected: &String{value: "bba"},
+		},
+		{
+			name:     "test trim",
+			input:    "strings.trim(\"  padded \t\")",
+			expected: &String{value: "padded"},
+		},
+		{
+			name:     "test trim cutset",
+			input:    "\"xxhixx\".trimLeft(\"x\")",
+			expected: &String{value: "hixx"},
+		},
+		{
+			name:     "test trim suffix",
+			input:    "\"main.gs\".trimSuffix(\".gs\")",
+			expected: &String{value: "main"},
+		},
+		{
+			name:     "test contains",
+			input:    "\"goscript\".contains(\"scr\")",
+			expected: &Boolean{value: true},
+		},
+		{
+			name:     "test has prefix",
+			input:    "strings.hasPrefix(\"goscript\", \"java\")",
+			expected: &Boolean{value: false},
+		},
+		{
+			name:     "test index counts runes",
+			input:    "\"héllo wörld\".index(\"wö\")",
+			expected: &Integer{value: 6},
+		},
+		{
+			name:     "test index not found",
+			input:    "\"hello\".index(\"z\")",
+			expected: &Integer{value: -1},
+		},
+		{
+			name:     "test upper",
+			input:    "\"héllo\".upper()",
+			expected: &String{value: "HÉLLO"},
+		},
+		{
+			name:     "test repeat",
+			input:    "\"ab\".repeat(3)",
+			expected: &String{value: "ababab"},
+		},
+		{
+			name:  "test repeat negative count",
+			input: "\"ab\".repeat(-1)",
+			err:   "repeat() count must not be negative",
+		},
+		{
+			name:  "test repeat too long",
+			input: "\"ab\".repeat(9223372036854775807)",
+			err:   "repeat() result would be longer than 268435456 bytes",
+		},
+		{
+			name:  "test pad too long",
+			input: "\"ab\".padLeft(9223372036854775807)",
+			err:   "padLeft() result would be longer than 268435456 bytes",
+		},
+		{
+			name:     "test fields",
+			input:    "\" a  b\tc \".fields()",
+			expected: newStringArray([]string{"a", "b", "c"}),
+		},
+		{
+			name:     "test pad left",
+			input:    "\"7\".padLeft(3, \"0\")",
+			expected: &String{value: "007"},
+		},
+		{
+			name:     "test pad right counts runes",
+			input:    "\"é\".padRight(3) + \"|\"",
+			expected: &String{value: "é  |"},
+		},
+		{
+			name:     "test length counts runes",
+			input:    "length(\"日本語\")",
+			expected: &Integer{value: 3},
+		},
+		{
+			name:     "test index string",
+			input:    "\"日本語\"[1]",
+			expected: &String{value: "本"},
+		},
+		{
+			name:     "test slice string",
+			input:    "\"héllo\"[1:4]",
+			expected: &String{value: "éll"},
+		},
+		{
+			name:     "test slice open bounds",
+			input:    "s = \"héllo\"\ns[:2] + s[3:]",
+			expected: &String{value: "hélo"},
+		},
+		{
+			name:  "test index out of range",
+			input: "\"abc\"[3]",
+			err:   "index 3 out of range [0:3]",
+		},
+		{
+			name:  "test unknown method",
+			input: "\"abc\".reverse()",
+			err:   "string has no attribute 'reverse'",
+		},
+		{
+			name:  "test wrong argument type",
+			input: "strings.upper(1)",
+			err:   "upper() expects a string, got integer",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := evalInput("import \"strings\"\n" + test.input)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package core
+
+import (
+	"sync"
+)
+
+// syncModule builds the native sync module.
+func syncModule(e *Evaluator) map[string]Object {
+	return map[string]Object{
+		"waitGroup": &GoFunction{Name: "waitGroup", Func: syncWaitGroup},
+		"mutex":     &GoFunction{Name: "mutex", Func: syncMutex},
+	}
+}
+
+// waitGroupMethods is the method table of WaitGroup.
+func waitGroupMethods() map[string]*GoFunction {
+	return map[string]*GoFunction{
+		"add":  {Name: "add", Func: waitGroupAdd},
+		"done": {Name: "done", Func: waitGroupDone},
+		"wait": {Name: "wait", EvalFunc: (*Evaluator).waitGroupWait},
+	}
+}
+
+// mutexMethods is the method table of Mutex.
+func mutexMethods() map[string]*GoFunction {
+	return map[string]*GoFunction{
+		"lock":    {Name: "lock", EvalFunc: (*Evaluator).mutexLock},
+		"unlock":  {Name: "unlock", Func: mutexUnlock},
+		"tryLock": {Name: "tryLock", Func: mutexTryLock},
+	}
+}
+
+// WaitGroup waits for a number of goroutines to finish like Go's.
+type WaitGroup struct {
+	nativeObject
+	group sync.WaitGroup
+	// count mirrors the counter of group so that misuse is an error rather
+	// than a Go panic
+	count int
+}
+
+func syncWaitGroup(args []Object) (Object, error) {
+	if err := checkArgs("waitGroup", args, 0, 0); err != nil {
+		return &Nil{}, err
+	}
+	return &WaitGroup{nativeObject: nativeObject{"waitgroup"}}, nil
+}
+
+func waitGroupArg(name string, args []Object, count int) (*WaitGroup, error) {
+	if err := checkArgs(name, args, count, count); err != nil {
+		return nil, err
+	}
+	wg, ok := args[0].(*WaitGroup)
+	if !ok {
+		return nil, newTypeError("%s() expects a wait group, got %s", name, args[0].Type())
+	}
+	return wg, nil
+}
+
+func (wg *WaitGroup) add(delta int) error {
+	if wg.count+delta < 0 {
+		return newRuntimeError(VALUE_ERROR, "negative wait group counter")
+	}
+	wg.count += delta
+	wg.group.Add(delta)
+	return nil
+}
+
+// waitGroupAdd is wg.add(delta).
+func waitGroupAdd(args []Object) (Object, error) {
+	wg, err := waitGroupArg("add", args, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	delta, err := intArg("add", args, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	return &Nil{}, wg.add(delta)
+}
+
+// waitGroupDone is wg.done(), the same as wg.add(-1).
+func waitGroupDone(args []Object) (Object, error) {
+	wg, err := waitGroupArg("done", args, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	return &Nil{}, wg.add(-1)
+}
+
+// waitGroupWait is wg.wait(), waiting until the counter is back to zero.
+func (e *Evaluator) waitGroupWait(args []Object) (Object, error) {
+	wg, err := waitGroupArg("wait", args, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	err = e.wait(func(stop <-chan struct{}) {
+		done := make(chan struct{})
+		go func() {
+			wg.group.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-stop:
+		}
+	})
+	return &Nil{}, err
+}
+
+func (wg *WaitGroup) Type() string {
+	return "waitgroup"
+}
+
+func (wg *WaitGroup) Value() interface{} {
+	return &wg.group
+}
+
+func (wg *WaitGroup) String() *String {
+	return &String{value: "<waitgroup>"}
+}
+
+func (wg *WaitGroup) Equal(other Object) (Object, error) {
+	return &Boolean{value: wg == other}, nil
+}
+
+func (wg *WaitGroup) NotEqual(other Object) (Object, error) {
+	return &Boolean{value: wg != other}, nil
+}
+
+// Mutex is a mutual exclusion lock like Go's. Only one goroutine runs
+// GoScript at a time but a mutex is still needed to keep others out while
+// the holder waits on a channel, a sleep or any other blocking call.
+type Mutex struct {
+	nativeObject
+	mutex  sync.Mutex
+	locked bool
+}
+
+func syncMutex(args []Object) (Object, error) {
+	if err := checkArgs("mutex", args, 0, 0); err != nil {
+		return &Nil{}, err
+	}
+	return &Mutex{nativeObject: nativeObject{"mutex"}}, nil
+}
+
+func mutexArg(name string, args []Object) (*Mutex, error) {
+	if err := checkArgs(name, args, 1, 1); err != nil {
+		return nil, err
+	}
+	m, ok := args[0].(*Mutex)
+	if !ok {
+		return nil, newTypeError("%s() expects a mutex, got %s", name, args[0].Type())
+	}
+	return m, nil
+}
+
+// mutexLock is m.lock(), waiting until the mutex is free.
+func (e *Evaluator) mutexLock(args []Object) (Object, error) {
+	m, err := mutexArg("lock", args)
+	if err != nil {
+		return &Nil{}, err
+	}
+	got := false
+	err = e.wait(func(stop <-chan struct{}) {
+		acquired := make(chan struct{})
+		go func() {
+			m.mutex.Lock()
+			close(acquired)
+		}()
+		select {
+		case <-acquired:
+			got = true
+		case <-stop:
+			// whoever is stopped does not get the mutex, it is handed back
+			// once it is acquired
+			go func() {
+				<-acquired
+				m.mutex.Unlock()
+			}()
+		}
+	})
+	if err != nil {
+		if got {
+			m.mutex.Unlock()
+		}
+		return &Nil{}, err
+	}
+	m.locked = true
+	return &Nil{}, nil
+}
+
+// mutexUnlock is m.unlock(), unlocking a mutex that is not locked is an
+// error.
+func mutexUnlock(args []Object) (Object, error) {
+	m, err := mutexArg("unlock", args)
+	if err != nil {
+		return &Nil{}, err
+	}
+	if !m.locked {
+		return &Nil{}, newRuntimeError(VALUE_ERROR, "unlock of unlocked mutex")
+	}
+	m.locked = false
+	m.mutex.Unlock()
+	return &Nil{}, nil
+}
+
+// mutexTryLock is m.tryLock(), locking the mutex if it is free and telling
+// whether it did.
+func mutexTryLock(args []Object) (Object, error) {
+	m, err := mutexArg("tryLock", args)
+	if err != nil {
+		return &Nil{}, err
+	}
+	if !m.mutex.TryLock() {
+		return &Boolean{value: false}, nil
+	}
+	m.locked = true
+	return &Boolean{value: true}, nil
+}
+
+func (m *Mutex) Type() string {
+	return "mutex"
+}
+
+func (m *Mutex) Value() interface{} {
+	return &m.mutex
+}
+
+func (m *Mutex) String() *String {
+	return &String{value: "<mutex>"}
+}
+
+func (m *Mutex) Equal(other Object) (Object, error) {
+	return &Boolean{value: m == other}, nil
+}
+
+func (m *Mutex) NotEqual(other Object) (Object, error) {
+	return &Boolean{value: m != other}, nil
+}
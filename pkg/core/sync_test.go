@@ -0,0 +1,59 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSyncModule(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected Object
+		err      string
+	}{
+		{
+			name:     "test wait group waits for goroutines",
+			input:    "wg = sync.waitGroup()\nresults = make_chan(5)\nfunc work(n) {\n defer wg.done()\n results.send(n * n)\n}\nfor i := 0; i < 5; i++ {\n wg.add(1)\n go work(i)\n}\nwg.wait()\nresults.close()\ntotal = 0\nfor v := range results {\n total = total + v\n}\ntotal",
+			expected: &Integer{value: 30},
+		},
+		{
+			name:     "test mutex guards across blocking calls",
+			input:    "import \"time\"\nmu = sync.mutex()\nwg = sync.waitGroup()\ncount = 0\nfunc inc() {\n mu.lock()\n c = count\n time.sleep(0.001)\n count = c + 1\n mu.unlock()\n wg.done()\n}\nfor i := 0; i < 10; i++ {\n wg.add(1)\n go inc()\n}\nwg.wait()\ncount",
+			expected: &Integer{value: 10},
+		},
+		{
+			name:     "test try lock",
+			input:    "mu = sync.mutex()\n[mu.tryLock(), mu.tryLock()]",
+			expected: &Array{Elements: []Object{&Boolean{value: true}, &Boolean{value: false}}},
+		},
+		{
+			name:  "test unlock of unlocked mutex",
+			input: "sync.mutex().unlock()",
+			err:   "unlock of unlocked mutex",
+		},
+		{
+			name:  "test negative wait group counter",
+			input: "sync.waitGroup().done()",
+			err:   "negative wait group counter",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := evalInput("import \"sync\"\n" + test.input)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,740 @@
+package core
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// processStart is the reading monotonic() counts from.
+var processStart = time.Now()
+
+// timeModule builds the native time module.
+func timeModule(e *Evaluator) map[string]Object {
+	functions := map[string]func([]Object) (Object, error){
+		"now":           timeNow,
+		"monotonic":     timeMonotonic,
+		"since":         timeSince,
+		"duration":      timeDuration,
+		"date":          timeDate,
+		"parse":         timeParse,
+		"strptime":      timeStrptime,
+		"fromUnix":      timeFromUnix,
+		"fromUnixMilli": timeFromUnixMilli,
+	}
+	module := map[string]Object{}
+	for name, fn := range functions {
+		module[name] = &GoFunction{Name: name, Func: fn}
+	}
+	module["sleep"] = &GoFunction{Name: "sleep", EvalFunc: (*Evaluator).timeSleep}
+	module["after"] = &GoFunction{Name: "after", EvalFunc: (*Evaluator).timeAfter}
+
+	durations := map[string]time.Duration{
+		"nanosecond":  time.Nanosecond,
+		"microsecond": time.Microsecond,
+		"millisecond": time.Millisecond,
+		"second":      time.Second,
+		"minute":      time.Minute,
+		"hour":        time.Hour,
+	}
+	for name, d := range durations {
+		module[name] = newDuration(d)
+	}
+
+	layouts := map[string]string{
+		"RFC3339":     time.RFC3339,
+		"RFC3339Nano": time.RFC3339Nano,
+		"RFC1123":     time.RFC1123,
+		"RFC822":      time.RFC822,
+		"Kitchen":     time.Kitchen,
+		"DateTime":    time.DateTime,
+		"DateOnly":    time.DateOnly,
+		"TimeOnly":    time.TimeOnly,
+	}
+	for name, layout := range layouts {
+		module[name] = &String{value: layout}
+	}
+	return module
+}
+
+// timeMethods is the method table of Time.
+func timeMethods() map[string]*GoFunction {
+	return map[string]*GoFunction{
+		"format":   {Name: "format", Func: timeFormat},
+		"strftime": {Name: "strftime", Func: timeStrftime},
+		"in":       {Name: "in", Func: timeIn},
+		"utc":      {Name: "utc", Func: timeUTC},
+		"local":    {Name: "local", Func: timeLocal},
+		"truncate": {Name: "truncate", Func: timeTruncate},
+	}
+}
+
+func timeArg(name string, args []Object, min, max int) (*Time, error) {
+	if err := checkArgs(name, args, min, max); err != nil {
+		return nil, err
+	}
+	t, ok := args[0].(*Time)
+	if !ok {
+		return nil, newTypeError("%s() expects a time, got %s", name, args[0].Type())
+	}
+	return t, nil
+}
+
+func durationArg(name string, args []Object, i int) (time.Duration, error) {
+	d, ok := args[i].(*Duration)
+	if !ok {
+		return 0, newTypeError("%s() expects a duration, got %s", name, args[i].Type())
+	}
+	return d.duration, nil
+}
+
+// loadLocation finds a time zone in the system tzdata. "UTC" and "Local"
+// are always available.
+func loadLocation(name string) (*time.Location, error) {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, newRuntimeError(VALUE_ERROR, "unknown time zone %q", name)
+	}
+	return location, nil
+}
+
+// locationArg reads the optional time zone argument, the local zone when it
+// is missing.
+func locationArg(name string, args []Object, i int) (*time.Location, error) {
+	if len(args) <= i {
+		return time.Local, nil
+	}
+	zone, err := stringArg(name, args, i)
+	if err != nil {
+		return nil, err
+	}
+	return loadLocation(zone)
+}
+
+func timeNow(args []Object) (Object, error) {
+	if err := checkArgs("now", args, 0, 0); err != nil {
+		return &Nil{}, err
+	}
+	return newTime(time.Now()), nil
+}
+
+// timeMonotonic returns the time elapsed since the interpreter started as
+// read from the monotonic clock, unaffected by changes to the wall clock.
+func timeMonotonic(args []Object) (Object, error) {
+	if err := checkArgs("monotonic", args, 0, 0); err != nil {
+		return &Nil{}, err
+	}
+	return newDuration(time.Since(processStart)), nil
+}
+
+func timeSince(args []Object) (Object, error) {
+	t, err := timeArg("since", args, 1, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	return newDuration(time.Since(t.time)), nil
+}
+
+// timeSleep pauses for a duration or a number of seconds.
+func (e *Evaluator) timeSleep(args []Object) (Object, error) {
+	if err := checkArgs("sleep", args, 1, 1); err != nil {
+		return &Nil{}, err
+	}
+	d, err := secondsArg("sleep", args[0])
+	if err != nil {
+		return &Nil{}, err
+	}
+	err = e.wait(func(stop <-chan struct{}) {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-stop:
+		}
+	})
+	return &Nil{}, err
+}
+
+// timeAfter is after(d), a channel receiving the time once d, a duration or
+// a number of seconds, has passed. It gives select a timeout.
+func (e *Evaluator) timeAfter(args []Object) (Object, error) {
+	if err := checkArgs("after", args, 1, 1); err != nil {
+		return &Nil{}, err
+	}
+	d, err := secondsArg("after", args[0])
+	if err != nil {
+		return &Nil{}, err
+	}
+	ch := newChannel(e.interpreter, 1)
+	time.AfterFunc(d, func() {
+		// the script may have closed the channel or filled it meanwhile,
+		// the time is then dropped. The lock orders the send after close.
+		ch.interpreter.lock.Lock()
+		defer ch.interpreter.lock.Unlock()
+		var err error
+		defer closedChannelError(&err)
+		select {
+		case ch.ch <- newTime(time.Now()):
+		default:
+		}
+	})
+	return ch, nil
+}
+
+// timeDuration parses a duration such as "1h30m" or "250ms".
+func timeDuration(args []Object) (Object, error) {
+	values, err := stringArgs("duration", args, 1, 1, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	d, err := time.ParseDuration(values[0])
+	if err != nil {
+		return &Nil{}, newRuntimeError(VALUE_ERROR, "invalid duration %q", values[0])
+	}
+	return newDuration(d), nil
+}
+
+// timeDate is date(year, month, day, hour, minute, second, nanosecond, zone)
+// where everything after the day is optional.
+func timeDate(args []Object) (Object, error) {
+	if err := checkArgs("date", args, 3, 8); err != nil {
+		return &Nil{}, err
+	}
+	fields := make([]int, 7)
+	for i := 0; i < len(args) && i < len(fields); i++ {
+		field, err := intArg("date", args, i)
+		if err != nil {
+			return &Nil{}, err
+		}
+		fields[i] = field
+	}
+	location, err := locationArg("date", args, 7)
+	if err != nil {
+		return &Nil{}, err
+	}
+	t := time.Date(fields[0], time.Month(fields[1]), fields[2], fields[3], fields[4], fields[5], fields[6], location)
+	return newTime(t), nil
+}
+
+// timeParse is parse(layout, value) or parse(layout, value, zone) using a
+// Go layout. The zone applies when the value does not give an offset.
+func timeParse(args []Object) (Object, error) {
+	values, err := stringArgs("parse", args, 2, 3, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	location, err := locationArg("parse", args, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	return parseTime(values[0], values[1], location)
+}
+
+// timeStrptime is parse with a strftime pattern such as "%Y-%m-%d".
+func timeStrptime(args []Object) (Object, error) {
+	values, err := stringArgs("strptime", args, 2, 3, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	location, err := locationArg("strptime", args, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	var layout strings.Builder
+	err = splitStrftime(values[0], func(literal string, directive rune) error {
+		layout.WriteString(literal)
+		if directive == 0 {
+			return nil
+		}
+		return writeStrftimeLayout(&layout, directive)
+	})
+	if err != nil {
+		return &Nil{}, err
+	}
+	return parseTime(layout.String(), values[1], location)
+}
+
+func parseTime(layout, value string, location *time.Location) (Object, error) {
+	t, err := time.ParseInLocation(layout, value, location)
+	if err != nil {
+		return &Nil{}, newRuntimeError(VALUE_ERROR, "cannot parse %q as time: %s", value, err.Error())
+	}
+	return newTime(t), nil
+}
+
+// timeFromUnix is fromUnix(seconds) or fromUnix(seconds, nanoseconds).
+func timeFromUnix(args []Object) (Object, error) {
+	if err := checkArgs("fromUnix", args, 1, 2); err != nil {
+		return &Nil{}, err
+	}
+	seconds, err := intArg("fromUnix", args, 0)
+	if err != nil {
+		return &Nil{}, err
+	}
+	nanoseconds := 0
+	if len(args) == 2 {
+		if nanoseconds, err = intArg("fromUnix", args, 1); err != nil {
+			return &Nil{}, err
+		}
+	}
+	return newTime(time.Unix(int64(seconds), int64(nanoseconds))), nil
+}
+
+func timeFromUnixMilli(args []Object) (Object, error) {
+	if err := checkArgs("fromUnixMilli", args, 1, 1); err != nil {
+		return &Nil{}, err
+	}
+	milliseconds, err := intArg("fromUnixMilli", args, 0)
+	if err != nil {
+		return &Nil{}, err
+	}
+	return newTime(time.UnixMilli(int64(milliseconds))), nil
+}
+
+// timeFormat formats a time with a Go layout such as time.RFC3339.
+func timeFormat(args []Object) (Object, error) {
+	t, err := timeArg("format", args, 2, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	layout, err := stringArg("format", args, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	return &String{value: t.time.Format(layout)}, nil
+}
+
+func timeStrftime(args []Object) (Object, error) {
+	t, err := timeArg("strftime", args, 2, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	pattern, err := stringArg("strftime", args, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	var out strings.Builder
+	err = splitStrftime(pattern, func(literal string, directive rune) error {
+		// directives are formatted one at a time so literal text is never
+		// mistaken for part of a Go layout
+		out.WriteString(literal)
+		if directive == 0 {
+			return nil
+		}
+		// Go only formats zeros as a fraction right after a . or , so the
+		// microseconds are written here
+		if directive == 'f' {
+			fmt.Fprintf(&out, "%06d", t.time.Nanosecond()/1000)
+			return nil
+		}
+		var layout strings.Builder
+		if err := writeStrftimeLayout(&layout, directive); err != nil {
+			return err
+		}
+		out.WriteString(t.time.Format(layout.String()))
+		return nil
+	})
+	if err != nil {
+		return &Nil{}, err
+	}
+	return &String{value: out.String()}, nil
+}
+
+// strftimeLayouts maps strftime directives to Go layout elements.
+var strftimeLayouts = map[rune]string{
+	'a': "Mon",
+	'A': "Monday",
+	'b': "Jan",
+	'B': "January",
+	'd': "02",
+	'e': "_2",
+	'F': "2006-01-02",
+	'H': "15",
+	'I': "03",
+	'j': "002",
+	'm': "01",
+	'M': "04",
+	'p': "PM",
+	'S': "05",
+	'T': "15:04:05",
+	'y': "06",
+	'Y': "2006",
+	'z': "-0700",
+	'Z': "MST",
+	'%': "%",
+}
+
+// splitStrftime calls fn with each run of literal text and the directive
+// following it, the last call has no directive.
+func splitStrftime(pattern string, fn func(literal string, directive rune) error) error {
+	runes := []rune(pattern)
+	start := 0
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '%' {
+			continue
+		}
+		if i+1 == len(runes) {
+			return newRuntimeError(VALUE_ERROR, "strftime pattern ends with a lone %%")
+		}
+		if err := fn(string(runes[start:i]), runes[i+1]); err != nil {
+			return err
+		}
+		i++
+		start = i + 1
+	}
+	return fn(string(runes[start:]), 0)
+}
+
+func writeStrftimeLayout(layout *strings.Builder, directive rune) error {
+	// %f is the microseconds, which strptime reads as a fraction of the
+	// seconds when it follows them after a . or ,
+	if directive == 'f' {
+		layout.WriteString("000000")
+		return nil
+	}
+	element, ok := strftimeLayouts[directive]
+	if !ok {
+		return newRuntimeError(VALUE_ERROR, "unsupported strftime directive %%%c", directive)
+	}
+	layout.WriteString(element)
+	return nil
+}
+
+// timeIn converts a time to the named zone, such as "Europe/London".
+func timeIn(args []Object) (Object, error) {
+	t, err := timeArg("in", args, 2, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	location, err := locationArg("in", args, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	return newTime(t.time.In(location)), nil
+}
+
+func timeUTC(args []Object) (Object, error) {
+	t, err := timeArg("utc", args, 1, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	return newTime(t.time.UTC()), nil
+}
+
+func timeLocal(args []Object) (Object, error) {
+	t, err := timeArg("local", args, 1, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	return newTime(t.time.Local()), nil
+}
+
+// timeTruncate rounds a time down to a multiple of a duration.
+func timeTruncate(args []Object) (Object, error) {
+	t, err := timeArg("truncate", args, 2, 2)
+	if err != nil {
+		return &Nil{}, err
+	}
+	d, err := durationArg("truncate", args, 1)
+	if err != nil {
+		return &Nil{}, err
+	}
+	return newTime(t.time.Truncate(d)), nil
+}
+
+// Time is an instant with a time zone. Times returned by now() carry a
+// monotonic clock reading, so subtracting them is not affected by changes
+// to the wall clock.
+type Time struct {
+	nativeObject
+	time time.Time
+}
+
+func newTime(t time.Time) *Time {
+	return &Time{nativeObject: nativeObject{"time"}, time: t}
+}
+
+func (t *Time) GetAttribute(name string) (Object, bool) {
+	switch name {
+	case "year":
+		return &Integer{value: int64(t.time.Year())}, true
+	case "month":
+		return &Integer{value: int64(t.time.Month())}, true
+	case "day":
+		return &Integer{value: int64(t.time.Day())}, true
+	case "hour":
+		return &Integer{value: int64(t.time.Hour())}, true
+	case "minute":
+		return &Integer{value: int64(t.time.Minute())}, true
+	case "second":
+		return &Integer{value: int64(t.time.Second())}, true
+	case "nanosecond":
+		return &Integer{value: int64(t.time.Nanosecond())}, true
+	case "weekday":
+		return &String{value: t.time.Weekday().String()}, true
+	case "yearDay":
+		return &Integer{value: int64(t.time.YearDay())}, true
+	case "unix":
+		return &Integer{value: t.time.Unix()}, true
+	case "unixMilli":
+		return &Integer{value: t.time.UnixMilli()}, true
+	case "unixNano":
+		return &Integer{value: t.time.UnixNano()}, true
+	case "zone":
+		name, _ := t.time.Zone()
+		return &String{value: name}, true
+	case "offset":
+		_, offset := t.time.Zone()
+		return &Integer{value: int64(offset)}, true
+	}
+	return nil, false
+}
+
+func (t *Time) Type() string {
+	return "time"
+}
+
+func (t *Time) Value() interface{} {
+	return t.time
+}
+
+func (t *Time) String() *String {
+	return &String{value: t.time.Format(time.RFC3339Nano)}
+}
+
+// Add moves a time forward by a duration.
+func (t *Time) Add(other Object) (Object, error) {
+	if d, ok := other.(*Duration); ok {
+		return newTime(t.time.Add(d.duration)), nil
+	}
+	return nil, newTypeError("Invalid type: cannot perform addition operation with %s and %s", t.Type(), other.Type())
+}
+
+// Sub gives the duration between two times, or moves a time back by a
+// duration.
+func (t *Time) Sub(other Object) (Object, error) {
+	switch other := other.(type) {
+	case *Time:
+		return newDuration(t.time.Sub(other.time)), nil
+	case *Duration:
+		return newTime(t.time.Add(-other.duration)), nil
+	}
+	return nil, newTypeError("Invalid type: cannot perform subtraction operation with %s and %s", t.Type(), other.Type())
+}
+
+// compare orders two times, it fails for anything but a time.
+func (t *Time) compare(other Object) (int, error) {
+	otherTime, ok := other.(*Time)
+	if !ok {
+		return 0, newTypeError("Invalid type: cannot compare %s with %s", t.Type(), other.Type())
+	}
+	return t.time.Compare(otherTime.time), nil
+}
+
+func (t *Time) Equal(other Object) (Object, error) {
+	result, err := t.compare(other)
+	if err != nil {
+		return nil, err
+	}
+	return &Boolean{value: result == 0}, nil
+}
+
+func (t *Time) NotEqual(other Object) (Object, error) {
+	result, err := t.compare(other)
+	if err != nil {
+		return nil, err
+	}
+	return &Boolean{value: result != 0}, nil
+}
+
+func (t *Time) GreaterThan(other Object) (Object, error) {
+	result, err := t.compare(other)
+	if err != nil {
+		return nil, err
+	}
+	return &Boolean{value: result > 0}, nil
+}
+
+func (t *Time) LessThan(other Object) (Object, error) {
+	result, err := t.compare(other)
+	if err != nil {
+		return nil, err
+	}
+	return &Boolean{value: result < 0}, nil
+}
+
+func (t *Time) GreaterThanOrEqual(other Object) (Object, error) {
+	result, err := t.compare(other)
+	if err != nil {
+		return nil, err
+	}
+	return &Boolean{value: result >= 0}, nil
+}
+
+func (t *Time) LessThanOrEqual(other Object) (Object, error) {
+	result, err := t.compare(other)
+	if err != nil {
+		return nil, err
+	}
+	return &Boolean{value: result <= 0}, nil
+}
+
+// Duration is the time elapsed between two instants, built from the
+// time.second style constants or time.duration("1h30m").
+type Duration struct {
+	nativeObject
+	duration time.Duration
+}
+
+func newDuration(d time.Duration) *Duration {
+	return &Duration{nativeObject: nativeObject{"duration"}, duration: d}
+}
+
+func (d *Duration) GetAttribute(name string) (Object, bool) {
+	switch name {
+	case "hours":
+		return &Float{value: d.duration.Hours()}, true
+	case "minutes":
+		return &Float{value: d.duration.Minutes()}, true
+	case "seconds":
+		return &Float{value: d.duration.Seconds()}, true
+	case "milliseconds":
+		return &Integer{value: d.duration.Milliseconds()}, true
+	case "microseconds":
+		return &Integer{value: d.duration.Microseconds()}, true
+	case "nanoseconds":
+		return &Integer{value: d.duration.Nanoseconds()}, true
+	}
+	return nil, false
+}
+
+func (d *Duration) Type() string {
+	return "duration"
+}
+
+func (d *Duration) Value() interface{} {
+	return d.duration
+}
+
+func (d *Duration) String() *String {
+	return &String{value: d.duration.String()}
+}
+
+func (d *Duration) Add(other Object) (Object, error) {
+	switch other := other.(type) {
+	case *Duration:
+		return newDuration(d.duration + other.duration), nil
+	case *Time:
+		return other.Add(d)
+	}
+	return nil, newTypeError("Invalid type: cannot perform addition operation with %s and %s", d.Type(), other.Type())
+}
+
+func (d *Duration) Sub(other Object) (Object, error) {
+	if otherDuration, ok := other.(*Duration); ok {
+		return newDuration(d.duration - otherDuration.duration), nil
+	}
+	return nil, newTypeError("Invalid type: cannot perform subtraction operation with %s and %s", d.Type(), other.Type())
+}
+
+// Multiply scales a duration by a number.
+func (d *Duration) Multiply(other Object) (Object, error) {
+	switch other := other.(type) {
+	case *Integer:
+		return newDuration(d.duration * time.Duration(other.value)), nil
+	case *Float:
+		return newDuration(time.Duration(math.Round(float64(d.duration) * other.value))), nil
+	}
+	return nil, newTypeError("Invalid type: cannot perform multiplication operation with %s and %s", d.Type(), other.Type())
+}
+
+// Divide by a number gives a duration, by another duration it gives their
+// ratio as a float.
+func (d *Duration) Divide(other Object) (Object, error) {
+	switch other := other.(type) {
+	case *Duration:
+		if other.duration == 0 {
+			return nil, newZeroDivisionError()
+		}
+		return &Float{value: float64(d.duration) / float64(other.duration)}, nil
+	case *Integer:
+		if other.value == 0 {
+			return nil, newZeroDivisionError()
+		}
+		return newDuration(d.duration / time.Duration(other.value)), nil
+	case *Float:
+		if other.value == 0 {
+			return nil, newZeroDivisionError()
+		}
+		return newDuration(time.Duration(math.Round(float64(d.duration) / other.value))), nil
+	}
+	return nil, newTypeError("Invalid type: cannot perform division operation with %s and %s", d.Type(), other.Type())
+}
+
+func (d *Duration) Modulo(other Object) (Object, error) {
+	if otherDuration, ok := other.(*Duration); ok {
+		if otherDuration.duration == 0 {
+			return nil, newZeroDivisionError()
+		}
+		return newDuration(d.duration % otherDuration.duration), nil
+	}
+	return nil, newTypeError("Invalid type: cannot perform modulo operation with %s and %s", d.Type(), other.Type())
+}
+
+func (d *Duration) compare(other Object) (int, error) {
+	otherDuration, ok := other.(*Duration)
+	if !ok {
+		return 0, newTypeError("Invalid type: cannot compare %s with %s", d.Type(), other.Type())
+	}
+	return cmp.Compare(d.duration, otherDuration.duration), nil
+}
+
+func (d *Duration) Equal(other Object) (Object, error) {
+	result, err := d.compare(other)
+	if err != nil {
+		return nil, err
+	}
+	return &Boolean{value: result == 0}, nil
+}
+
+func (d *Duration) NotEqual(other Object) (Object, error) {
+	result, err := d.compare(other)
+	if err != nil {
+		return nil, err
+	}
+	return &Boolean{value: result != 0}, nil
+}
+
+func (d *Duration) GreaterThan(other Object) (Object, error) {
+	result, err := d.compare(other)
+	if err != nil {
+		return nil, err
+	}
+	return &Boolean{value: result > 0}, nil
+}
+
+func (d *Duration) LessThan(other Object) (Object, error) {
+	result, err := d.compare(other)
+	if err != nil {
+		return nil, err
+	}
+	return &Boolean{value: result < 0}, nil
+}
+
+func (d *Duration) GreaterThanOrEqual(other Object) (Object, error) {
+	result, err := d.compare(other)
+	if err != nil {
+		return nil, err
+	}
+	return &Boolean{value: result >= 0}, nil
+}
+
+func (d *Duration) LessThanOrEqual(other Object) (Object, error) {
+	result, err := d.compare(other)
+	if err != nil {
+		return nil, err
+	}
+	return &Boolean{value: result <= 0}, nil
+}
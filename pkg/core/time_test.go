@@ -0,0 +1,160 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTimeModule(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected Object
+		err      string
+	}{
+		{
+			name:     "test date and format",
+			input:    "t = time.date(2024, 3, 9, 14, 5, 0, 0, \"UTC\")\nt.format(time.RFC3339)",
+			expected: &String{value: "2024-03-09T14:05:00Z"},
+		},
+		{
+			name:     "test strftime",
+			input:    "t = time.date(2024, 3, 9, 14, 5, 7, 0, \"UTC\")\nt.strftime(\"%A %d %B %Y, %H:%M:%S (day %j) 100%%\")",
+			expected: &String{value: "Saturday 09 March 2024, 14:05:07 (day 069) 100%"},
+		},
+		{
+			name:     "test strftime keeps literal text",
+			input:    "time.date(2006, 1, 2, 0, 0, 0, 0, \"UTC\").strftime(\"Mon 2006: %Y\")",
+			expected: &String{value: "Mon 2006: 2006"},
+		},
+		{
+			name:     "test strftime microseconds",
+			input:    "t = time.date(2024, 1, 2, 3, 4, 5, 123456789, \"UTC\")\n[t.strftime(\"%H:%M:%S.%f\"), t.strftime(\"%f%S\")]",
+			expected: newStringArray([]string{"03:04:05.123456", "12345605"}),
+		},
+		{
+			name:     "test strptime microseconds",
+			input:    "t = time.strptime(\"%H:%M:%S.%f\", \"03:04:05.123456\", \"UTC\")\nt.strftime(\"%f\")",
+			expected: &String{value: "123456"},
+		},
+		{
+			name:  "test strftime unsupported directive",
+			input: "time.now().strftime(\"%Q\")",
+			err:   "unsupported strftime directive %Q",
+		},
+		{
+			name:     "test parse with layout",
+			input:    "t = time.parse(time.DateTime, \"2024-03-09 14:05:00\", \"UTC\")\n[t.year, t.month, t.day, t.hour]",
+			expected: &Array{Elements: []Object{&Integer{value: 2024}, &Integer{value: 3}, &Integer{value: 9}, &Integer{value: 14}}},
+		},
+		{
+			name:     "test strptime",
+			input:    "time.strptime(\"%d/%m/%Y %H:%M:%S.%f\", \"09/03/2024 14:05:00.250000\", \"UTC\").unixMilli",
+			expected: &Integer{value: 1709993100250},
+		},
+		{
+			name:  "test parse invalid time",
+			input: "time.parse(time.DateOnly, \"tomorrow\")",
+			err:   "cannot parse \"tomorrow\" as time: parsing time \"tomorrow\" as \"2006-01-02\": cannot parse \"tomorrow\" as \"2006\"",
+		},
+		{
+			name:     "test time zones",
+			input:    "t = time.date(2024, 7, 1, 12, 0, 0, 0, \"UTC\").in(\"America/New_York\")\n[t.hour, t.zone, t.offset]",
+			expected: &Array{Elements: []Object{&Integer{value: 8}, &String{value: "EDT"}, &Integer{value: -4 * 3600}}},
+		},
+		{
+			name:  "test unknown time zone",
+			input: "time.now().in(\"Nowhere/City\")",
+			err:   "unknown time zone \"Nowhere/City\"",
+		},
+		{
+			name:     "test unix round trip",
+			input:    "t = time.fromUnix(1700000000).utc()\n[t.unix, t.format(time.DateOnly), time.fromUnixMilli(t.unixMilli) == t]",
+			expected: &Array{Elements: []Object{&Integer{value: 1700000000}, &String{value: "2023-11-14"}, &Boolean{value: true}}},
+		},
+		{
+			name:     "test subtracting times gives a duration",
+			input:    "t1 = time.date(2024, 1, 1, 0, 0, 0, 0, \"UTC\")\nt2 = time.date(2024, 1, 2, 1, 30, 0, 0, \"UTC\")\nd = t2 - t1\n[d == time.duration(\"25h30m\"), d.hours]",
+			expected: &Array{Elements: []Object{&Boolean{value: true}, &Float{value: 25.5}}},
+		},
+		{
+			name:     "test time plus duration",
+			input:    "t = time.date(2024, 2, 28, 23, 0, 0, 0, \"UTC\") + 2 * time.hour\nt.format(time.DateTime)",
+			expected: &String{value: "2024-02-29 01:00:00"},
+		},
+		{
+			name:     "test time comparison",
+			input:    "t = time.now()\n[t < t + time.second, t == t - time.duration(\"0s\"), t > t]",
+			expected: &Array{Elements: []Object{&Boolean{value: true}, &Boolean{value: true}, &Boolean{value: false}}},
+		},
+		{
+			name:     "test duration arithmetic",
+			input:    "d = time.duration(\"1h30m\")\n[(d + time.minute * 30).minutes, d / time.minute, d % time.hour == 30 * time.minute]",
+			expected: &Array{Elements: []Object{&Float{value: 120}, &Float{value: 90}, &Boolean{value: true}}},
+		},
+		{
+			name:  "test invalid duration",
+			input: "time.duration(\"soon\")",
+			err:   "invalid duration \"soon\"",
+		},
+		{
+			name:  "test duration division by zero",
+			input: "time.second / 0",
+			err:   "Division by zero",
+		},
+		{
+			name:  "test comparing time with integer",
+			input: "time.now() < 1",
+			err:   "Invalid type: cannot compare time with integer",
+		},
+		{
+			name:     "test sleep and monotonic clock",
+			input:    "start = time.monotonic()\ntime.sleep(time.millisecond * 5)\ntime.monotonic() - start >= time.millisecond * 5",
+			expected: &Boolean{value: true},
+		},
+		{
+			name:     "test after channel closed before it fires",
+			input:    "c = time.after(0.01)\nc.close()\ntime.sleep(0.05)\nc.recv()",
+			expected: &Nil{},
+		},
+		{
+			name:     "test after channel filled before it fires",
+			input:    "c = time.after(0.01)\nc.send(1)\ntime.sleep(0.05)\n[c.recv(), c.len]",
+			expected: &Array{Elements: []Object{&Integer{value: 1}, &Integer{value: 0}}},
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := evalInput("import \"time\"\n" + test.input)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestTimeNow(t *testing.T) {
+	before := time.Now()
+	result, err := evalInput("import \"time\"\ntime.now()")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now, ok := result.(*Time)
+	if !ok {
+		t.Fatalf("expected a time, got %v", result)
+	}
+	if now.time.Before(before) || now.time.After(time.Now()) {
+		t.Fatalf("now() returned %v, outside the time of the call", now.time)
+	}
+}
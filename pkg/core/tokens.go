@@ -11,6 +11,7 @@ const (
 	// Literals
 	IDENT  // main, foo, bar, x, y, etc.
 	INT    // int
+	BIGINT // 123n
 	FLOAT  // 123.456
 	STRING // "abc", 'abc'
 	BOOL   // true
@@ -73,6 +74,17 @@ const (
 	CALL
 	ASYNC
 	AWAIT
+	THROW
+	TRY
+	CATCH
+	FINALLY
+	DEFER
+	RANGE
+	GO
+	SELECT
+	CASE
+	DEFAULT
+	YIELD
 )
 
 var keywordLookup = map[string]TokenType{
@@ -93,6 +105,17 @@ var keywordLookup = map[string]TokenType{
 	"struct":   STRUCT,
 	"async":    ASYNC,
 	"await":    AWAIT,
+	"throw":    THROW,
+	"try":      TRY,
+	"catch":    CATCH,
+	"finally":  FINALLY,
+	"defer":    DEFER,
+	"range":    RANGE,
+	"go":       GO,
+	"select":   SELECT,
+	"case":     CASE,
+	"default":  DEFAULT,
+	"yield":    YIELD,
 }
 
 type Token struct {
@@ -112,6 +135,7 @@ var TokenTypeStr = map[TokenType]string{
 	WS:          "WS",
 	IDENT:       "IDENT",
 	INT:         "INT",
+	BIGINT:      "BIGINT",
 	FLOAT:       "FLOAT",
 	STRING:      "STRING",
 	ARRAY:       "ARRAY",
@@ -158,4 +182,15 @@ var TokenTypeStr = map[TokenType]string{
 	FALSE:       "FALSE",
 	NEWLINE:     "NEWLINE",
 	CALL:        "CALL",
+	THROW:       "THROW",
+	TRY:         "TRY",
+	CATCH:       "CATCH",
+	FINALLY:     "FINALLY",
+	DEFER:       "DEFER",
+	RANGE:       "RANGE",
+	GO:          "GO",
+	SELECT:      "SELECT",
+	CASE:        "CASE",
+	DEFAULT:     "DEFAULT",
+	YIELD:       "YIELD",
 }
@@ -0,0 +1,395 @@
+package core
+
+import (
+	"fmt"
+)
+
+// SetVM selects the backend running programs, the bytecode virtual machine
+// when enabled or the tree walker otherwise. Both give the same results.
+func (e *Evaluator) SetVM(enabled bool) {
+	e.vm = enabled
+}
+
+// execute runs a program, or a statement of one, on the backend the
+// evaluator is set to use. Nodes the compiler hands to the tree walker run
+// on it entirely, only function bodies are compiled again.
+func (e *Evaluator) execute(node Node) (Object, error) {
+	recordClosures(node, map[string]bool{}, e.closures)
+	// the global table of a module is the frame it runs in
+	globals := e.globals.scope
+	if frame := e.functionFrame(); frame != nil {
+		globals = frame.scope
+	}
+	if !e.vm {
+		return e.walk(node, resolveProgram(node), nil, globals)
+	}
+	chunk, err := compileProgram(node)
+	if err != nil {
+		return e.walk(node, resolveProgram(node), nil, globals)
+	}
+	value, returned, err := e.runChunk(chunk, nil, globals)
+	if err != nil {
+		return &Nil{}, err
+	}
+	if returned {
+		return &Nil{}, &returnSignal{value: value}
+	}
+	return value, nil
+}
+
+// runFunction runs the body of fn in the frame callFunction pushed for it.
+// Bodies are compiled the first time they run.
+func (e *Evaluator) runFunction(fn *Function, args []Object) (Object, error) {
+	chunk, ok := e.chunks[fn.Body]
+	if !ok {
+		// a body too large to compile is left to the tree walker
+		chunk, _ = compileFunction(fn.Body, fn.Arguments, e.closures[fn.Body])
+		e.chunks[fn.Body] = chunk
+	}
+	if chunk == nil {
+		return e.walkFunction(fn, args)
+	}
+
+	slots := newSlots(chunk.slots, chunk.params, args)
+	value, returned, err := e.runChunk(chunk, slots, e.closureGlobals())
+	if err != nil || !returned {
+		return &Nil{}, err
+	}
+	return value, nil
+}
+
+// binaryOperations maps the binary instructions onto the Object methods
+// implementing them.
+var binaryOperations = [...]func(Object, Object) (Object, error){
+	OP_ADD:           Object.Add,
+	OP_SUB:           Object.Sub,
+	OP_MULTIPLY:      Object.Multiply,
+	OP_DIVIDE:        Object.Divide,
+	OP_MODULO:        Object.Modulo,
+	OP_EQUAL:         Object.Equal,
+	OP_NOT_EQUAL:     Object.NotEqual,
+	OP_GREATER:       Object.GreaterThan,
+	OP_LESS:          Object.LessThan,
+	OP_GREATER_EQUAL: Object.GreaterThanOrEqual,
+	OP_LESS_EQUAL:    Object.LessThanOrEqual,
+}
+
+// hasOperand marks the instructions followed by an operand.
+var hasOperand = [...]bool{
+	OP_CONSTANT:            true,
+	OP_GET_LOCAL:           true,
+	OP_SET_LOCAL:           true,
+	OP_GET_NAME:            true,
+	OP_GET_FUNCTION:        true,
+	OP_GET_GLOBAL:          true,
+	OP_GET_GLOBAL_FUNCTION: true,
+	OP_SET_NAME:            true,
+	OP_DECLARE_NAME:        true,
+	OP_ATTRIBUTE:           true,
+	OP_SLICE:               true,
+	OP_ARRAY:               true,
+	OP_MAP:                 true,
+	OP_JUMP:                true,
+	OP_JUMP_IF_FALSE:       true,
+	OP_RANGE:               true,
+	OP_RANGE_NEXT:          true,
+	OP_CALL:                true,
+	OP_TAIL_CALL:           true,
+	OP_CLOSURE:             true,
+	OP_EVAL:                true,
+}
+
+// rangeState is the iterator of a range loop, kept on the stack while the
+// loop runs.
+type rangeState struct {
+	nativeObject
+	next func() (key, element Object, ok bool, err error)
+	pair bool
+}
+
+func (r *rangeState) Type() string {
+	return "iterator"
+}
+
+func (r *rangeState) Value() interface{} {
+	return r
+}
+
+func (r *rangeState) String() *String {
+	return &String{value: "<iterator>"}
+}
+
+func (r *rangeState) Equal(other Object) (Object, error) {
+	return &Boolean{value: r == other}, nil
+}
+
+func (r *rangeState) NotEqual(other Object) (Object, error) {
+	return &Boolean{value: r != other}, nil
+}
+
+func (e *Evaluator) push(value Object) {
+	e.stack = append(e.stack, value)
+}
+
+func (e *Evaluator) pop() Object {
+	value := e.stack[len(e.stack)-1]
+	e.stack[len(e.stack)-1] = nil
+	e.stack = e.stack[:len(e.stack)-1]
+	return value
+}
+
+// runChunk runs chunk in the current frame with slots holding its local
+// variables and globals being its global table. The value is what a return
+// statement returned, in which case returned is set, or else the value left
+// on the stack by a program. An error unwinds the frames and the stack the
+// chunk pushed.
+func (e *Evaluator) runChunk(chunk *Chunk, slots []Object, globals map[string]Object) (value Object, returned bool, err error) {
+	base, bottom := e.framePointer, len(e.stack)
+	defer func() {
+		for e.framePointer > base {
+			e.popFrame()
+		}
+		clear(e.stack[bottom:])
+		e.stack = e.stack[:bottom]
+	}()
+
+	code := chunk.code
+	for ip := 0; ip < len(code); {
+		if e.limited {
+			if err := e.step(); err != nil {
+				return &Nil{}, false, err
+			}
+		}
+		start := ip
+		op := Opcode(code[ip])
+		ip++
+		var a int
+		if hasOperand[op] {
+			a = int(code[ip])<<8 | int(code[ip+1])
+			ip += 2
+		}
+
+		switch op {
+		case OP_CONSTANT:
+			e.push(chunk.constants[a])
+		case OP_NIL:
+			e.push(&Nil{})
+		case OP_POP:
+			e.pop()
+		case OP_DUP:
+			e.push(e.stack[len(e.stack)-1])
+		case OP_GET_LOCAL:
+			e.push(slots[a])
+		case OP_SET_LOCAL:
+			slots[a] = e.pop()
+		case OP_GET_NAME:
+			variable, err := e.getIdentifier(chunk.names[a])
+			if err != nil {
+				return &Nil{}, false, newRuntimeError(NAME_ERROR, "variable '%s' is not defined", chunk.names[a])
+			}
+			e.push(variable)
+		case OP_GET_FUNCTION:
+			fn, err := e.getIdentifier(chunk.names[a])
+			if err != nil {
+				return &Nil{}, false, newRuntimeError(NAME_ERROR, "function '%s' is not defined", chunk.names[a])
+			}
+			e.push(fn)
+		case OP_GET_GLOBAL, OP_GET_GLOBAL_FUNCTION:
+			name := chunk.names[a]
+			value, ok := globals[name]
+			if !ok {
+				value, ok = e.builtins[name]
+			}
+			if !ok && op == OP_GET_GLOBAL_FUNCTION {
+				return &Nil{}, false, newRuntimeError(NAME_ERROR, "function '%s' is not defined", name)
+			}
+			if !ok {
+				return &Nil{}, false, newRuntimeError(NAME_ERROR, "variable '%s' is not defined", name)
+			}
+			e.push(value)
+		case OP_SET_NAME:
+			e.setIdentifier(chunk.names[a], e.pop())
+		case OP_DECLARE_NAME:
+			e.callStack[e.framePointer].scope[chunk.names[a]] = e.pop()
+		case OP_ATTRIBUTE:
+			attribute, err := e.getAttribute(e.pop(), chunk.names[a])
+			if err != nil {
+				return &Nil{}, false, err
+			}
+			e.push(attribute)
+		case OP_INDEX:
+			index := e.pop()
+			element, err := indexObject(e.pop(), index)
+			if err != nil {
+				return &Nil{}, false, err
+			}
+			e.push(element)
+		case OP_SET_INDEX:
+			index := e.pop()
+			object := e.pop()
+			if err := setIndex(object, index, e.pop()); err != nil {
+				return &Nil{}, false, err
+			}
+		case OP_SLICE:
+			var start, end Object
+			if a&2 != 0 {
+				end = e.pop()
+			}
+			if a&1 != 0 {
+				start = e.pop()
+			}
+			slice, err := e.allocated(sliceObject(e.pop(), start, end))
+			if err != nil {
+				return &Nil{}, false, err
+			}
+			e.push(slice)
+		case OP_ARRAY:
+			elements := make([]Object, a)
+			copy(elements, e.stack[len(e.stack)-a:])
+			clear(e.stack[len(e.stack)-a:])
+			e.stack = e.stack[:len(e.stack)-a]
+			array, err := e.allocated(&Array{Elements: elements}, nil)
+			if err != nil {
+				return &Nil{}, false, err
+			}
+			e.push(array)
+		case OP_MAP_KEY:
+			if _, err := mapKey(e.stack[len(e.stack)-1]); err != nil {
+				return &Nil{}, false, err
+			}
+		case OP_MAP:
+			m := NewMap()
+			pairs := e.stack[len(e.stack)-2*a:]
+			for i := 0; i < len(pairs); i += 2 {
+				key, _ := mapKey(pairs[i]) // checked by OP_MAP_KEY
+				m.Set(key, pairs[i+1])
+			}
+			clear(pairs)
+			e.stack = e.stack[:len(e.stack)-2*a]
+			if _, err := e.allocated(m, nil); err != nil {
+				return &Nil{}, false, err
+			}
+			e.push(m)
+		case OP_ADD, OP_SUB, OP_MULTIPLY, OP_DIVIDE, OP_MODULO, OP_EQUAL, OP_NOT_EQUAL,
+			OP_GREATER, OP_LESS, OP_GREATER_EQUAL, OP_LESS_EQUAL:
+			right := e.pop()
+			result, err := e.allocated(binaryOperations[op](e.pop(), right))
+			if err != nil {
+				return &Nil{}, false, err
+			}
+			e.push(result)
+		case OP_INCREMENT, OP_DECREMENT:
+			operator := "++"
+			if op == OP_DECREMENT {
+				operator = "--"
+			}
+			newVal, err := e.allocated(increment(e.pop(), operator))
+			if err != nil {
+				return &Nil{}, false, err
+			}
+			e.push(newVal)
+		case OP_JUMP:
+			// a jump back is a loop starting its next iteration
+			if a < ip {
+				if err := e.checkpoint(); err != nil {
+					return &Nil{}, false, err
+				}
+			}
+			ip = a
+		case OP_JUMP_IF_FALSE:
+			if !isTruthy(e.pop()) {
+				ip = a
+			}
+		case OP_PUSH_FRAME:
+			if err := e.pushFrame(); err != nil {
+				return &Nil{}, false, err
+			}
+		case OP_POP_FRAME:
+			e.popFrame()
+		case OP_RANGE:
+			next, err := rangeIterator(e.pop(), a == 1)
+			if err != nil {
+				return &Nil{}, false, err
+			}
+			e.push(&rangeState{nativeObject: nativeObject{"iterator"}, next: next, pair: a == 1})
+		case OP_RANGE_NEXT:
+			state := e.stack[len(e.stack)-1].(*rangeState)
+			key, element, ok, err := state.next()
+			if err != nil {
+				return &Nil{}, false, err
+			}
+			if !ok {
+				ip = a
+				continue
+			}
+			if state.pair {
+				e.push(element)
+			}
+			e.push(key)
+		case OP_CALL:
+			args := make([]Object, a)
+			copy(args, e.stack[len(e.stack)-a:])
+			clear(e.stack[len(e.stack)-a:])
+			e.stack = e.stack[:len(e.stack)-a]
+			result, err := e.callObject(e.pop(), args, chunk.lines[start])
+			if err != nil {
+				return &Nil{}, false, err
+			}
+			e.push(result)
+		case OP_TAIL_CALL:
+			args := make([]Object, a)
+			copy(args, e.stack[len(e.stack)-a:])
+			clear(e.stack[len(e.stack)-a:])
+			e.stack = e.stack[:len(e.stack)-a]
+			err := e.returnCall(e.pop(), args, chunk.lines[start])
+			if ret, ok := err.(*returnSignal); ok {
+				return ret.value, true, nil
+			}
+			return &Nil{}, false, err
+		case OP_RETURN:
+			return e.pop(), true, nil
+		case OP_THROW:
+			return &Nil{}, false, throw(e.pop())
+		case OP_CLOSURE:
+			fn, err := e.allocated(e.makeFunction(chunk.nodes[a].(*FunctionLiteral)), nil)
+			if err != nil {
+				return &Nil{}, false, err
+			}
+			e.push(fn)
+		case OP_YIELD:
+			value := e.pop()
+			if e.generator == nil {
+				return &Nil{}, false, fmt.Errorf("'yield' outside generator on line: %d", chunk.lines[start])
+			}
+			sent, err := e.generator.yield(e, value)
+			if err != nil {
+				return &Nil{}, false, err
+			}
+			e.push(sent)
+		case OP_AWAIT:
+			value := e.pop()
+			task, ok := value.(*Task)
+			if !ok {
+				return &Nil{}, false, newTypeError("object of type %s can't be awaited", value.Type())
+			}
+			result, err := task.wait(e)
+			if err != nil {
+				return &Nil{}, false, err
+			}
+			e.push(result)
+		case OP_EVAL:
+			result, err := e.evaluate(chunk.nodes[a])
+			if err != nil {
+				return &Nil{}, false, err
+			}
+			e.push(result)
+		default:
+			return &Nil{}, false, fmt.Errorf("unknown opcode %d", op)
+		}
+	}
+
+	if len(e.stack) > bottom {
+		return e.pop(), false, nil
+	}
+	return &Nil{}, false, nil
+}
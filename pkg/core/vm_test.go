@@ -0,0 +1,131 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVMScopes(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected Object
+		err      string
+	}{
+		{
+			name:     "test loop variable used before its declaration",
+			input:    "func f() {\n total := 0\n for i := 0; i < 3; i++ {\n  if i > 0 {\n   total = total + y\n  }\n  y := i * 10\n }\n return total\n}\nf()",
+			expected: &Integer{value: 10},
+		},
+		{
+			name:     "test declaration in loop shadows",
+			input:    "func f() {\n x := 1\n for i := 0; i < 2; i++ {\n  x := 5\n  x = x + i\n }\n return x\n}\nf()",
+			expected: &Integer{value: 1},
+		},
+		{
+			name:     "test declaration in if reaches the function frame",
+			input:    "func f(a) {\n if a > 0 {\n  a := 100\n }\n return a\n}\nf(1)",
+			expected: &Integer{value: 100},
+		},
+		{
+			name:     "test closure over function local",
+			input:    "func counter() {\n n := 0\n return func() {\n  n++\n  return n\n }\n}\nc = counter()\nc()\nc()",
+			expected: &Integer{value: 2},
+		},
+		{
+			name:     "test return inside try",
+			input:    "func f() {\n try {\n  return 1 / 0\n } catch e {\n  return e.message\n }\n}\nf()",
+			expected: &String{value: "Division by zero"},
+		},
+		{
+			name:     "test frames unwind after error in loop",
+			input:    "func fail(n) {\n for i := 0; i < n; i++ {\n  if i == 2 {\n   throw \"stop\"\n  }\n }\n}\nx = 5\ntry {\n fail(10)\n} catch e {\n x = x + 1\n}\nx",
+			expected: &Integer{value: 6},
+		},
+		{
+			name:     "test return inside range",
+			input:    "func find(xs, want) {\n for i, x := range xs {\n  if x == want {\n   return i\n  }\n }\n return \"none\"\n}\n[find([5, 6, 7], 7), find([1], 9)]",
+			expected: &Array{Elements: []Object{&Integer{value: 2}, &String{value: "none"}}},
+		},
+		{
+			name:     "test generator with local slots",
+			input:    "func count(n) {\n for i := 0; i < n; i++ {\n  yield i * i\n }\n}\ntotal = 0\nfor v := range count(4) {\n total = total + v\n}\ntotal",
+			expected: &Integer{value: 14},
+		},
+		{
+			name:  "test variable assigned in loop is gone after it",
+			input: "func f() {\n for i := 0; i < 1; i++ {\n  z = 1\n }\n return z\n}\nf()",
+			err:   "variable 'z' is not defined",
+		},
+		{
+			name:  "test recursion limit",
+			input: "func r(n) {\n return [r(n + 1)]\n}\nr(0)",
+			err:   "maximum call stack size of 10000 exceeded",
+		},
+	}
+
+	for _, backend := range backends {
+		for _, test := range cases {
+			t.Run(backend.name+"/"+test.name, func(t *testing.T) {
+				result, err := evalInputWith(test.input, backend.vm)
+				if test.err != "" {
+					if err == nil || toRuntimeError(err).Message != test.err {
+						t.Fatalf("expected error %q, got %v", test.err, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !reflect.DeepEqual(result, test.expected) {
+					t.Fatalf("expected %v, got %v", test.expected, result)
+				}
+			})
+		}
+	}
+}
+
+func TestCompileFunctionSlots(t *testing.T) {
+	cases := []struct {
+		name   string
+		input  string
+		slots  int
+		params []int
+	}{
+		{
+			name:   "test locals get slots",
+			input:  "func f(a, b) {\n c := a + b\n for i := 0; i < c; i++ {\n  c = c - 1\n }\n return c\n}",
+			slots:  4,
+			params: []int{0, 1},
+		},
+		{
+			name:   "test closures keep locals in the frame",
+			input:  "func f(a) {\n c := a\n return func() {\n  return c\n }\n}",
+			slots:  0,
+			params: []int{-1},
+		},
+		{
+			name:   "test declared in if keeps the name in the frame",
+			input:  "func f(a, b) {\n if b {\n  a := 1\n }\n return a\n}",
+			slots:  1,
+			params: []int{-1, 0},
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			program, err := NewV1Parser(NewV1Lexer(test.input), false).ParseProgram()
+			if err != nil {
+				t.Fatal(err)
+			}
+			fn := program.(*BlockStatement).Statements[0].(*FunctionLiteral)
+			chunk, err := compileFunction(fn.Body, fn.Arguments, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if chunk.slots != test.slots || !reflect.DeepEqual(chunk.params, test.params) {
+				t.Fatalf("expected %d slots and parameters %v, got %d and %v", test.slots, test.params, chunk.slots, chunk.params)
+			}
+		})
+	}
+}